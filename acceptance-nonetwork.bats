@@ -24,3 +24,212 @@
   run bin/kubeconform -cache fixtures/cache -summary -schema-location 'https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .NormalizedKubernetesVersion }}{{ .StrictSuffix }}/{{ .ResourceKind }}{{ .KindSuffix }}.json' fixtures/valid.yaml
   [ "$status" -eq 0 ]
 }
+
+@test "Pass when using settings from a configuration file" {
+  run bin/kubeconform -config fixtures/kubeconform-config.yaml fixtures/valid.yaml
+  [ "$status" -eq 0 ]
+  [ "$output" = "Summary: 1 resource found in 1 file - Valid: 0, Invalid: 0, Errors: 0, Skipped: 1" ]
+}
+
+@test "Pass when command-line parameters override settings from a configuration file" {
+  run bin/kubeconform -config fixtures/kubeconform-config.yaml -skip Deployment -summary=false -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/valid.yaml
+  [ "$status" -eq 0 ]
+  [ "$output" = "" ]
+}
+
+@test "Fail when the configuration file is invalid" {
+  run bin/kubeconform -config fixtures/valid.json fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+}
+
+@test "Fail when checking for duplicates and a file contains the same resource twice" {
+  run bin/kubeconform -n 1 -check-duplicates -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/duplicates.yaml
+  [ "$status" -eq 1 ]
+  [ "${lines[0]}" = "fixtures/duplicates.yaml:26:3 - ReplicationController bob failed validation: duplicate resource v1/ReplicationController/default/bob, also defined in fixtures/duplicates.yaml:1" ]
+  [[ "$output" == *"  fixtures/duplicates.yaml:26:3: /metadata/name: duplicate resource v1/ReplicationController/default/bob, also defined in fixtures/duplicates.yaml:1"* ]]
+}
+
+@test "Fail when checking for duplicates and a file contains the same cluster-scoped resource twice" {
+  run bin/kubeconform -check-duplicates -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/duplicates-non-namespaced.yaml
+  [ "$status" -eq 1 ]
+}
+
+@test "Fail when checking for duplicates and a resource is in the default namespace twice" {
+  run bin/kubeconform -check-duplicates -default-namespace the-default-namespace -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/duplicates-with-namespace-default.yaml
+  [ "$status" -eq 1 ]
+}
+
+@test "Pass when checking for duplicates and resources are in different namespaces" {
+  run bin/kubeconform -check-duplicates -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/same-object-different-namespace.yaml
+  [ "$status" -eq 0 ]
+}
+
+@test "Pass when checking for duplicates and resources are in different namespaces, one being the default namespace" {
+  run bin/kubeconform -check-duplicates -default-namespace the-default-namespace -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/same-object-different-namespace-default.yaml
+  [ "$status" -eq 0 ]
+}
+
+@test "Pass when checking for duplicates and resources have the same kind but a different apiVersion" {
+  run bin/kubeconform -check-duplicates -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/same-kind-different-api.yaml
+  [ "$status" -eq 0 ]
+}
+
+@test "Fail when checking for duplicates and the same resource is defined in two files" {
+  run bin/kubeconform -check-duplicates -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/same-object-different-namespace.yaml fixtures/same-object-different-namespace-default.yaml
+  [ "$status" -eq 1 ]
+}
+
+@test "Pass when checking for duplicates and duplicated resources are of a skipped kind" {
+  run bin/kubeconform -check-duplicates -skip SkipThisKind fixtures/duplicates-skipped-kinds.yaml
+  [ "$status" -eq 0 ]
+}
+
+@test "Pass when not checking for duplicates and a file contains the same resource twice" {
+  run bin/kubeconform -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/duplicates.yaml
+  [ "$status" -eq 0 ]
+}
+
+@test "Pass when converting a CRD to JSON schema and validating a custom resource against it" {
+  run bin/kubeconform crd2schema -output-dir "$BATS_TEST_TMPDIR" fixtures/crd_schema.yaml
+  [ "$status" -eq 0 ]
+  [ "$output" = "JSON schema written to $BATS_TEST_TMPDIR/trainingjob_v1.json" ]
+  run bin/kubeconform -summary -schema-location "$BATS_TEST_TMPDIR/{{ .ResourceKind }}_{{ .ResourceAPIVersion }}.json" fixtures/test_crd.yaml
+  [ "$status" -eq 0 ]
+  [ "$output" = "Summary: 1 resource found in 1 file - Valid: 1, Invalid: 0, Errors: 0, Skipped: 0" ]
+}
+
+@test "Fail when converting a file that contains no CRD to JSON schema" {
+  run bin/kubeconform crd2schema -output-dir "$BATS_TEST_TMPDIR" fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+}
+
+@test "Pass when validating a custom resource against a CRD found later in the input" {
+  run bin/kubeconform -summary -crds-from-input -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/test_crd.yaml fixtures/crd_schema.yaml
+  [ "$status" -eq 0 ]
+  [ "$output" = "Summary: 2 resources found in 2 files - Valid: 1, Invalid: 0, Errors: 0, Skipped: 1" ]
+}
+
+@test "Fail when validating an invalid custom resource against a CRD found in the input" {
+  run bin/kubeconform -crds-from-input -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/test_crd_invalid.yaml fixtures/crd_schema.yaml
+  [ "$status" -eq 1 ]
+}
+
+@test "Fail when parsing an invalid file and output a SARIF log" {
+  run bin/kubeconform -output sarif -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/missing_apiversion.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" == *'"ruleId": "error"'* ]]
+  [[ "$output" == *'"uri": "fixtures/missing_apiversion.yaml"'* ]]
+}
+
+@test "Fail when parsing an invalid file and report the line and column of the error" {
+  run bin/kubeconform -schema-location 'fixtures/registry/{{ .ResourceKind }}{{ .KindSuffix }}.json' fixtures/test_crd_invalid.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" == "fixtures/test_crd_invalid.yaml:27:3 - TrainingJob xgboost-mnist-debugger is invalid: "* ]]
+}
+
+@test "Fail when parsing an invalid file and output the line and column of the error as JSON" {
+  run bin/kubeconform -output json -schema-location 'fixtures/registry/{{ .ResourceKind }}{{ .KindSuffix }}.json' fixtures/test_crd_invalid.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" == *'"line": 27,'* ]]
+  [[ "$output" == *'"column": 3'* ]]
+}
+
+@test "Pass when validating a resource against a schema bundle" {
+  run bin/kubeconform bundle -output "$BATS_TEST_TMPDIR/schemas.tar.gz" -schema-location 'fixtures/registry/{{ .ResourceKind }}{{ .KindSuffix }}.json' fixtures/test_crd.yaml
+  [ "$status" -eq 0 ]
+  [ "$output" = "1 schema written to $BATS_TEST_TMPDIR/schemas.tar.gz" ]
+  run bin/kubeconform -summary -schema-location "bundle://$BATS_TEST_TMPDIR/schemas.tar.gz" fixtures/test_crd.yaml
+  [ "$status" -eq 0 ]
+  [ "$output" = "Summary: 1 resource found in 1 file - Valid: 1, Invalid: 0, Errors: 0, Skipped: 0" ]
+}
+
+@test "Fail when bundling schemas for an invalid range of Kubernetes versions" {
+  run bin/kubeconform bundle -kubernetes-version 1.27.0,1.29.0-1.28.0 -output "$BATS_TEST_TMPDIR/schemas.tar.gz" fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+  [ "$output" = "failed parsing command line: 1.29.0-1.28.0 is not a valid range of versions, it must span increasing minor versions of the same major version" ]
+}
+
+@test "Fail when using a schema bundle that does not exist" {
+  run bin/kubeconform -schema-location "bundle://does-not-exist.tar.gz" fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+}
+
+@test "Fail when using -watch with -render" {
+  run bin/kubeconform -watch -render fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+  [ "$output" = "-watch can not be used with -render" ]
+}
+
+@test "Fail when passing a file to the serve command" {
+  run bin/kubeconform serve fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+  [ "$output" = "failed parsing command line: unexpected argument fixtures/valid.yaml" ]
+}
+
+@test "Fail when a resource does not follow a policy rule" {
+  run bin/kubeconform -policy fixtures/policy.yaml -schema-location 'fixtures/registry/{{ .ResourceKind }}{{ .KindSuffix }}.json' fixtures/test_crd.yaml
+  [ "$status" -eq 1 ]
+  [ "$output" = "fixtures/test_crd.yaml:3:1 - TrainingJob xgboost-mnist-debugger is invalid: failed rule team-label: resources must have a team label" ]
+}
+
+@test "Fail when the policy file does not exist" {
+  run bin/kubeconform -policy fixtures/does-not-exist.yaml fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+}
+
+@test "Fail when reading schemas from a kubeconfig context that does not exist" {
+  KUBECONFIG=fixtures/does-not-exist run bin/kubeconform -kube-context missing fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+  [ "$output" = "failed initialising kube:// registry: could not find kubeconfig context missing" ]
+}
+
+@test "Pass when validating against several Kubernetes versions" {
+  run bin/kubeconform -kubernetes-version 1.27.0-1.29.0 -summary -schema-location 'fixtures/registry/{{ .ResourceKind }}{{ .KindSuffix }}.json' fixtures/test_crd.yaml
+  [ "$status" -eq 0 ]
+  [ "${lines[0]}" = "Summary: 1 resource found in 1 file" ]
+  [ "${lines[1]}" = "  1.27.0 - Valid: 1, Invalid: 0, Errors: 0, Skipped: 0" ]
+  [ "${lines[3]}" = "  1.29.0 - Valid: 1, Invalid: 0, Errors: 0, Skipped: 0" ]
+}
+
+@test "Fail when using an output format not supporting several Kubernetes versions" {
+  run bin/kubeconform -kubernetes-version 1.27.0,1.28.0 -output junit fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+  [ "$output" = "'outputFormat' must be 'json', 'pretty' or 'text' when validating against several Kubernetes versions" ]
+}
+
+@test "Fail with the replacement apiVersion when a resource uses an apiVersion that was removed" {
+  run bin/kubeconform -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/deprecated_api_version.yaml
+  [ "$status" -eq 1 ]
+  [ "$output" = "fixtures/deprecated_api_version.yaml:1:1 - Ingress web failed validation: could not find schema for Ingress: extensions/v1beta1 Ingress was removed in Kubernetes 1.22, use networking.k8s.io/v1 instead" ]
+}
+
+@test "Fail on warnings with -fail-on warning" {
+  run bin/kubeconform -fail-on warning -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' fixtures/deprecated_api_version.yaml
+  [ "$status" -eq 1 ]
+}
+
+@test "Fail when passing an invalid value to -fail-on" {
+  run bin/kubeconform -fail-on info fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+}
+
+@test "Fix deprecated apiVersions with -fix" {
+  cp fixtures/deprecated_api_version.yaml "$BATS_TEST_TMPDIR/ingress.yaml"
+  run bin/kubeconform -fix -ignore-missing-schemas -schema-location 'fixtures/{{ .ResourceKind }}.json' "$BATS_TEST_TMPDIR/ingress.yaml"
+  [ "$status" -eq 0 ]
+  [ "$output" = "$BATS_TEST_TMPDIR/ingress.yaml:1 - Ingress web: /apiVersion: replaced apiVersion extensions/v1beta1 with networking.k8s.io/v1, fields that differ between these versions are not migrated" ]
+  run grep -c "apiVersion: networking.k8s.io/v1" "$BATS_TEST_TMPDIR/ingress.yaml"
+  [ "$output" = "1" ]
+}
+
+@test "Fail when using -fix with stdin" {
+  run bash -c "cat fixtures/valid.yaml | bin/kubeconform -fix"
+  [ "$status" -eq 1 ]
+  [ "$output" = "-fix requires files or folders to fix" ]
+}
+
+@test "Report the schemas downloaded with -prefetch" {
+  run bin/kubeconform -prefetch -summary -schema-location 'fixtures/registry/{{ .ResourceKind }}{{ .KindSuffix }}.json' fixtures/test_crd.yaml
+  [ "$status" -eq 0 ]
+  [ "${lines[0]}" = "Prefetched schemas - Fetched: 1, Cached: 0, Missing: 0" ]
+}
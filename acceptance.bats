@@ -127,6 +127,19 @@ resetCacheFolder() {
   [ "$status" -eq 0 ]
 }
 
+@test "Report resources with missing schemas with a warning" {
+  run bin/kubeconform -schema-location 'fixtures/{{ .ResourceKind }}.json' -ignore-missing-schemas -summary fixtures/valid.yaml
+  [ "$status" -eq 0 ]
+  [ "${lines[0]}" == 'fixtures/valid.yaml:1:1 - ReplicationController bob has warnings: rule missing-schema: could not find schema for ReplicationController' ]
+  [ "${lines[1]}" == 'Summary: 1 resource found in 1 file - Valid: 0, Invalid: 0, Errors: 0, Skipped: 0, Warnings: 1' ]
+}
+
+@test "Fail on missing schemas with -fail-on warning" {
+  run bin/kubeconform -schema-location 'fixtures/{{ .ResourceKind }}.json' -ignore-missing-schemas -fail-on warning fixtures/valid.yaml
+  [ "$status" -eq 1 ]
+  [ "$output" == 'fixtures/valid.yaml:1:1 - ReplicationController bob has warnings: rule missing-schema: could not find schema for ReplicationController' ]
+}
+
 @test "Pass when parsing a config with additional properties" {
   run bin/kubeconform -summary fixtures/extra_property.yaml
   [ "$status" -eq 0 ]
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/yannh/kubeconform/pkg/config"
+	"github.com/yannh/kubeconform/pkg/loader"
+	"github.com/yannh/kubeconform/pkg/registry"
+	"github.com/yannh/kubeconform/pkg/resource"
+)
+
+type bundleConfig struct {
+	Files              []string
+	Help               bool
+	KubernetesVersions []string
+	NumberOfWorkers    int
+	Output             string
+	SchemaLocations    []string
+	SkipTLS            bool
+	StrictModes        []bool
+}
+
+func bundleFromFlags(progName string, args []string) (bundleConfig, string, error) {
+	var buf bytes.Buffer
+	var k8sVersions, schemaLocations config.ArrayParam
+	var strictMode string
+	c := bundleConfig{}
+
+	flags := flag.NewFlagSet(progName, flag.ContinueOnError)
+	flags.SetOutput(&buf)
+	flags.BoolVar(&c.Help, "h", false, "show help information")
+	flags.BoolVar(&c.SkipTLS, "insecure-skip-tls-verify", false, "disable verification of the server's SSL certificate. This will make your HTTPS connections insecure")
+	flags.Var(&k8sVersions, "kubernetes-version", "version of Kubernetes to download the schemas for, can be specified multiple times. Several versions or ranges of versions can be passed as a comma-separated list, e.g.: 1.27.0,1.28.0 or 1.27.0-1.29.0 (default: master)")
+	flags.IntVar(&c.NumberOfWorkers, "n", 8, "number of schemas to download in parallel")
+	flags.StringVar(&c.Output, "output", "", "path of the bundle to write - a .tar, .tar.gz, .tgz or .zip file")
+	flags.Var(&schemaLocations, "schema-location", "override schemas location search path (can be specified multiple times)")
+	flags.StringVar(&strictMode, "strict-mode", "false", "schemas to download - false (standard schemas), true (strict schemas) or both")
+	flags.Usage = func() {
+		fmt.Fprintf(&buf, "Usage: %s bundle [OPTION]... [FILE OR FOLDER]...\n", progName)
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	c.Files = flags.Args()
+
+	if c.Help {
+		flags.Usage()
+	}
+	if c.Help || err != nil {
+		return c, buf.String(), err
+	}
+
+	c.KubernetesVersions = []string{"master"}
+	if len(k8sVersions) > 0 {
+		if c.KubernetesVersions, err = config.ParseK8sVersions(strings.Join(k8sVersions, ",")); err != nil {
+			return c, buf.String(), err
+		}
+	}
+
+	c.SchemaLocations = schemaLocations
+	if len(c.SchemaLocations) == 0 {
+		c.SchemaLocations = []string{"default"}
+	}
+
+	switch strictMode {
+	case "false":
+		c.StrictModes = []bool{false}
+	case "true":
+		c.StrictModes = []bool{true}
+	case "both":
+		c.StrictModes = []bool{false, true}
+	default:
+		return c, buf.String(), fmt.Errorf("invalid value %s for -strict-mode: must be false, true or both", strictMode)
+	}
+
+	if c.Output == "" {
+		return c, buf.String(), fmt.Errorf("missing required parameter -output")
+	}
+
+	return c, buf.String(), nil
+}
+
+// kindsFromFiles returns the kinds and apiVersions of the resources found in files
+func kindsFromFiles(files []string) ([]registry.Manifest, error) {
+	resources, errors := resource.FromFiles(context.Background(), files, nil)
+
+	var firstErr error
+	done := make(chan struct{})
+	go func() {
+		for err := range errors {
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		close(done)
+	}()
+
+	seen := map[registry.Manifest]struct{}{}
+	manifests := []registry.Manifest{}
+	for res := range resources {
+		sig, err := res.Signature()
+		if err != nil || sig.Kind == "" || sig.Version == "" {
+			continue
+		}
+		m := registry.Manifest{Kind: sig.Kind, Version: sig.Version}
+		if _, ok := seen[m]; !ok {
+			seen[m] = struct{}{}
+			manifests = append(manifests, m)
+		}
+	}
+	<-done
+
+	return manifests, firstErr
+}
+
+// downloadSchemas downloads the schemas for kinds from the first registry that has them,
+// and adds them to schemas, indexed by their path in the bundle
+func downloadSchemas(cfg bundleConfig, k8sVersion string, strict bool, kinds []registry.Manifest, schemas map[string][]byte) (missing []registry.Manifest, err error) {
+	registries := []registry.Registry{}
+	for _, schemaLocation := range cfg.SchemaLocations {
+		reg, err := registry.New(schemaLocation, "", strict, cfg.SkipTLS, false)
+		if err != nil {
+			return nil, err
+		}
+		registries = append(registries, reg)
+	}
+
+	var mu sync.Mutex
+	var firstErr error
+	jobs := make(chan registry.Manifest)
+	wg := sync.WaitGroup{}
+	for i := 0; i < cfg.NumberOfWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range jobs {
+				content, err := downloadSchema(registries, m, k8sVersion)
+				name, nameErr := registry.BundleEntryName(m.Kind, m.Version, k8sVersion, strict)
+
+				mu.Lock()
+				switch {
+				case err == nil && nameErr != nil:
+					err = nameErr
+					fallthrough
+				case err != nil:
+					if firstErr == nil {
+						firstErr = err
+					}
+				case content == nil:
+					missing = append(missing, m)
+				default:
+					schemas[name] = content
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, m := range kinds {
+		jobs <- m
+	}
+	close(jobs)
+	wg.Wait()
+
+	return missing, firstErr
+}
+
+// downloadSchema returns the schema for the resource m from the first registry
+// that has it, or nil if none does
+func downloadSchema(registries []registry.Registry, m registry.Manifest, k8sVersion string) ([]byte, error) {
+	for _, reg := range registries {
+		_, s, err := reg.DownloadSchema(m.Kind, m.Version, k8sVersion)
+		if err == nil {
+			return json.Marshal(s)
+		}
+		if _, notfound := err.(*loader.NotFoundError); notfound {
+			continue
+		}
+		if _, nonJSONError := err.(*loader.NonJSONResponseError); nonJSONError {
+			continue
+		}
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// bundle downloads the schemas for one or more Kubernetes versions into a single archive,
+// that can then be used with -schema-location bundle://path
+func bundle(progName string, args []string) int {
+	cfg, out, err := bundleFromFlags(progName, args)
+	if out != "" {
+		o := os.Stderr
+		errCode := 1
+		if cfg.Help {
+			o = os.Stdout
+			errCode = 0
+		}
+		fmt.Fprintln(o, out)
+		return errCode
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed parsing command line: %s\n", err.Error())
+		return 1
+	}
+
+	var fileKinds []registry.Manifest
+	if len(cfg.Files) > 0 {
+		if fileKinds, err = kindsFromFiles(cfg.Files); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	schemas := map[string][]byte{}
+	for _, k8sVersion := range cfg.KubernetesVersions {
+		for _, strict := range cfg.StrictModes {
+			kinds := fileKinds
+			if len(cfg.Files) == 0 {
+				seen := map[registry.Manifest]struct{}{}
+				for _, schemaLocation := range cfg.SchemaLocations {
+					manifests, err := registry.ListKinds(schemaLocation, k8sVersion, strict, cfg.SkipTLS)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "failed listing schemas in %s: %s\n", schemaLocation, err)
+						continue
+					}
+					for _, m := range manifests {
+						if _, ok := seen[m]; !ok {
+							seen[m] = struct{}{}
+							kinds = append(kinds, m)
+						}
+					}
+				}
+				if len(kinds) == 0 {
+					fmt.Fprintf(os.Stderr, "no schemas found for Kubernetes version %s\n", k8sVersion)
+					return 1
+				}
+			}
+
+			missing, err := downloadSchemas(cfg, k8sVersion, strict, kinds, schemas)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			for _, m := range missing {
+				fmt.Fprintf(os.Stderr, "could not find schema for %s %s in Kubernetes version %s\n", m.Version, m.Kind, k8sVersion)
+			}
+		}
+	}
+
+	if err := registry.WriteBundle(cfg.Output, schemas); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	plural := ""
+	if len(schemas) != 1 {
+		plural = "s"
+	}
+	fmt.Printf("%d schema%s written to %s\n", len(schemas), plural, cfg.Output)
+
+	return 0
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/yannh/kubeconform/pkg/crd"
+	"github.com/yannh/kubeconform/pkg/resource"
+)
+
+type crd2schemaConfig struct {
+	DenyRootAdditionalProperties bool
+	FilenameFormat               string
+	Files                        []string
+	Help                         bool
+	OutputDir                    string
+}
+
+func crd2schemaFromFlags(progName string, args []string) (crd2schemaConfig, string, error) {
+	var buf bytes.Buffer
+	c := crd2schemaConfig{}
+
+	flags := flag.NewFlagSet(progName, flag.ContinueOnError)
+	flags.SetOutput(&buf)
+	flags.BoolVar(&c.DenyRootAdditionalProperties, "deny-root-additional-properties", false, "disallow additional properties at the root of the schemas")
+	flags.StringVar(&c.FilenameFormat, "filename-format", crd.DefaultFilenameFormat, "Go template used to name the schema files. Available variables: ResourceKind, ResourceAPIVersion, Group, KindSuffix")
+	flags.BoolVar(&c.Help, "h", false, "show help information")
+	flags.StringVar(&c.OutputDir, "output-dir", ".", "folder to write the schemas to")
+	flags.Usage = func() {
+		fmt.Fprintf(&buf, "Usage: %s crd2schema [OPTION]... [FILE OR FOLDER]...\n", progName)
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	c.Files = flags.Args()
+
+	if c.Help {
+		flags.Usage()
+	}
+
+	return c, buf.String(), err
+}
+
+// crd2schema converts the CustomResourceDefinitions found in the files given on the
+// command line, or on stdin, to JSON schemas, one per served version
+func crd2schema(progName string, args []string) int {
+	cfg, out, err := crd2schemaFromFlags(progName, args)
+	if out != "" {
+		o := os.Stderr
+		errCode := 1
+		if cfg.Help {
+			o = os.Stdout
+			errCode = 0
+		}
+		fmt.Fprintln(o, out)
+		return errCode
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed parsing command line: %s\n", err.Error())
+		return 1
+	}
+
+	ctx := context.Background()
+	var resources <-chan resource.Resource
+	var errors <-chan error
+	if len(cfg.Files) == 0 || (len(cfg.Files) == 1 && cfg.Files[0] == "-") {
+		resources, errors = resource.FromStream(ctx, "stdin", os.Stdin)
+	} else {
+		resources, errors = resource.FromFiles(ctx, cfg.Files, nil)
+	}
+
+	go func() {
+		for err := range errors {
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}()
+
+	opts := crd.Opts{DenyAdditionalProperties: true, DenyRootAdditionalProperties: cfg.DenyRootAdditionalProperties}
+	success := true
+	found := false
+	for res := range resources {
+		obj, err := res.Object()
+		if err != nil || obj == nil || !crd.IsCRD(obj) {
+			continue
+		}
+		found = true
+
+		schemas, err := crd.ToSchemas(obj, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s - %s\n", res.Path, err)
+			success = false
+			continue
+		}
+
+		for _, schema := range schemas {
+			filename, err := writeSchema(cfg.OutputDir, cfg.FilenameFormat, schema)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s - %s\n", res.Path, err)
+				success = false
+				continue
+			}
+			fmt.Printf("JSON schema written to %s\n", filename)
+		}
+	}
+
+	if !found {
+		fmt.Fprintln(os.Stderr, "no CustomResourceDefinition found")
+		return 1
+	}
+	if !success {
+		return 1
+	}
+
+	return 0
+}
+
+func writeSchema(outputDir, filenameFormat string, schema crd.Schema) (string, error) {
+	filename, err := schema.Filename(filenameFormat)
+	if err != nil {
+		return "", err
+	}
+	filename = filepath.Join(outputDir, filename)
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return "", fmt.Errorf("failed creating folder for %s: %s", filename, err)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed creating %s: %s", filename, err)
+	}
+	defer f.Close()
+
+	if err := writeJSON(f, schema.Schema); err != nil {
+		return "", fmt.Errorf("failed writing %s: %s", filename, err)
+	}
+
+	return filename, nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
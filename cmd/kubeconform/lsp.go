@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/yannh/kubeconform/pkg/config"
+	"github.com/yannh/kubeconform/pkg/lsp"
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+// languageServer runs kubeconform as a Language Server, communicating with the editor
+// over stdin and stdout. It accepts the same parameters and configuration file as a
+// validation run; files given on the command line are ignored.
+func languageServer(progName string, args []string) int {
+	cfg, out, err := config.FromFlags(progName+" lsp", args)
+	if out != "" {
+		o := os.Stderr
+		errCode := 1
+		if cfg.Help {
+			o = os.Stdout
+			errCode = 0
+		}
+		fmt.Fprintln(o, out)
+		return errCode
+	}
+
+	if cfg.Version {
+		fmt.Println(version)
+		return 0
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed parsing command line: %s\n", err.Error())
+		return 1
+	}
+
+	opts, err := validatorOpts(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// Duplicates are looked for across the documents opened during the whole session,
+	// which would report a document as duplicating itself after each edit
+	opts.CheckDuplicates = false
+
+	v, err := validator.New(cfg.SchemaLocations, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := lsp.NewServer(v, version).Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
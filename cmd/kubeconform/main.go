@@ -5,25 +5,32 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"runtime"
 	"runtime/pprof"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/yannh/kubeconform/pkg/config"
+	"github.com/yannh/kubeconform/pkg/fix"
 	"github.com/yannh/kubeconform/pkg/output"
+	"github.com/yannh/kubeconform/pkg/policy"
 	"github.com/yannh/kubeconform/pkg/resource"
 	"github.com/yannh/kubeconform/pkg/validator"
 )
 
 var version = "development"
 
-func processResults(cancel context.CancelFunc, o output.Output, validationResults <-chan validator.Result, exitOnError bool) <-chan bool {
+// processResults writes the results to o, and returns whether none of them failed the run: results
+// that are invalid or errored, and, if failOnWarning is set, results with warnings
+func processResults(cancel context.CancelFunc, o output.Output, validationResults <-chan validator.Result, exitOnError bool, failOnWarning bool) <-chan bool {
 	success := true
 	result := make(chan bool)
 
 	go func() {
 		for res := range validationResults {
-			if res.Status == validator.Error || res.Status == validator.Invalid {
+			if res.Status == validator.Error || res.Status == validator.Invalid || (failOnWarning && res.HasWarnings()) {
 				success = false
 			}
 			if o != nil {
@@ -46,6 +53,108 @@ func processResults(cancel context.CancelFunc, o output.Output, validationResult
 	return result
 }
 
+// validateCRDsFirst validates the CustomResourceDefinitions found in resources as they
+// are read, and holds back all other resources until the input has been read entirely,
+// so that custom resources are validated once the CRDs defining them are known, and
+// duplicates of cluster-scoped custom resources are detected
+func validateCRDsFirst(resources <-chan resource.Resource, validationResults chan<- validator.Result, v validator.Validator, k8sVersions []string) <-chan resource.Resource {
+	deferred := make(chan resource.Resource)
+
+	go func() {
+		held := []resource.Resource{}
+		for res := range resources {
+			if sig, err := res.Signature(); err == nil && sig.IsCRD() {
+				for _, result := range validateVersions(v, res, k8sVersions) {
+					validationResults <- result
+				}
+				continue
+			}
+			held = append(held, res)
+		}
+
+		for _, res := range held {
+			deferred <- res
+		}
+		close(deferred)
+	}()
+
+	return deferred
+}
+
+// prefetchSchemas reads all the resources sent on resources, and downloads the schemas they need
+// for each of the Kubernetes versions k8sVersions, using parallelism goroutines, before sending
+// the resources on the returned channel. The number of schemas fetched is reported on stderr.
+func prefetchSchemas(resources <-chan resource.Resource, v validator.Validator, k8sVersions []string, parallelism int) <-chan resource.Resource {
+	prefetched := make(chan resource.Resource)
+
+	go func() {
+		held := []resource.Resource{}
+		sigs := []resource.Signature{}
+		for res := range resources {
+			if sig, err := res.Signature(); err == nil {
+				sigs = append(sigs, *sig)
+			}
+			held = append(held, res)
+		}
+
+		stats := v.Prefetch(sigs, k8sVersions, parallelism)
+		errors := ""
+		if stats.Errors > 0 {
+			errors = fmt.Sprintf(", Errors: %d", stats.Errors)
+		}
+		fmt.Fprintf(os.Stderr, "Prefetched schemas - Fetched: %d, Cached: %d, Missing: %d%s\n", stats.Fetched, stats.Cached, stats.Missing, errors)
+
+		for _, res := range held {
+			prefetched <- res
+		}
+		close(prefetched)
+	}()
+
+	return prefetched
+}
+
+// validateVersions validates res against each of the Kubernetes versions k8sVersions,
+// or against the version of the validator if a single version is given
+func validateVersions(v validator.Validator, res resource.Resource, k8sVersions []string) []validator.Result {
+	if len(k8sVersions) < 2 {
+		return []validator.Result{v.ValidateResource(res)}
+	}
+
+	results := make([]validator.Result, 0, len(k8sVersions))
+	for _, k8sVersion := range k8sVersions {
+		results = append(results, v.ValidateResourceForVersion(res, k8sVersion))
+	}
+	return results
+}
+
+// validatorOpts returns the options of the validator for the configuration cfg
+func validatorOpts(cfg config.Config) (validator.Opts, error) {
+	opts := validator.Opts{
+		Cache:                cfg.Cache,
+		Debug:                cfg.Debug,
+		SkipTLS:              cfg.SkipTLS,
+		SkipKinds:            cfg.SkipKinds,
+		RejectKinds:          cfg.RejectKinds,
+		KubernetesVersion:    cfg.KubernetesVersion.Versions()[0],
+		KubeContext:          cfg.KubeContext,
+		Strict:               cfg.Strict,
+		IgnoreMissingSchemas: cfg.IgnoreMissingSchemas,
+		CheckDuplicates:      cfg.CheckDuplicates,
+		CRDsFromInput:        cfg.CRDsFromInput,
+		DefaultNamespace:     cfg.DefaultNamespace,
+	}
+
+	if len(cfg.Policies) > 0 {
+		p, err := policy.FromFiles(cfg.Policies...)
+		if err != nil {
+			return opts, err
+		}
+		opts.RuleEngine = p
+	}
+
+	return opts, nil
+}
+
 func kubeconform(cfg config.Config) int {
 	var err error
 	cpuProfileFile := os.Getenv("KUBECONFORM_CPUPROFILE_FILE")
@@ -63,6 +172,46 @@ func kubeconform(cfg config.Config) int {
 		defer pprof.StopCPUProfile()
 	}
 
+	if cfg.Watch {
+		switch {
+		case len(cfg.Files) == 0 || (len(cfg.Files) == 1 && cfg.Files[0] == "-"):
+			fmt.Fprintln(os.Stderr, "-watch requires files or folders to watch")
+			return 1
+		case cfg.Render:
+			fmt.Fprintln(os.Stderr, "-watch can not be used with -render")
+			return 1
+		case cfg.CheckDuplicates:
+			fmt.Fprintln(os.Stderr, "-watch can not be used with -check-duplicates")
+			return 1
+		}
+	}
+
+	if cfg.Fix {
+		switch {
+		case len(cfg.Files) == 0 || (len(cfg.Files) == 1 && cfg.Files[0] == "-"):
+			fmt.Fprintln(os.Stderr, "-fix requires files or folders to fix")
+			return 1
+		case cfg.Render:
+			fmt.Fprintln(os.Stderr, "-fix can not be used with -render")
+			return 1
+		case cfg.CheckDuplicates:
+			fmt.Fprintln(os.Stderr, "-fix can not be used with -check-duplicates")
+			return 1
+		}
+	}
+
+	k8sVersions := cfg.KubernetesVersion.Versions()
+	if len(k8sVersions) > 1 {
+		switch {
+		case cfg.Watch:
+			fmt.Fprintln(os.Stderr, "-watch can not be used with several Kubernetes versions")
+			return 1
+		case cfg.CheckDuplicates:
+			fmt.Fprintln(os.Stderr, "-check-duplicates can not be used with several Kubernetes versions")
+			return 1
+		}
+	}
+
 	useStdin := false
 	if len(cfg.Files) == 0 || (len(cfg.Files) == 1 && cfg.Files[0] == "-") {
 		stat, _ := os.Stdin.Stat()
@@ -73,36 +222,81 @@ func kubeconform(cfg config.Config) int {
 	}
 
 	var o output.Output
-	if o, err = output.New(os.Stdout, cfg.OutputFormat, cfg.Summary, useStdin, cfg.Verbose); err != nil {
+	if len(k8sVersions) > 1 {
+		o, err = output.NewMatrix(os.Stdout, cfg.OutputFormat, k8sVersions, cfg.Summary, useStdin, cfg.Verbose)
+	} else {
+		o, err = output.New(os.Stdout, cfg.OutputFormat, cfg.Summary, useStdin, cfg.Verbose)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	opts, err := validatorOpts(cfg)
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
 	var v validator.Validator
-	v, err = validator.New(cfg.SchemaLocations, validator.Opts{
-		Cache:                cfg.Cache,
-		Debug:                cfg.Debug,
-		SkipTLS:              cfg.SkipTLS,
-		SkipKinds:            cfg.SkipKinds,
-		RejectKinds:          cfg.RejectKinds,
-		KubernetesVersion:    cfg.KubernetesVersion.String(),
-		Strict:               cfg.Strict,
-		IgnoreMissingSchemas: cfg.IgnoreMissingSchemas,
-	})
+	v, err = validator.New(cfg.SchemaLocations, opts)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
 
-	validationResults := make(chan validator.Result)
-	ctx, cancel := context.WithCancel(context.Background())
-	successChan := processResults(cancel, o, validationResults, cfg.ExitOnError)
+	discoveryOpts := resource.DiscoveryOpts{
+		IgnoreFilePatterns: cfg.IgnoreFilenamePatterns,
+		Readers:            cfg.NumberOfWorkers,
+		Render: resource.RenderOpts{
+			Enabled:           cfg.Render,
+			HelmValuesFiles:   cfg.HelmValuesFiles,
+			KustomizeOverlays: cfg.KustomizeOverlays,
+		},
+	}
+
+	if cfg.Fix {
+		if err := fixFiles(cfg, v, discoveryOpts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	var resourcesChan <-chan resource.Resource
 	var errors <-chan error
 	if useStdin {
 		resourcesChan, errors = resource.FromStream(ctx, "stdin", os.Stdin)
 	} else {
-		resourcesChan, errors = resource.FromFiles(ctx, cfg.Files, cfg.IgnoreFilenamePatterns)
+		resourcesChan, errors = resource.FromFilesWithOpts(ctx, cfg.Files, discoveryOpts)
+	}
+
+	success := validateResources(cancel, cfg, v, o, resourcesChan, errors, cfg.ExitOnError)
+	o.Flush()
+
+	if cfg.Watch {
+		return watch(cfg, v, discoveryOpts)
+	}
+
+	if !success {
+		return 1
+	}
+
+	return 0
+}
+
+// validateResources validates the resources read from resourcesChan using multiple workers, and
+// writes the results to o. It returns false if a resource is invalid or could not be validated.
+// cancel is called to stop the discovery of resources, on discovery errors or when exitOnError is set.
+func validateResources(cancel context.CancelFunc, cfg config.Config, v validator.Validator, o output.Output, resourcesChan <-chan resource.Resource, errors <-chan error, exitOnError bool) bool {
+	validationResults := make(chan validator.Result)
+	successChan := processResults(cancel, o, validationResults, exitOnError, cfg.FailOn == "warning")
+	k8sVersions := cfg.KubernetesVersion.Versions()
+
+	if cfg.Prefetch {
+		resourcesChan = prefetchSchemas(resourcesChan, v, k8sVersions, cfg.NumberOfWorkers)
+	}
+	if cfg.CRDsFromInput || cfg.CheckDuplicates {
+		resourcesChan = validateCRDsFirst(resourcesChan, validationResults, v, k8sVersions)
 	}
 
 	// Process discovered resources across multiple workers
@@ -111,7 +305,9 @@ func kubeconform(cfg config.Config) int {
 		wg.Add(1)
 		go func(resources <-chan resource.Resource, validationResults chan<- validator.Result, v validator.Validator) {
 			for res := range resources {
-				validationResults <- v.ValidateResource(res)
+				for _, result := range validateVersions(v, res, k8sVersions) {
+					validationResults <- result
+				}
 			}
 			wg.Done()
 		}(resourcesChan, validationResults, v)
@@ -146,17 +342,77 @@ func kubeconform(cfg config.Config) int {
 	wg.Wait()
 
 	close(validationResults)
-	success := <-successChan
-	o.Flush()
+	return <-successChan
+}
 
-	if !success {
-		return 1
-	}
+// fixFiles validates the files in cfg.Files, and rewrites them to fix the errors that can be
+// fixed automatically, reporting the changes made on stderr
+func fixFiles(cfg config.Config, v validator.Validator, discoveryOpts resource.DiscoveryOpts) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	return 0
+	f := fix.New(os.Stderr, v)
+	resourcesChan, errors := resource.FromFilesWithOpts(ctx, cfg.Files, discoveryOpts)
+	validateResources(cancel, cfg, v, f, resourcesChan, errors, false)
+	return f.Flush()
+}
+
+// watch validates the files in cfg.Files again whenever they change, until kubeconform is
+// interrupted. The validator v, and the schemas it has already loaded, are reused across runs.
+func watch(cfg config.Config, v validator.Validator, discoveryOpts resource.DiscoveryOpts) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintln(os.Stderr, "Watching for changes, press Ctrl+C to stop")
+	changes, errors := resource.Watch(ctx, cfg.Files, discoveryOpts)
+	for {
+		select {
+		case files, ok := <-changes:
+			if !ok {
+				if ctx.Err() != nil {
+					return 0
+				}
+				return 1
+			}
+
+			filesPlural := ""
+			if len(files) > 1 {
+				filesPlural = "s"
+			}
+			// Written to stderr so every run of the json, junit or sarif outputs is a valid document
+			fmt.Fprintf(os.Stderr, "\n%s - validating %d changed file%s\n", time.Now().Format(time.TimeOnly), len(files), filesPlural)
+
+			o, _ := output.New(os.Stdout, cfg.OutputFormat, cfg.Summary, false, cfg.Verbose)
+			runCtx, cancel := context.WithCancel(ctx)
+			resourcesChan, discoveryErrors := resource.FromFilesWithOpts(runCtx, files, discoveryOpts)
+			validateResources(cancel, cfg, v, o, resourcesChan, discoveryErrors, false)
+			o.Flush()
+			cancel()
+
+		case err, ok := <-errors:
+			if !ok {
+				errors = nil
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "failed watching for changes: %s\n", err)
+		}
+	}
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "bundle":
+			os.Exit(bundle(os.Args[0], os.Args[2:]))
+		case "crd2schema":
+			os.Exit(crd2schema(os.Args[0], os.Args[2:]))
+		case "lsp":
+			os.Exit(languageServer(os.Args[0], os.Args[2:]))
+		case "serve":
+			os.Exit(serve(os.Args[0], os.Args[2:]))
+		}
+	}
+
 	cfg, out, err := config.FromFlags(os.Args[0], os.Args[1:])
 	if out != "" {
 		o := os.Stderr
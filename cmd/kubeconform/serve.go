@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yannh/kubeconform/pkg/config"
+	"github.com/yannh/kubeconform/pkg/server"
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+// serve runs kubeconform as an HTTP server until it receives SIGINT or SIGTERM. It accepts
+// the same parameters and configuration file as a validation run, and the -listen,
+// -tls-cert-file and -tls-key-file parameters; files given on the command line are ignored.
+func serve(progName string, args []string) int {
+	var listen, tlsCertFile, tlsKeyFile string
+	cfg, out, err := config.FromFlagsWith(progName+" serve", args, func(flags *flag.FlagSet) {
+		flags.StringVar(&listen, "listen", ":8080", "address to listen on")
+		flags.StringVar(&tlsCertFile, "tls-cert-file", "", "certificate to serve HTTPS with, required by admission webhooks")
+		flags.StringVar(&tlsKeyFile, "tls-key-file", "", "private key of the certificate given with -tls-cert-file")
+	})
+	if out != "" {
+		o := os.Stderr
+		errCode := 1
+		if cfg.Help {
+			o = os.Stdout
+			errCode = 0
+		}
+		fmt.Fprintln(o, out)
+		return errCode
+	}
+
+	if cfg.Version {
+		fmt.Println(version)
+		return 0
+	}
+
+	if err == nil && (tlsCertFile == "") != (tlsKeyFile == "") {
+		err = fmt.Errorf("-tls-cert-file and -tls-key-file must be set together")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed parsing command line: %s\n", err.Error())
+		return 1
+	}
+
+	opts, err := validatorOpts(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	v, err := validator.New(cfg.SchemaLocations, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	s := server.New(v, cfg.KubernetesVersion.Versions())
+
+	srv := &http.Server{
+		Addr:              listen,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Listening on %s\n", listen)
+	if tlsCertFile != "" {
+		err = srv.ListenAndServeTLS(tlsCertFile, tlsKeyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,215 @@
+// Package cel evaluates expressions written in the Common Expression Language
+// (https://github.com/google/cel-spec), as used by Kubernetes validation rules, against
+// resources decoded from YAML or JSON. Expressions are compiled and evaluated with cel-go.
+//
+// Expressions can use the standard definitions of CEL, optional values, cross-type numeric
+// comparisons, and the strings, sets, lists and two-variable comprehensions extensions, as
+// Kubernetes does. The functions of the Kubernetes CEL libraries, such as quantities, URLs or
+// IP addresses, are not available: expressions using them fail to compile.
+//
+// Variables have the dyn type. Numbers decoded from YAML or JSON do not carry the int or double
+// type their schema declares, whole numbers are therefore evaluated as ints: a value of 2.0 in
+// a resource is the int 2, and a value of 2.5 the double 2.5.
+package cel
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/ext"
+)
+
+// costLimit is the maximum cost of the evaluation of an expression, the same as the limit
+// Kubernetes sets on each validation rule
+const costLimit = 1000000
+
+// Program is a compiled CEL expression
+type Program struct {
+	expr    string
+	program cel.Program
+}
+
+// envs are the CEL environments, indexed by the comma-separated list of their variables
+var envs sync.Map
+
+func newEnv(variables []string) (*cel.Env, error) {
+	k := strings.Join(variables, ",")
+	if env, ok := envs.Load(k); ok {
+		return env.(*cel.Env), nil
+	}
+
+	opts := []cel.EnvOption{
+		cel.OptionalTypes(),
+		cel.CrossTypeNumericComparisons(true),
+		ext.Strings(ext.StringsVersion(2)),
+		ext.Sets(),
+		ext.Lists(),
+		ext.TwoVarComprehensions(),
+	}
+	for _, v := range variables {
+		opts = append(opts, cel.Variable(v, cel.DynType))
+	}
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, err
+	}
+	envs.Store(k, env)
+	return env, nil
+}
+
+// Compile parses and checks the CEL expression expr. Evaluating the expression can only
+// reference the variables listed in variables.
+func Compile(expr string, variables ...string) (*Program, error) {
+	p, _, err := compile(expr, variables)
+	return p, err
+}
+
+// CompileString is like Compile, for expressions that must evaluate to a string, such as
+// the messageExpression of validation rules
+func CompileString(expr string, variables ...string) (*Program, error) {
+	p, ast, err := compile(expr, variables)
+	if err != nil {
+		return nil, err
+	}
+	if t := ast.OutputType(); !t.IsExactType(types.StringType) && !t.IsExactType(types.DynType) {
+		return nil, fmt.Errorf("failed compiling expression %s: evaluates to %s, expected string", expr, t)
+	}
+	return p, nil
+}
+
+func compile(expr string, variables []string) (*Program, *cel.Ast, error) {
+	env, err := newEnv(variables)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed compiling expression %s: %s", expr, err)
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, nil, fmt.Errorf("failed compiling expression %s: %s", expr, issuesError(issues))
+	}
+	program, err := env.Program(ast, cel.CostLimit(costLimit))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed compiling expression %s: %s", expr, err)
+	}
+
+	return &Program{expr: expr, program: program}, ast, nil
+}
+
+// issuesError returns the errors found compiling an expression on a single line,
+// without the excerpts of the expression cel-go adds to them
+func issuesError(issues *cel.Issues) error {
+	msgs := []string{}
+	for _, e := range issues.Errors() {
+		msg := strings.TrimSuffix(e.Message, " (in container '')")
+		if !slices.Contains(msgs, msg) {
+			msgs = append(msgs, msg)
+		}
+	}
+	return errors.New(strings.Join(msgs, ", "))
+}
+
+// Eval evaluates the program with the values of its variables. Values are
+// expected to be decoded from JSON or YAML, e.g. map[string]any or []any.
+func (p *Program) Eval(vars map[string]any) (any, error) {
+	out, err := p.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return native(out), nil
+}
+
+// EvalBool evaluates a program that is expected to return a boolean
+func (p *Program) EvalBool(vars map[string]any) (bool, error) {
+	out, err := p.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := out.(types.Bool)
+	if !ok {
+		return false, fmt.Errorf("expression %s evaluated to %s, expected bool", p.expr, out.Type().TypeName())
+	}
+	return bool(b), nil
+}
+
+// EvalString evaluates a program that is expected to return a string
+func (p *Program) EvalString(vars map[string]any) (string, error) {
+	out, err := p.eval(vars)
+	if err != nil {
+		return "", err
+	}
+	str, ok := out.(types.String)
+	if !ok {
+		return "", fmt.Errorf("expression %s evaluated to %s, expected string", p.expr, out.Type().TypeName())
+	}
+	return string(str), nil
+}
+
+func (p *Program) eval(vars map[string]any) (ref.Val, error) {
+	activation := map[string]any{}
+	for name, v := range vars {
+		activation[name] = wholeNumbersToInts(v)
+	}
+	out, _, err := p.program.Eval(activation)
+	return out, err
+}
+
+// String returns the source of the expression
+func (p *Program) String() string {
+	return p.expr
+}
+
+// wholeNumbersToInts returns a copy of v, a value decoded from JSON or YAML, in which
+// the numbers without a fractional part are converted to int64
+func wholeNumbersToInts(v any) any {
+	switch v := v.(type) {
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v)
+		}
+		return v
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			m[k] = wholeNumbersToInts(item)
+		}
+		return m
+	case []any:
+		l := make([]any, len(v))
+		for i, item := range v {
+			l[i] = wholeNumbersToInts(item)
+		}
+		return l
+	}
+	return v
+}
+
+// native converts the CEL value v to the Go types values decoded from JSON or YAML have,
+// such as []any, map[string]any or nil
+func native(v ref.Val) any {
+	switch v := v.(type) {
+	case types.Null:
+		return nil
+	case traits.Lister:
+		l := []any{}
+		for it := v.Iterator(); it.HasNext() == types.True; {
+			l = append(l, native(it.Next()))
+		}
+		return l
+	case traits.Mapper:
+		m := map[string]any{}
+		for it := v.Iterator(); it.HasNext() == types.True; {
+			k := it.Next()
+			m[fmt.Sprint(k.Value())] = native(v.Get(k))
+		}
+		return m
+	}
+	return v.Value()
+}
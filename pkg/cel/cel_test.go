@@ -0,0 +1,154 @@
+package cel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	object := map[string]any{
+		"kind": "Deployment",
+		"metadata": map[string]any{
+			"name":   "web",
+			"labels": map[string]any{"app": "web", "team": "a"},
+		},
+		"spec": map[string]any{
+			"replicas": float64(3),
+			"ratio":    float64(0.5),
+			"template": map[string]any{
+				"spec": map[string]any{
+					"containers": []any{
+						map[string]any{"name": "web", "image": "nginx:1.25", "resources": map[string]any{"limits": map[string]any{"cpu": "1"}}},
+						map[string]any{"name": "sidecar", "image": "envoy:latest"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, testCase := range []struct {
+		expr     string
+		expected any
+	}{
+		{"1 + 2 * 3 - 4 / 2 % 3", int64(5)},
+		{"1.5 * 2.0", 3.0},
+		{"1 < 1.5 && 2 >= 1.5 && 1u < 2", true},
+		{"[1, 2] + [3]", []any{int64(1), int64(2), int64(3)}},
+		{"{'a': [1, null]}", map[string]any{"a": []any{int64(1), nil}}},
+		{"object.spec.replicas", int64(3)},
+		{"object.spec.replicas + 1", int64(4)},
+		{"object.spec.ratio * 2.0", 1.0},
+		{"object.spec.replicas > object.spec.ratio", true},
+		{"object.metadata.labels['team']", "a"},
+		{"object.spec.template.spec.containers[1].name", "sidecar"},
+		{"has(object.spec.replicas) && !has(object.spec.selector)", true},
+		{"object.spec.template.spec.containers.all(c, has(c.resources) && has(c.resources.limits))", false},
+		{"object.spec.template.spec.containers.exists_one(c, c.name.startsWith('s'))", true},
+		{"object.spec.template.spec.containers.map(c, c.name != 'web', c.image)", []any{"envoy:latest"}},
+		{"object.metadata.labels.all(k, v, k.size() <= 4 && v != '')", true},
+		{"object.metadata.name.matches('^[a-z]+$') && 'abc'.contains('bc')", true},
+		{"'A-b'.lowerAscii() + 'c'.upperAscii() + ' d '.trim()", "a-bCd"},
+		{"'a,b'.split(',').join('-') + 'aaa'.replace('a', 'b')", "a-bbbb"},
+		{"sets.contains([1, 2, 3], [3, 1]) && [3, 1, 2].sort() == [1, 2, 3]", true},
+		{"object.?spec.?selector.orValue('none')", "none"},
+		{"type(object.spec.replicas) == int && type(object.spec.ratio) == double", true},
+		{"duration('1m') > duration('30s')", true},
+		// Errors are absorbed by the other operand of logical operators
+		{"has(object.spec.selector) && object.spec.selector.matchLabels.size() > 0", false},
+		{"object.spec.selector.matchLabels.size() > 0 || true", true},
+	} {
+		p, err := Compile(testCase.expr, "object")
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", testCase.expr, err)
+			continue
+		}
+		got, err := p.Eval(map[string]any{"object": object})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", testCase.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf("%s: expected %#v, got %#v", testCase.expr, testCase.expected, got)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	object := map[string]any{"spec": map[string]any{"replicas": float64(3), "ratio": float64(0.5)}}
+
+	for _, testCase := range []struct {
+		expr string
+		err  string
+	}{
+		{"object.status.replicas", "no such key: status"},
+		{"object['spec']['missing']", "no such key: missing"},
+		{"[1][1]", "index out of bounds"},
+		{"1 / 0", "division by zero"},
+		{"9223372036854775807 + object.spec.replicas", "overflow"},
+		{"object.spec.replicas + object.spec.ratio", "no such overload"},
+		{"[0].all(x, 1 / x == 1)", "division by zero"},
+		{"'a'.matches(object.spec.replicas > 0 ? '(' : 'a')", "error parsing regexp"},
+		{"int(object.spec.replicas > 0 ? 'a' : '1')", "type conversion error"},
+		{"object.spec.replicas.map(x, x)", "expected iterable type"},
+	} {
+		p, err := Compile(testCase.expr, "object")
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", testCase.expr, err)
+			continue
+		}
+		_, err = p.Eval(map[string]any{"object": object})
+		if err == nil {
+			t.Errorf("%s: expected an error", testCase.expr)
+			continue
+		}
+		if !strings.Contains(err.Error(), testCase.err) {
+			t.Errorf("%s: expected error %s, got %s", testCase.expr, testCase.err, err)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	for _, testCase := range []struct {
+		expr string
+		err  string
+	}{
+		{"self.a.all(x, x > y)", "undeclared reference to 'y'"},
+		{"self == oldSelf", "undeclared reference to 'oldSelf'"},
+		{"quantity(self.cpu).isLessThan(quantity('1'))", "undeclared reference to 'quantity'"},
+		{"1 + 'a'", "found no matching overload for '_+_'"},
+		{"self.a ==", "Syntax error"},
+		{"9223372036854775808 > 0", "invalid int literal"},
+	} {
+		if _, err := Compile(testCase.expr, "self"); err == nil || !strings.Contains(err.Error(), testCase.err) {
+			t.Errorf("%s: expected error %s, got %v", testCase.expr, testCase.err, err)
+		}
+	}
+
+	if _, err := Compile("self == oldSelf", "self", "oldSelf"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	p, _ := Compile("self.size()", "self")
+	if _, err := p.EvalBool(map[string]any{"self": "abc"}); err == nil || !strings.Contains(err.Error(), "evaluated to int, expected bool") {
+		t.Errorf("expected an error for a non boolean result, got %v", err)
+	}
+	p, _ = Compile("self.size() == 3", "self")
+	if b, err := p.EvalBool(map[string]any{"self": "abc"}); err != nil || !b {
+		t.Errorf("expected true, got %t: %s", b, err)
+	}
+}
+
+func TestEvalCostLimit(t *testing.T) {
+	p, err := Compile("self.all(x, self.all(y, self.all(z, x + y + z >= 0)))", "self")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	l := []any{}
+	for i := 0; i < 200; i++ {
+		l = append(l, float64(i))
+	}
+	if _, err := p.EvalBool(map[string]any{"self": l}); err == nil || !strings.Contains(err.Error(), "cost limit exceeded") {
+		t.Errorf("expected the cost limit to be exceeded, got %v", err)
+	}
+}
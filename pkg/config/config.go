@@ -2,59 +2,173 @@ package config
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+
+	"sigs.k8s.io/yaml"
 )
 
+// DefaultConfigFiles are the configuration files kubeconform looks for, from the
+// working directory up to the root of the filesystem, when -config is not set
+var DefaultConfigFiles = []string{".kubeconform.yaml", ".kubeconform.yml"}
+
 type Config struct {
-	Cache                  string              `yaml:"cache" json:"cache"`
-	Debug                  bool                `yaml:"debug" json:"debug"`
-	ExitOnError            bool                `yaml:"exitOnError" json:"exitOnError"`
-	Files                  []string            `yaml:"files" json:"files"`
-	Help                   bool                `yaml:"help" json:"help"`
-	IgnoreFilenamePatterns []string            `yaml:"ignoreFilenamePatterns" json:"ignoreFilenamePatterns"`
-	IgnoreMissingSchemas   bool                `yaml:"ignoreMissingSchemas" json:"ignoreMissingSchemas"`
-	KubernetesVersion      k8sVersionValue     `yaml:"kubernetesVersion" json:"kubernetesVersion"`
-	NumberOfWorkers        int                 `yaml:"numberOfWorkers" json:"numberOfWorkers"`
-	OutputFormat           string              `yaml:"output" json:"output"`
-	RejectKinds            map[string]struct{} `yaml:"reject" json:"reject"`
-	SchemaLocations        []string            `yaml:"schemaLocations" json:"schemaLocations"`
-	SkipKinds              map[string]struct{} `yaml:"skip" json:"skip"`
-	SkipTLS                bool                `yaml:"insecureSkipTLSVerify" json:"insecureSkipTLSVerify"`
-	Strict                 bool                `yaml:"strict" json:"strict"`
-	Summary                bool                `yaml:"summary" json:"summary"`
-	Verbose                bool                `yaml:"verbose" json:"verbose"`
-	Version                bool                `yaml:"version" json:"version"`
-}
-
-type arrayParam []string
-
-func (ap *arrayParam) String() string {
+	Cache                  string          `yaml:"cache" json:"cache"`
+	CheckDuplicates        bool            `yaml:"checkDuplicates" json:"checkDuplicates"`
+	ConfigFile             string          `yaml:"-" json:"-"`
+	CRDsFromInput          bool            `yaml:"crdsFromInput" json:"crdsFromInput"`
+	Debug                  bool            `yaml:"debug" json:"debug"`
+	DefaultNamespace       string          `yaml:"defaultNamespace" json:"defaultNamespace"`
+	ExitOnError            bool            `yaml:"exitOnError" json:"exitOnError"`
+	FailOn                 string          `yaml:"failOn" json:"failOn"`
+	Files                  []string        `yaml:"files" json:"files"`
+	Fix                    bool            `yaml:"fix" json:"fix"`
+	Help                   bool            `yaml:"help" json:"help"`
+	HelmValuesFiles        []string        `yaml:"helmValues" json:"helmValues"`
+	IgnoreFilenamePatterns []string        `yaml:"ignoreFilenamePatterns" json:"ignoreFilenamePatterns"`
+	IgnoreMissingSchemas   bool            `yaml:"ignoreMissingSchemas" json:"ignoreMissingSchemas"`
+	KubeContext            string          `yaml:"kubeContext" json:"kubeContext"`
+	KubernetesVersion      k8sVersionValue `yaml:"kubernetesVersion" json:"kubernetesVersion"`
+	KustomizeOverlays      []string        `yaml:"kustomizeOverlays" json:"kustomizeOverlays"`
+	NumberOfWorkers        int             `yaml:"numberOfWorkers" json:"numberOfWorkers"`
+	OutputFormat           string          `yaml:"output" json:"output"`
+	Policies               []string        `yaml:"policies" json:"policies"`
+	Prefetch               bool            `yaml:"prefetch" json:"prefetch"`
+	RejectKinds            kindsValue      `yaml:"reject" json:"reject"`
+	Render                 bool            `yaml:"render" json:"render"`
+	SchemaLocations        []string        `yaml:"schemaLocations" json:"schemaLocations"`
+	SkipKinds              kindsValue      `yaml:"skip" json:"skip"`
+	SkipTLS                bool            `yaml:"insecureSkipTLSVerify" json:"insecureSkipTLSVerify"`
+	Strict                 bool            `yaml:"strict" json:"strict"`
+	Summary                bool            `yaml:"summary" json:"summary"`
+	Verbose                bool            `yaml:"verbose" json:"verbose"`
+	Version                bool            `yaml:"version" json:"version"`
+	Watch                  bool            `yaml:"watch" json:"watch"`
+}
+
+// ArrayParam is a command-line parameter that can be specified multiple times
+type ArrayParam []string
+
+func (ap *ArrayParam) String() string {
 	return strings.Join(*ap, " - ")
 }
 
-func (ap *arrayParam) Set(value string) error {
+func (ap *ArrayParam) Set(value string) error {
 	*ap = append(*ap, value)
 	return nil
 }
 
+// k8sVersionValue is a version of Kubernetes, or a comma-separated list of versions. Lists
+// can contain ranges of versions, such as 1.27.0-1.29.0, which are expanded to the first
+// release of each minor version in between: 1.27.0,1.28.0,1.29.0.
 type k8sVersionValue string
 
 func (kv *k8sVersionValue) String() string {
 	return string(*kv)
 }
 
+// Versions returns the versions of Kubernetes to validate against
+func (kv k8sVersionValue) Versions() []string {
+	return strings.Split(string(kv), ",")
+}
+
 func (kv k8sVersionValue) MarshalText() ([]byte, error) {
 	return []byte(kv), nil
 }
 
 func (kv *k8sVersionValue) UnmarshalText(v []byte) error {
-	if ok, _ := regexp.MatchString(`^(master|\d+\.\d+\.\d+)$`, string(v)); ok != true {
-		return fmt.Errorf("%v is not a valid version. Valid values are \"master\" (default) or full version x.y.z (e.g. \"1.27.2\")", string(v))
+	versions, err := ParseK8sVersions(string(v))
+	if err != nil {
+		return err
+	}
+	*kv = k8sVersionValue(strings.Join(versions, ","))
+	return nil
+}
+
+// UnmarshalJSON accepts, in configuration files, either a list of versions
+// or a comma-separated string of versions
+func (kv *k8sVersionValue) UnmarshalJSON(data []byte) error {
+	var csv string
+	if err := json.Unmarshal(data, &csv); err == nil {
+		return kv.UnmarshalText([]byte(csv))
+	}
+
+	var versions []string
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return fmt.Errorf("expected a list or a comma-separated string of versions: %s", err)
+	}
+	return kv.UnmarshalText([]byte(strings.Join(versions, ",")))
+}
+
+var k8sVersionRegexp = regexp.MustCompile(`^(master|\d+\.\d+\.\d+)$`)
+
+// ParseK8sVersions parses a comma-separated list of versions and ranges of versions
+func ParseK8sVersions(s string) ([]string, error) {
+	versions := []string{}
+	seen := map[string]bool{}
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		expanded := []string{v}
+		if from, to, isRange := strings.Cut(v, "-"); isRange {
+			var err error
+			if expanded, err = expandK8sVersionRange(from, to); err != nil {
+				return nil, err
+			}
+		} else if !k8sVersionRegexp.MatchString(v) {
+			return nil, fmt.Errorf("%v is not a valid version. Valid values are \"master\" (default), full versions x.y.z (e.g. \"1.27.2\"), comma-separated lists of versions or ranges of versions (e.g. \"1.27.0-1.29.0\")", v)
+		}
+
+		for _, version := range expanded {
+			if !seen[version] {
+				seen[version] = true
+				versions = append(versions, version)
+			}
+		}
+	}
+	return versions, nil
+}
+
+// expandK8sVersionRange returns the versions from and to, and the first release of each
+// minor version between them, e.g. 1.27.3-1.29.1 is expanded to 1.27.3,1.28.0,1.29.1
+func expandK8sVersionRange(from, to string) ([]string, error) {
+	var fromMajor, fromMinor, fromPatch, toMajor, toMinor, toPatch int
+	_, errFrom := fmt.Sscanf(from, "%d.%d.%d", &fromMajor, &fromMinor, &fromPatch)
+	_, errTo := fmt.Sscanf(to, "%d.%d.%d", &toMajor, &toMinor, &toPatch)
+	if errFrom != nil || errTo != nil || !k8sVersionRegexp.MatchString(from) || !k8sVersionRegexp.MatchString(to) {
+		return nil, fmt.Errorf("%s-%s is not a valid range of versions, expected x.y.z-x.y.z (e.g. \"1.27.0-1.29.0\")", from, to)
+	}
+	if fromMajor != toMajor || fromMinor >= toMinor {
+		return nil, fmt.Errorf("%s-%s is not a valid range of versions, it must span increasing minor versions of the same major version", from, to)
+	}
+
+	versions := []string{from}
+	for minor := fromMinor + 1; minor < toMinor; minor++ {
+		versions = append(versions, fmt.Sprintf("%d.%d.0", fromMajor, minor))
+	}
+	return append(versions, to), nil
+}
+
+// kindsValue is a set of kinds or GVKs. In configuration files, it can be
+// written either as a list or as a comma-separated string.
+type kindsValue map[string]struct{}
+
+func (kv *kindsValue) UnmarshalJSON(data []byte) error {
+	var csv string
+	if err := json.Unmarshal(data, &csv); err == nil {
+		*kv = splitCSV(csv)
+		return nil
+	}
+
+	var kinds []string
+	if err := json.Unmarshal(data, &kinds); err != nil {
+		return fmt.Errorf("expected a list or a comma-separated string of kinds: %s", err)
 	}
-	*kv = k8sVersionValue(v)
+	*kv = splitCSV(strings.Join(kinds, ","))
 	return nil
 }
 
@@ -72,34 +186,179 @@ func splitCSV(csvStr string) map[string]struct{} {
 	return valuesMap
 }
 
-// FromFlags retrieves kubeconform's runtime configuration from the command-line parameters
+// FindConfigFile looks for one of the DefaultConfigFiles in dir and its parent folders.
+// It returns an empty string if no configuration file could be found.
+func FindConfigFile(dir string) string {
+	for {
+		for _, name := range DefaultConfigFiles {
+			p := filepath.Join(dir, name)
+			if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+				return p
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// FromFile reads kubeconform's configuration from a YAML or JSON file. Settings that are
+// not present in the file keep the value they have in defaults. Relative paths in the file
+// are relative to the folder of the file.
+func FromFile(path string, defaults Config) (Config, error) {
+	c := defaults
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return c, fmt.Errorf("failed reading configuration file %s: %s", path, err)
+	}
+
+	file := Config{}
+	if err := yaml.UnmarshalStrict(content, &file); err != nil {
+		return c, fmt.Errorf("failed parsing configuration file %s: %s", path, err)
+	}
+	if err := yaml.UnmarshalStrict(content, &c); err != nil {
+		return c, fmt.Errorf("failed parsing configuration file %s: %s", path, err)
+	}
+	c.ConfigFile = path
+
+	dir := filepath.Dir(path)
+	if file.Files != nil {
+		c.Files = resolvePaths(dir, c.Files)
+	}
+	if file.HelmValuesFiles != nil {
+		c.HelmValuesFiles = resolvePaths(dir, c.HelmValuesFiles)
+	}
+	if file.Policies != nil {
+		c.Policies = resolvePaths(dir, c.Policies)
+	}
+	if file.Cache != "" {
+		c.Cache = resolvePaths(dir, []string{c.Cache})[0]
+	}
+	if file.SchemaLocations != nil {
+		locations := []string{}
+		for _, l := range c.SchemaLocations {
+			if l != "default" && !strings.Contains(l, "://") {
+				l = resolvePaths(dir, []string{l})[0]
+			}
+			locations = append(locations, l)
+		}
+		c.SchemaLocations = locations
+	}
+
+	return c, nil
+}
+
+// resolvePaths returns the paths, relative to dir unless they are absolute.
+// "-", standing for the standard input, is left as is.
+func resolvePaths(dir string, paths []string) []string {
+	resolved := []string{}
+	for _, p := range paths {
+		if p != "-" && !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		resolved = append(resolved, p)
+	}
+	return resolved
+}
+
+func defaultConfig() Config {
+	return Config{
+		DefaultNamespace:  "default",
+		Files:             []string{},
+		KubernetesVersion: "master",
+		NumberOfWorkers:   4,
+		OutputFormat:      "text",
+		SkipKinds:         kindsValue{},
+		RejectKinds:       kindsValue{},
+	}
+}
+
+// FromFlags retrieves kubeconform's runtime configuration from the command-line parameters.
+// If a configuration file is passed with -config, or found in the working directory or one of
+// its parents, its settings are used as defaults for the command-line parameters.
 func FromFlags(progName string, args []string) (Config, string, error) {
-	var schemaLocationsParam, ignoreFilenamePatterns arrayParam
+	return FromFlagsWith(progName, args, nil)
+}
+
+// FromFlagsWith is FromFlags for subcommands accepting additional parameters: addFlags,
+// if not nil, is called to define them on the flag set before parsing the command line.
+func FromFlagsWith(progName string, args []string, addFlags func(*flag.FlagSet)) (Config, string, error) {
+	c, out, err := parseFlags(progName, args, defaultConfig(), addFlags)
+	if err != nil || c.Help || c.Version {
+		return c, out, err
+	}
+
+	configFile := c.ConfigFile
+	if configFile == "" {
+		if wd, err := os.Getwd(); err == nil {
+			configFile = FindConfigFile(wd)
+			// Relative to the working directory, so are the paths of the file resolved against its folder
+			if rel, err := filepath.Rel(wd, configFile); err == nil && configFile != "" {
+				configFile = rel
+			}
+		}
+	}
+	if configFile == "" {
+		return c, out, err
+	}
+
+	defaults, err := FromFile(configFile, defaultConfig())
+	if err != nil {
+		return c, out, err
+	}
+
+	// Parse the command line a second time, so that parameters passed explicitly
+	// take precedence over the settings of the configuration file
+	c, out, err = parseFlags(progName, args, defaults, addFlags)
+	c.ConfigFile = configFile
+	return c, out, err
+}
+
+func parseFlags(progName string, args []string, defaults Config, addFlags func(*flag.FlagSet)) (Config, string, error) {
+	var schemaLocationsParam, ignoreFilenamePatterns, helmValuesFiles, kustomizeOverlays, policies ArrayParam
 	var skipKindsCSV, rejectKindsCSV string
 	flags := flag.NewFlagSet(progName, flag.ContinueOnError)
 	var buf bytes.Buffer
 	flags.SetOutput(&buf)
 
-	c := Config{}
-	c.Files = []string{}
+	c := defaults
 
-	flags.TextVar(&c.KubernetesVersion, "kubernetes-version", k8sVersionValue("master"), "version of Kubernetes to validate against, e.g.: 1.18.0")
+	flags.StringVar(&c.ConfigFile, "config", "", "path to a configuration file (default: first .kubeconform.yaml found in the current folder or its parents)")
+	flags.TextVar(&c.KubernetesVersion, "kubernetes-version", defaults.KubernetesVersion, "version of Kubernetes to validate against, e.g.: 1.18.0. Several versions or ranges of versions can be passed as a comma-separated list, e.g.: 1.27.0,1.28.0 or 1.27.0-1.29.0")
 	flags.Var(&schemaLocationsParam, "schema-location", "override schemas location search path (can be specified multiple times)")
+	flags.StringVar(&c.KubeContext, "kube-context", defaults.KubeContext, "read schemas from the cluster of this kubeconfig context before other schema locations, as kube://CONTEXT")
 	flags.StringVar(&skipKindsCSV, "skip", "", "comma-separated list of kinds or GVKs to ignore")
 	flags.StringVar(&rejectKindsCSV, "reject", "", "comma-separated list of kinds or GVKs to reject")
-	flags.BoolVar(&c.Debug, "debug", false, "print debug information")
-	flags.BoolVar(&c.ExitOnError, "exit-on-error", false, "immediately stop execution when the first error is encountered")
-	flags.BoolVar(&c.IgnoreMissingSchemas, "ignore-missing-schemas", false, "skip files with missing schemas instead of failing")
+	flags.BoolVar(&c.Debug, "debug", defaults.Debug, "print debug information")
+	flags.BoolVar(&c.CRDsFromInput, "crds-from-input", defaults.CRDsFromInput, "validate custom resources against the CustomResourceDefinitions found in the input. All resources are read before validating custom resources")
+	flags.BoolVar(&c.CheckDuplicates, "check-duplicates", defaults.CheckDuplicates, "report resources with the same apiVersion, kind, namespace and name")
+	flags.StringVar(&c.DefaultNamespace, "default-namespace", defaults.DefaultNamespace, "namespace assumed for resources that do not set one, when checking for duplicates")
+	flags.BoolVar(&c.ExitOnError, "exit-on-error", defaults.ExitOnError, "immediately stop execution when the first error is encountered")
+	flags.StringVar(&c.FailOn, "fail-on", defaults.FailOn, "lowest severity of the findings making kubeconform fail - error or warning (default: error)")
+	flags.BoolVar(&c.Fix, "fix", defaults.Fix, "rewrite files to fix the errors that can be fixed automatically: values of the wrong type, renamed fields and deprecated apiVersions")
+	flags.BoolVar(&c.IgnoreMissingSchemas, "ignore-missing-schemas", defaults.IgnoreMissingSchemas, "report resources with missing schemas with a warning instead of failing")
 	flags.Var(&ignoreFilenamePatterns, "ignore-filename-pattern", "regular expression specifying paths to ignore (can be specified multiple times)")
-	flags.BoolVar(&c.Summary, "summary", false, "print a summary at the end (ignored for junit output)")
-	flags.IntVar(&c.NumberOfWorkers, "n", 4, "number of goroutines to run concurrently")
-	flags.BoolVar(&c.Strict, "strict", false, "disallow additional properties not in schema or duplicated keys")
-	flags.StringVar(&c.OutputFormat, "output", "text", "output format - json, junit, pretty, tap, text")
-	flags.BoolVar(&c.Verbose, "verbose", false, "print results for all resources (ignored for tap and junit output)")
-	flags.BoolVar(&c.SkipTLS, "insecure-skip-tls-verify", false, "disable verification of the server's SSL certificate. This will make your HTTPS connections insecure")
-	flags.StringVar(&c.Cache, "cache", "", "cache schemas downloaded via HTTP to this folder")
+	flags.BoolVar(&c.Render, "render", defaults.Render, "render folders containing a Helm chart (Chart.yaml) or a Kustomize configuration (kustomization.yaml) before validating them")
+	flags.Var(&helmValuesFiles, "helm-values", "values file to use when rendering Helm charts (can be specified multiple times)")
+	flags.Var(&kustomizeOverlays, "kustomize-overlay", "only render Kustomize folders with this name, e.g.: production (can be specified multiple times)")
+	flags.Var(&policies, "policy", "file containing policy rules resources must follow, in addition to their schema (can be specified multiple times)")
+	flags.BoolVar(&c.Prefetch, "prefetch", defaults.Prefetch, "read all resources, and download the schemas they need concurrently, before validating them")
+	flags.BoolVar(&c.Summary, "summary", defaults.Summary, "print a summary at the end (ignored for junit and sarif output)")
+	flags.IntVar(&c.NumberOfWorkers, "n", defaults.NumberOfWorkers, "number of goroutines to run concurrently")
+	flags.BoolVar(&c.Strict, "strict", defaults.Strict, "disallow additional properties not in schema or duplicated keys")
+	flags.StringVar(&c.OutputFormat, "output", defaults.OutputFormat, "output format - json, junit, pretty, sarif, tap, text")
+	flags.BoolVar(&c.Verbose, "verbose", defaults.Verbose, "print results for all resources (ignored for tap, junit and sarif output)")
+	flags.BoolVar(&c.SkipTLS, "insecure-skip-tls-verify", defaults.SkipTLS, "disable verification of the server's SSL certificate. This will make your HTTPS connections insecure")
+	flags.StringVar(&c.Cache, "cache", defaults.Cache, "cache schemas downloaded via HTTP to this folder")
+	flags.BoolVar(&c.Watch, "watch", defaults.Watch, "keep running, and validate files again when they change")
 	flags.BoolVar(&c.Help, "h", false, "show help information")
 	flags.BoolVar(&c.Version, "v", false, "show version information")
+	if addFlags != nil {
+		addFlags(flags)
+	}
 	flags.Usage = func() {
 		fmt.Fprintf(&buf, "Usage: %s [OPTION]... [FILE OR FOLDER]...\n", progName)
 		flags.PrintDefaults()
@@ -107,11 +366,37 @@ func FromFlags(progName string, args []string) (Config, string, error) {
 
 	err := flags.Parse(args)
 
-	c.SkipKinds = splitCSV(skipKindsCSV)
-	c.RejectKinds = splitCSV(rejectKindsCSV)
-	c.IgnoreFilenamePatterns = ignoreFilenamePatterns
-	c.SchemaLocations = schemaLocationsParam
-	c.Files = flags.Args()
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if set["skip"] {
+		c.SkipKinds = splitCSV(skipKindsCSV)
+	}
+	if set["reject"] {
+		c.RejectKinds = splitCSV(rejectKindsCSV)
+	}
+	if set["ignore-filename-pattern"] {
+		c.IgnoreFilenamePatterns = ignoreFilenamePatterns
+	}
+	if set["helm-values"] {
+		c.HelmValuesFiles = helmValuesFiles
+	}
+	if set["kustomize-overlay"] {
+		c.KustomizeOverlays = kustomizeOverlays
+	}
+	if set["policy"] {
+		c.Policies = policies
+	}
+	if set["schema-location"] {
+		c.SchemaLocations = schemaLocationsParam
+	}
+	if len(flags.Args()) > 0 || len(defaults.Files) == 0 {
+		c.Files = flags.Args()
+	}
+
+	if err == nil && c.FailOn != "" && c.FailOn != "error" && c.FailOn != "warning" {
+		err = fmt.Errorf("invalid value \"%s\" for -fail-on, must be error or warning", c.FailOn)
+	}
 
 	if c.Help {
 		flags.Usage()
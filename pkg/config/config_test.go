@@ -1,6 +1,9 @@
 package config
 
 import (
+	"flag"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -40,6 +43,37 @@ func TestSkipKindMaps(t *testing.T) {
 	}
 }
 
+func TestK8sVersionValue(t *testing.T) {
+	for _, testCase := range []struct {
+		value  string
+		expect []string
+		err    bool
+	}{
+		{"master", []string{"master"}, false},
+		{"1.27.2", []string{"1.27.2"}, false},
+		{"1.27.0,1.28.0, 1.29.0", []string{"1.27.0", "1.28.0", "1.29.0"}, false},
+		{"1.27.3-1.30.1", []string{"1.27.3", "1.28.0", "1.29.0", "1.30.1"}, false},
+		{"1.26.0,1.27.0-1.28.0,master", []string{"1.26.0", "1.27.0", "1.28.0", "master"}, false},
+		{"1.27.0,1.27.0-1.28.0", []string{"1.27.0", "1.28.0"}, false},
+		{"latest", nil, true},
+		{"1.27.0,", nil, true},
+		{"1.29.0-1.27.0", nil, true},
+		{"1.27.0-1.27.3", nil, true},
+		{"1.27.0-2.0.0", nil, true},
+		{"master-1.29.0", nil, true},
+	} {
+		var kv k8sVersionValue
+		err := kv.UnmarshalText([]byte(testCase.value))
+		if (err != nil) != testCase.err {
+			t.Errorf("%s: expected error %t, got %v", testCase.value, testCase.err, err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(kv.Versions(), testCase.expect) {
+			t.Errorf("%s: expected versions %v, got %v", testCase.value, testCase.expect, kv.Versions())
+		}
+	}
+}
+
 func TestFromFlags(t *testing.T) {
 	testCases := []struct {
 		args []string
@@ -49,6 +83,7 @@ func TestFromFlags(t *testing.T) {
 			[]string{},
 			Config{
 				Files:             []string{},
+				DefaultNamespace:  "default",
 				KubernetesVersion: "master",
 				NumberOfWorkers:   4,
 				OutputFormat:      "text",
@@ -62,6 +97,7 @@ func TestFromFlags(t *testing.T) {
 			Config{
 				Files:             []string{},
 				Help:              true,
+				DefaultNamespace:  "default",
 				KubernetesVersion: "master",
 				NumberOfWorkers:   4,
 				OutputFormat:      "text",
@@ -75,6 +111,7 @@ func TestFromFlags(t *testing.T) {
 			Config{
 				Files:             []string{},
 				Version:           true,
+				DefaultNamespace:  "default",
 				KubernetesVersion: "master",
 				NumberOfWorkers:   4,
 				OutputFormat:      "text",
@@ -87,6 +124,7 @@ func TestFromFlags(t *testing.T) {
 			[]string{"-skip", "a,b,c"},
 			Config{
 				Files:             []string{},
+				DefaultNamespace:  "default",
 				KubernetesVersion: "master",
 				NumberOfWorkers:   4,
 				OutputFormat:      "text",
@@ -99,6 +137,7 @@ func TestFromFlags(t *testing.T) {
 			[]string{"-skip", "a, b, c"},
 			Config{
 				Files:             []string{},
+				DefaultNamespace:  "default",
 				KubernetesVersion: "master",
 				NumberOfWorkers:   4,
 				OutputFormat:      "text",
@@ -111,6 +150,7 @@ func TestFromFlags(t *testing.T) {
 			[]string{"-skip", "a,b, c"},
 			Config{
 				Files:             []string{},
+				DefaultNamespace:  "default",
 				KubernetesVersion: "master",
 				NumberOfWorkers:   4,
 				OutputFormat:      "text",
@@ -123,6 +163,7 @@ func TestFromFlags(t *testing.T) {
 			[]string{"-summary", "-verbose", "file1", "file2"},
 			Config{
 				Files:             []string{"file1", "file2"},
+				DefaultNamespace:  "default",
 				KubernetesVersion: "master",
 				NumberOfWorkers:   4,
 				OutputFormat:      "text",
@@ -142,6 +183,7 @@ func TestFromFlags(t *testing.T) {
 				Debug:                true,
 				Files:                []string{"file1", "file2"},
 				IgnoreMissingSchemas: true,
+				DefaultNamespace:     "default",
 				KubernetesVersion:    "1.16.0",
 				NumberOfWorkers:      2,
 				OutputFormat:         "json",
@@ -153,8 +195,122 @@ func TestFromFlags(t *testing.T) {
 				Verbose:              true,
 			},
 		},
+		{
+			[]string{"-check-duplicates", "-default-namespace", "team-a", "file1"},
+			Config{
+				CheckDuplicates:   true,
+				DefaultNamespace:  "team-a",
+				Files:             []string{"file1"},
+				KubernetesVersion: "master",
+				NumberOfWorkers:   4,
+				OutputFormat:      "text",
+				SchemaLocations:   nil,
+				SkipKinds:         map[string]struct{}{},
+				RejectKinds:       map[string]struct{}{},
+			},
+		},
+		{
+			[]string{"-prefetch", "folder"},
+			Config{
+				DefaultNamespace:  "default",
+				Files:             []string{"folder"},
+				KubernetesVersion: "master",
+				NumberOfWorkers:   4,
+				OutputFormat:      "text",
+				Prefetch:          true,
+				SchemaLocations:   nil,
+				SkipKinds:         map[string]struct{}{},
+				RejectKinds:       map[string]struct{}{},
+			},
+		},
+		{
+			[]string{"-fix", "folder"},
+			Config{
+				DefaultNamespace:  "default",
+				Files:             []string{"folder"},
+				Fix:               true,
+				KubernetesVersion: "master",
+				NumberOfWorkers:   4,
+				OutputFormat:      "text",
+				SchemaLocations:   nil,
+				SkipKinds:         map[string]struct{}{},
+				RejectKinds:       map[string]struct{}{},
+			},
+		},
+		{
+			[]string{"-watch", "folder"},
+			Config{
+				DefaultNamespace:  "default",
+				Files:             []string{"folder"},
+				KubernetesVersion: "master",
+				NumberOfWorkers:   4,
+				OutputFormat:      "text",
+				SchemaLocations:   nil,
+				SkipKinds:         map[string]struct{}{},
+				RejectKinds:       map[string]struct{}{},
+				Watch:             true,
+			},
+		},
+		{
+			[]string{"-policy", "rules.yaml", "-policy", "team-rules.yaml", "file"},
+			Config{
+				DefaultNamespace:  "default",
+				Files:             []string{"file"},
+				KubernetesVersion: "master",
+				NumberOfWorkers:   4,
+				OutputFormat:      "text",
+				Policies:          []string{"rules.yaml", "team-rules.yaml"},
+				SchemaLocations:   nil,
+				SkipKinds:         map[string]struct{}{},
+				RejectKinds:       map[string]struct{}{},
+			},
+		},
+		{
+			[]string{"-kubernetes-version", "1.27.0-1.29.0", "file"},
+			Config{
+				DefaultNamespace:  "default",
+				Files:             []string{"file"},
+				KubernetesVersion: "1.27.0,1.28.0,1.29.0",
+				NumberOfWorkers:   4,
+				OutputFormat:      "text",
+				SchemaLocations:   nil,
+				SkipKinds:         map[string]struct{}{},
+				RejectKinds:       map[string]struct{}{},
+			},
+		},
+		{
+			[]string{"-fail-on", "warning", "file"},
+			Config{
+				DefaultNamespace:  "default",
+				FailOn:            "warning",
+				Files:             []string{"file"},
+				KubernetesVersion: "master",
+				NumberOfWorkers:   4,
+				OutputFormat:      "text",
+				SchemaLocations:   nil,
+				SkipKinds:         map[string]struct{}{},
+				RejectKinds:       map[string]struct{}{},
+			},
+		},
+		{
+			[]string{"-kube-context", "staging", "file"},
+			Config{
+				DefaultNamespace:  "default",
+				Files:             []string{"file"},
+				KubeContext:       "staging",
+				KubernetesVersion: "master",
+				NumberOfWorkers:   4,
+				OutputFormat:      "text",
+				SchemaLocations:   nil,
+				SkipKinds:         map[string]struct{}{},
+				RejectKinds:       map[string]struct{}{},
+			},
+		},
 	}
 
+	// No configuration file must be found in the working directory or its parents
+	t.Chdir(t.TempDir())
+
 	for i, testCase := range testCases {
 		cfg, _, _ := FromFlags("kubeconform", testCase.args)
 		if reflect.DeepEqual(cfg, testCase.conf) != true {
@@ -162,3 +318,181 @@ func TestFromFlags(t *testing.T) {
 		}
 	}
 }
+
+func TestFindConfigFile(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := FindConfigFile(nested); got != "" {
+		t.Errorf("expected no configuration file to be found, got %s", got)
+	}
+
+	configFile := filepath.Join(root, "a", ".kubeconform.yaml")
+	if err := os.WriteFile(configFile, []byte("strict: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := FindConfigFile(nested); got != configFile {
+		t.Errorf("expected configuration file %s, got %s", configFile, got)
+	}
+}
+
+func TestFromFlagsWithConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "kubeconform.yaml")
+	content := []byte(`
+kubernetesVersion: 1.27.2
+schemaLocations:
+  - default
+  - schemas/{{ .ResourceKind }}.json
+skip: [ConfigMap, v1/Secret]
+reject: Pod,Job
+strict: true
+summary: true
+files:
+  - manifests/
+`)
+	if err := os.WriteFile(configFile, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		args []string
+		conf Config
+	}{
+		{
+			[]string{"-config", configFile},
+			Config{
+				ConfigFile:        configFile,
+				Files:             []string{filepath.Join(dir, "manifests")},
+				DefaultNamespace:  "default",
+				KubernetesVersion: "1.27.2",
+				NumberOfWorkers:   4,
+				OutputFormat:      "text",
+				SchemaLocations:   []string{"default", filepath.Join(dir, "schemas/{{ .ResourceKind }}.json")},
+				SkipKinds:         map[string]struct{}{"ConfigMap": {}, "v1/Secret": {}},
+				RejectKinds:       map[string]struct{}{"Pod": {}, "Job": {}},
+				Strict:            true,
+				Summary:           true,
+			},
+		},
+		{
+			[]string{"-config", configFile, "-kubernetes-version", "1.28.0", "-skip", "Deployment",
+				"-schema-location", "folder", "-strict=false", "-output", "json", "file1"},
+			Config{
+				ConfigFile:        configFile,
+				Files:             []string{"file1"},
+				DefaultNamespace:  "default",
+				KubernetesVersion: "1.28.0",
+				NumberOfWorkers:   4,
+				OutputFormat:      "json",
+				SchemaLocations:   []string{"folder"},
+				SkipKinds:         map[string]struct{}{"Deployment": {}},
+				RejectKinds:       map[string]struct{}{"Pod": {}, "Job": {}},
+				Strict:            false,
+				Summary:           true,
+			},
+		},
+	}
+
+	for i, testCase := range testCases {
+		cfg, _, err := FromFlags("kubeconform", testCase.args)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %s", i, err)
+		}
+		if reflect.DeepEqual(cfg, testCase.conf) != true {
+			t.Errorf("test %d: failed parsing config - expected , got: \n%+v\n%+v", i, testCase.conf, cfg)
+		}
+	}
+}
+
+func TestFromFlagsWithConfigFileInParentFolder(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "apps", "web")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := []byte(`
+files: [manifests/]
+schemaLocations:
+  - default
+  - https://example.com/{{ .ResourceKind }}.json
+  - schemas/{{ .ResourceKind }}.json
+  - /schemas/{{ .ResourceKind }}.json
+policies: [policies.yaml]
+`)
+	if err := os.WriteFile(filepath.Join(root, ".kubeconform.yaml"), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(nested)
+
+	cfg, _, err := FromFlags("kubeconform", []string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expect := Config{
+		ConfigFile:        filepath.Join("..", "..", ".kubeconform.yaml"),
+		Files:             []string{filepath.Join("..", "..", "manifests")},
+		DefaultNamespace:  "default",
+		KubernetesVersion: "master",
+		NumberOfWorkers:   4,
+		OutputFormat:      "text",
+		Policies:          []string{filepath.Join("..", "..", "policies.yaml")},
+		SchemaLocations: []string{
+			"default",
+			"https://example.com/{{ .ResourceKind }}.json",
+			filepath.Join("..", "..", "schemas", "{{ .ResourceKind }}.json"),
+			"/schemas/{{ .ResourceKind }}.json",
+		},
+		SkipKinds:   map[string]struct{}{},
+		RejectKinds: map[string]struct{}{},
+	}
+	if !reflect.DeepEqual(cfg, expect) {
+		t.Errorf("failed parsing config - expected , got: \n%+v\n%+v", expect, cfg)
+	}
+
+	// Files passed on the command line are relative to the working directory
+	cfg, _, _ = FromFlags("kubeconform", []string{"deployment.yaml"})
+	if !reflect.DeepEqual(cfg.Files, []string{"deployment.yaml"}) {
+		t.Errorf("expected files [deployment.yaml], got %v", cfg.Files)
+	}
+}
+
+func TestFromFlagsWithInvalidFailOn(t *testing.T) {
+	if _, _, err := FromFlags("kubeconform", []string{"-fail-on", "info", "file"}); err == nil {
+		t.Errorf("expected an error for an invalid -fail-on value")
+	}
+}
+
+func TestFromFlagsWithInvalidConfigFile(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "kubeconform.yaml")
+	if err := os.WriteFile(configFile, []byte("kubernetesVersion: latest\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := FromFlags("kubeconform", []string{"-config", configFile}); err == nil {
+		t.Errorf("expected an error parsing an invalid configuration file")
+	}
+}
+
+func TestFromFlagsWithAdditionalFlags(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	listen := ""
+	cfg, _, err := FromFlagsWith("kubeconform serve", []string{"-listen", ":9090", "-strict"}, func(flags *flag.FlagSet) {
+		flags.StringVar(&listen, "listen", ":8080", "address to listen on")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if listen != ":9090" || !cfg.Strict {
+		t.Errorf("expected -listen :9090 and -strict to be parsed, got %s and %t", listen, cfg.Strict)
+	}
+
+	if _, _, err := FromFlags("kubeconform", []string{"-listen", ":9090"}); err == nil {
+		t.Errorf("expected an error for an undefined flag")
+	}
+}
@@ -0,0 +1,236 @@
+// Package crd converts Kubernetes CustomResourceDefinitions to JSON schemas that
+// can be used by kubeconform to validate custom resources.
+package crd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// DefaultFilenameFormat is the name of the schema files written by default,
+// matching the layout of most schema registries
+const DefaultFilenameFormat = "{{ .ResourceKind }}_{{ .ResourceAPIVersion }}"
+
+// Opts contains options for the conversion of a CRD to JSON schemas
+type Opts struct {
+	DenyAdditionalProperties     bool // set additionalProperties to false in objects defining properties, except at the root
+	DenyRootAdditionalProperties bool // also set additionalProperties to false at the root of the schema
+}
+
+// Schema is the JSON schema for one version of a custom resource
+type Schema struct {
+	Group   string         // API group of the resource, e.g. monitoring.coreos.com
+	Version string         // version of the resource, e.g. v1
+	Kind    string         // kind of the resource, e.g. Prometheus
+	Schema  map[string]any // JSON schema of the resource
+}
+
+// APIVersion returns the apiVersion of the resources validated by the schema, e.g. monitoring.coreos.com/v1
+func (s Schema) APIVersion() string {
+	return s.Group + "/" + s.Version
+}
+
+// Filename computes the name of the file the schema should be written to, from a
+// Go template such as DefaultFilenameFormat. The template can use the variables
+// ResourceKind, ResourceAPIVersion, Group and KindSuffix, which have the same meaning
+// as in schema locations. The resulting filename is lowercase. The variables are read
+// from the CRD, so they can not contain path separators or "..", and the filename can
+// not point outside of the folder the schema is written to.
+func (s Schema) Filename(format string) (string, error) {
+	for _, v := range []string{s.Kind, s.Version, s.Group} {
+		if strings.ContainsAny(v, `/\`) || strings.Contains(v, "..") {
+			return "", fmt.Errorf("invalid name %s in CustomResourceDefinition, names can not contain path separators or \"..\"", v)
+		}
+	}
+
+	tmpl, err := template.New("filename").Parse(format)
+	if err != nil {
+		return "", fmt.Errorf("failed parsing filename format %s: %s", format, err)
+	}
+
+	tplData := struct {
+		ResourceKind       string
+		ResourceAPIVersion string
+		Group              string
+		KindSuffix         string
+	}{
+		s.Kind,
+		s.Version,
+		s.Group,
+		"-" + strings.Split(s.Group, ".")[0] + "-" + s.Version,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, tplData); err != nil {
+		return "", fmt.Errorf("failed computing filename: %s", err)
+	}
+
+	filename := strings.ToLower(buf.String()) + ".json"
+	if !filepath.IsLocal(filename) {
+		return "", fmt.Errorf("filename %s is outside of the output folder", filename)
+	}
+	return filename, nil
+}
+
+// IsCRD returns true if the decoded resource obj is a CustomResourceDefinition
+func IsCRD(obj map[string]any) bool {
+	kind, _ := obj["kind"].(string)
+	apiVersion, _ := obj["apiVersion"].(string)
+	return kind == "CustomResourceDefinition" && strings.HasPrefix(apiVersion, "apiextensions.k8s.io/")
+}
+
+// ToSchemas returns a JSON schema for each served version of the CustomResourceDefinition crd.
+// Both apiextensions.k8s.io/v1 CRDs, defining a schema per version, and apiextensions.k8s.io/v1beta1
+// CRDs, sharing a single spec.validation across versions, are supported.
+func ToSchemas(crd map[string]any, opts Opts) ([]Schema, error) {
+	if !IsCRD(crd) {
+		return nil, fmt.Errorf("resource is not a CustomResourceDefinition")
+	}
+
+	spec, ok := crd["spec"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("CustomResourceDefinition has no spec")
+	}
+	group, _ := spec["group"].(string)
+	names, _ := spec["names"].(map[string]any)
+	kind, _ := names["kind"].(string)
+	if group == "" || kind == "" {
+		return nil, fmt.Errorf("CustomResourceDefinition is missing spec.group or spec.names.kind")
+	}
+
+	// apiextensions.k8s.io/v1beta1 schema, shared by all versions
+	var sharedSchema map[string]any
+	if validation, ok := spec["validation"].(map[string]any); ok {
+		sharedSchema, _ = validation["openAPIV3Schema"].(map[string]any)
+	}
+
+	schemas := []Schema{}
+	versions, _ := spec["versions"].([]any)
+	for _, v := range versions {
+		version, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := version["name"].(string)
+		if served, ok := version["served"].(bool); ok && !served {
+			continue
+		}
+
+		schema := sharedSchema
+		if s, ok := version["schema"].(map[string]any); ok {
+			if openAPIV3Schema, ok := s["openAPIV3Schema"].(map[string]any); ok {
+				schema = openAPIV3Schema
+			}
+		}
+		if schema == nil || name == "" {
+			continue
+		}
+
+		schemas = append(schemas, Schema{Group: group, Version: name, Kind: kind, Schema: convert(schema, opts)})
+	}
+
+	// apiextensions.k8s.io/v1beta1 CRDs can declare a single version in spec.version
+	if len(versions) == 0 && sharedSchema != nil {
+		if name, ok := spec["version"].(string); ok {
+			schemas = append(schemas, Schema{Group: group, Version: name, Kind: kind, Schema: convert(sharedSchema, opts)})
+		}
+	}
+
+	return schemas, nil
+}
+
+// convert returns a copy of the openAPIV3Schema of a CRD, modified so it can be used
+// to validate resources with kubeconform
+func convert(openAPIV3Schema map[string]any, opts Opts) map[string]any {
+	schema := deepCopy(openAPIV3Schema).(map[string]any)
+	if opts.DenyRootAdditionalProperties {
+		addImplicitRootProperties(schema)
+	}
+	if opts.DenyAdditionalProperties || opts.DenyRootAdditionalProperties {
+		DenyAdditionalProperties(schema, !opts.DenyRootAdditionalProperties)
+	}
+	return replaceIntOrString(schema).(map[string]any)
+}
+
+// implicitRootProperties are the properties of all the resources, which CRDs usually do not declare
+var implicitRootProperties = map[string]any{
+	"apiVersion": map[string]any{"type": "string"},
+	"kind":       map[string]any{"type": "string"},
+	"metadata":   map[string]any{"type": "object"},
+}
+
+// addImplicitRootProperties adds the properties all resources have to the root of the schema,
+// if it defines properties but not these, so they are allowed when additional properties are not
+func addImplicitRootProperties(schema map[string]any) {
+	properties, ok := schema["properties"].(map[string]any)
+	if !ok {
+		return
+	}
+	for name, p := range implicitRootProperties {
+		if _, ok := properties[name]; !ok {
+			properties[name] = deepCopy(p)
+		}
+	}
+}
+
+func deepCopy(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for k, val := range v {
+			c[k] = deepCopy(val)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, val := range v {
+			c[i] = deepCopy(val)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// DenyAdditionalProperties sets additionalProperties to false in every object of the schema data
+// defining properties, unless additionalProperties is already set. The root object is left
+// unchanged if skip is true. This recreates the behaviour of kubectl
+// https://github.com/kubernetes/kubernetes/blob/225b9119d6a8f03fcbe3cc3d590c261965d928d0/pkg/kubectl/validation/schema.go#L312
+func DenyAdditionalProperties(data any, skip bool) {
+	obj, ok := data.(map[string]any)
+	if !ok {
+		return
+	}
+
+	if _, hasProperties := obj["properties"]; hasProperties && !skip {
+		if _, ok := obj["additionalProperties"]; !ok {
+			obj["additionalProperties"] = false
+		}
+	}
+	for _, v := range obj {
+		DenyAdditionalProperties(v, false)
+	}
+}
+
+// replaceIntOrString replaces properties using the int-or-string format, which is not
+// part of the JSON schema specification, with a schema accepting both strings and integers
+func replaceIntOrString(data any) any {
+	switch data := data.(type) {
+	case map[string]any:
+		for k, v := range data {
+			if obj, ok := v.(map[string]any); ok && obj["format"] == "int-or-string" {
+				data[k] = map[string]any{"oneOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "integer"}}}
+				continue
+			}
+			data[k] = replaceIntOrString(v)
+		}
+	case []any:
+		for i, v := range data {
+			data[i] = replaceIntOrString(v)
+		}
+	}
+	return data
+}
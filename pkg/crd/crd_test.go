@@ -0,0 +1,295 @@
+package crd
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func loadYAML(t *testing.T, path string) map[string]any {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed reading %s: %s", path, err)
+	}
+	var obj map[string]any
+	if err := yaml.Unmarshal(content, &obj); err != nil {
+		t.Fatalf("failed parsing %s: %s", path, err)
+	}
+	return obj
+}
+
+func loadJSON(t *testing.T, path string) map[string]any {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed reading %s: %s", path, err)
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(content, &obj); err != nil {
+		t.Fatalf("failed parsing %s: %s", path, err)
+	}
+	return obj
+}
+
+// The expected schemas were generated with openapi2jsonschema.py, the Python script crd2schema replaces
+func TestToSchemasMatchesOpenAPI2JSONSchema(t *testing.T) {
+	crd := loadYAML(t, "testdata/prometheus-operator-0prometheusCustomResourceDefinition.yaml")
+
+	for _, testCase := range []struct {
+		name     string
+		opts     Opts
+		expected string
+	}{
+		{
+			"default",
+			Opts{DenyAdditionalProperties: true},
+			"testdata/prometheus_v1-expected.json",
+		},
+		{
+			"deny additional properties at the root",
+			Opts{DenyAdditionalProperties: true, DenyRootAdditionalProperties: true},
+			"testdata/prometheus_v1-denyRootAdditionalProperties.json",
+		},
+	} {
+		schemas, err := ToSchemas(crd, testCase.opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", testCase.name, err)
+		}
+		if len(schemas) != 1 {
+			t.Fatalf("%s: expected 1 schema, got %d", testCase.name, len(schemas))
+		}
+
+		filename, err := schemas[0].Filename(DefaultFilenameFormat)
+		if err != nil || filename != "prometheus_v1.json" {
+			t.Errorf("%s: expected filename prometheus_v1.json, got %s (%v)", testCase.name, filename, err)
+		}
+
+		// Round-trip through JSON so that numbers have the same type as in the expected schema
+		b, _ := json.Marshal(schemas[0].Schema)
+		var got map[string]any
+		json.Unmarshal(b, &got)
+		if !reflect.DeepEqual(got, loadJSON(t, testCase.expected)) {
+			t.Errorf("%s: schema does not match %s", testCase.name, testCase.expected)
+		}
+	}
+}
+
+func TestToSchemas(t *testing.T) {
+	for _, testCase := range []struct {
+		name   string
+		crd    string
+		expect []string
+	}{
+		{
+			"v1 CRD with a schema per version, ignoring versions not served",
+			`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+spec:
+  group: stable.example.com
+  names:
+    kind: CronTab
+  versions:
+  - name: v1
+    served: true
+    schema:
+      openAPIV3Schema:
+        type: object
+  - name: v2
+    served: true
+    schema:
+      openAPIV3Schema:
+        type: object
+  - name: v0
+    served: false
+    schema:
+      openAPIV3Schema:
+        type: object
+`,
+			[]string{"stable.example.com/v1 CronTab", "stable.example.com/v2 CronTab"},
+		},
+		{
+			"v1beta1 CRD sharing its validation across versions",
+			`apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+spec:
+  group: stable.example.com
+  names:
+    kind: CronTab
+  validation:
+    openAPIV3Schema:
+      type: object
+  versions:
+  - name: v1beta1
+    served: true
+  - name: v1
+    served: true
+`,
+			[]string{"stable.example.com/v1beta1 CronTab", "stable.example.com/v1 CronTab"},
+		},
+		{
+			"v1beta1 CRD with a single version",
+			`apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+spec:
+  group: stable.example.com
+  version: v1
+  names:
+    kind: CronTab
+  validation:
+    openAPIV3Schema:
+      type: object
+`,
+			[]string{"stable.example.com/v1 CronTab"},
+		},
+		{
+			"CRD without schema",
+			`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+spec:
+  group: stable.example.com
+  names:
+    kind: CronTab
+  versions:
+  - name: v1
+    served: true
+`,
+			[]string{},
+		},
+	} {
+		var obj map[string]any
+		if err := yaml.Unmarshal([]byte(testCase.crd), &obj); err != nil {
+			t.Fatal(err)
+		}
+		schemas, err := ToSchemas(obj, Opts{})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", testCase.name, err)
+		}
+		got := []string{}
+		for _, s := range schemas {
+			got = append(got, s.APIVersion()+" "+s.Kind)
+		}
+		if !reflect.DeepEqual(got, testCase.expect) {
+			t.Errorf("%s: expected %+v, got %+v", testCase.name, testCase.expect, got)
+		}
+	}
+}
+
+func TestReplaceIntOrString(t *testing.T) {
+	for _, testCase := range []struct {
+		input, expect map[string]any
+	}{
+		{
+			map[string]any{"something": map[string]any{"format": "int-or-string"}},
+			map[string]any{"something": map[string]any{"oneOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "integer"}}}},
+		},
+		{
+			map[string]any{"something": map[string]any{"format": "string"}},
+			map[string]any{"something": map[string]any{"format": "string"}},
+		},
+	} {
+		if got := replaceIntOrString(testCase.input); !reflect.DeepEqual(got, testCase.expect) {
+			t.Errorf("expected %+v, got %+v", testCase.expect, got)
+		}
+	}
+}
+
+func TestDenyAdditionalProperties(t *testing.T) {
+	for _, testCase := range []struct {
+		input, expect map[string]any
+	}{
+		{
+			map[string]any{"something": map[string]any{"properties": map[string]any{}}},
+			map[string]any{"something": map[string]any{"properties": map[string]any{}, "additionalProperties": false}},
+		},
+		{
+			map[string]any{"something": map[string]any{"somethingelse": map[string]any{}}},
+			map[string]any{"something": map[string]any{"somethingelse": map[string]any{}}},
+		},
+		{
+			map[string]any{"properties": map[string]any{}},
+			map[string]any{"properties": map[string]any{}},
+		},
+	} {
+		DenyAdditionalProperties(testCase.input, true)
+		if !reflect.DeepEqual(testCase.input, testCase.expect) {
+			t.Errorf("expected %+v, got %+v", testCase.expect, testCase.input)
+		}
+	}
+}
+
+func TestFilename(t *testing.T) {
+	s := Schema{Group: "monitoring.coreos.com", Version: "v1", Kind: "Prometheus"}
+	for _, testCase := range []struct {
+		format, expect string
+	}{
+		{DefaultFilenameFormat, "prometheus_v1.json"},
+		{"{{ .ResourceKind }}{{ .KindSuffix }}", "prometheus-monitoring-v1.json"},
+		{"{{ .Group }}/{{ .ResourceKind }}_{{ .ResourceAPIVersion }}", "monitoring.coreos.com/prometheus_v1.json"},
+	} {
+		got, err := s.Filename(testCase.format)
+		if err != nil || got != testCase.expect {
+			t.Errorf("format %s: expected %s, got %s (%v)", testCase.format, testCase.expect, got, err)
+		}
+	}
+}
+
+func TestFilenameOutsideOfOutputFolder(t *testing.T) {
+	for _, testCase := range []struct {
+		schema Schema
+		format string
+	}{
+		{Schema{Group: "example.com", Version: "v1", Kind: "../../etc/cron.d/job"}, DefaultFilenameFormat},
+		{Schema{Group: "example.com", Version: `..\..\v1`, Kind: "Job"}, DefaultFilenameFormat},
+		{Schema{Group: "..", Version: "v1", Kind: "Job"}, "{{ .Group }}/{{ .ResourceKind }}"},
+		{Schema{Group: "example.com", Version: "v1", Kind: "Job"}, "/etc/{{ .ResourceKind }}"},
+		{Schema{Group: "example.com", Version: "v1", Kind: "Job"}, "../{{ .ResourceKind }}"},
+	} {
+		if got, err := testCase.schema.Filename(testCase.format); err == nil {
+			t.Errorf("%+v, format %s: expected an error, got %s", testCase.schema, testCase.format, got)
+		}
+	}
+}
+
+func TestImplicitRootProperties(t *testing.T) {
+	crd := map[string]any{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"spec": map[string]any{
+			"group": "stable.example.com",
+			"names": map[string]any{"kind": "CronTab"},
+			"versions": []any{map[string]any{
+				"name":   "v1",
+				"served": true,
+				"schema": map[string]any{"openAPIV3Schema": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"spec": map[string]any{"type": "object"}},
+				}},
+			}},
+		},
+	}
+
+	for _, testCase := range []struct {
+		name   string
+		opts   Opts
+		expect []string
+	}{
+		{"root open", Opts{DenyAdditionalProperties: true}, []string{"spec"}},
+		{"root closed", Opts{DenyAdditionalProperties: true, DenyRootAdditionalProperties: true}, []string{"apiVersion", "kind", "metadata", "spec"}},
+	} {
+		schemas, err := ToSchemas(crd, testCase.opts)
+		if err != nil || len(schemas) != 1 {
+			t.Fatalf("%s: expected 1 schema, got %d (%v)", testCase.name, len(schemas), err)
+		}
+		got := []string{}
+		for name := range schemas[0].Schema["properties"].(map[string]any) {
+			got = append(got, name)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, testCase.expect) {
+			t.Errorf("%s: expected properties %v, got %v", testCase.name, testCase.expect, got)
+		}
+	}
+}
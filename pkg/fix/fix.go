@@ -0,0 +1,402 @@
+// Package fix rewrites resources to fix the validation errors that can be fixed
+// automatically, editing the files they were read from in place. Edits are made
+// line by line, so comments, key order and formatting are preserved.
+package fix
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yannh/kubeconform/pkg/resource"
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+// renamedFields lists fields of Kubernetes resources that were renamed between two versions
+// of their API, indexed by Kind, then by the path of the former field, in which * stands for
+// the index of an item of a list
+var renamedFields = map[string]map[string]string{
+	"EndpointSlice":              {"/endpoints/*/topology": "deprecatedTopology"},
+	"Ingress":                    {"/spec/backend": "defaultBackend"},
+	"PriorityLevelConfiguration": {"/spec/limited/assuredConcurrencyShares": "nominalConcurrencyShares"},
+}
+
+// Change is an edit fixing a validation error of a resource, replacing Old with New
+// between the byte offsets Start and End of a line of the file the resource was read from
+type Change struct {
+	Resource    string // Kind and name of the resource
+	Path        string // JSON pointer to the value that was fixed
+	Description string
+	Line        int
+	Start, End  int
+	Old, New    string
+}
+
+var (
+	typeErrorRegexp = regexp.MustCompile(`^got (\w+), want (.+)$`)
+	quotedRegexp    = regexp.MustCompile(`'([^']*)'`)
+	integerRegexp   = regexp.MustCompile(`^-?[0-9]+$`)
+	numberRegexp    = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+	indexRegexp     = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Changes returns the changes fixing the validation errors of result that can be fixed:
+// values of a type that can be converted to the expected type, fields that were renamed,
+// and deprecated apiVersions that have a replacement. Fields are only renamed if the
+// resource, validated again by v with the changes, is valid under the new name.
+func Changes(result validator.Result, v validator.Validator) []Change {
+	res := result.Resource
+	sig, err := res.Signature()
+	if err != nil {
+		return nil
+	}
+	name := fmt.Sprintf("%s %s", sig.Kind, sig.Name)
+
+	changes := []Change{}
+	renames := map[int]string{} // path of the renamed fields, indexed by change
+	for _, ve := range result.ValidationErrors {
+		switch {
+		case ve.Rule == validator.DeprecatedAPIVersionRule:
+			replacement := validator.ReplacementAPIVersion(sig.Version, sig.Kind)
+			if replacement == "" {
+				continue
+			}
+			if c, ok := valueChange(&res, ve.Path, func(_ string, quoted bool) (string, bool) {
+				if quoted {
+					return strconv.Quote(replacement), true
+				}
+				return replacement, true
+			}); ok {
+				// Only the apiVersion is replaced: fields that changed between the two versions, such as
+				// the selector Deployments require since apps/v1, still need to be fixed manually
+				c.Description = fmt.Sprintf("replaced apiVersion %s with %s, fields that differ between these versions are not migrated", sig.Version, replacement)
+				changes = append(changes, c)
+			}
+
+		case ve.Rule == "" && ve.Keyword == "type":
+			m := typeErrorRegexp.FindStringSubmatch(ve.Msg)
+			if m == nil {
+				continue
+			}
+			want := strings.Split(m[2], " or ")
+			if c, ok := valueChange(&res, ve.Path, func(value string, quoted bool) (string, bool) { return convert(value, quoted, m[1], want) }); ok {
+				c.Description = fmt.Sprintf("converted %s to %s", c.Old, c.New)
+				changes = append(changes, c)
+			}
+
+		case ve.Rule == "" && ve.Keyword == "additionalProperties":
+			for _, m := range quotedRegexp.FindAllStringSubmatch(ve.Msg, -1) {
+				newName, ok := renamedField(sig.Kind, ve.Path+"/"+m[1])
+				if !ok {
+					continue
+				}
+				line, start, end, ok := res.KeyPosition(ve.Path + "/" + m[1])
+				if !ok {
+					continue
+				}
+				old := lineOf(&res, line)[start:end]
+				renames[len(changes)] = ve.Path + "/" + newName
+				changes = append(changes, Change{
+					Path:        ve.Path + "/" + m[1],
+					Description: fmt.Sprintf("renamed %s to %s", m[1], newName),
+					Line:        line,
+					Start:       start,
+					End:         end,
+					Old:         old,
+					New:         requote(old, newName),
+				})
+			}
+		}
+	}
+
+	if len(renames) > 0 {
+		changes = dropInvalidRenames(v, result, changes, renames)
+	}
+
+	for i := range changes {
+		changes[i].Resource = name
+	}
+	return changes
+}
+
+// renamedField returns the new name of the field at path, in a resource of the Kind kind
+func renamedField(kind, path string) (string, bool) {
+	for pattern, newName := range renamedFields[kind] {
+		if matchPath(pattern, path) {
+			return newName, true
+		}
+	}
+	return "", false
+}
+
+// matchPath returns true if path matches pattern, in which * matches the index of an item of a list
+func matchPath(pattern, path string) bool {
+	p, q := strings.Split(pattern, "/"), strings.Split(path, "/")
+	if len(p) != len(q) {
+		return false
+	}
+	for i := range p {
+		if p[i] != q[i] && (p[i] != "*" || !indexRegexp.MatchString(q[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// dropInvalidRenames returns changes without the renames of fields that are still invalid under
+// their new name, such as the backend of an Ingress still using the serviceName and servicePort of
+// extensions/v1beta1 once renamed defaultBackend. The resource of result is validated again by v,
+// with all the changes applied. renames holds the new path of the renamed fields, indexed by change.
+func dropInvalidRenames(v validator.Validator, result validator.Result, changes []Change, renames map[int]string) []Change {
+	res := result.Resource
+
+	// Changes are positioned in the file, the resource starts at line res.Line of it
+	local := slices.Clone(changes)
+	for i := range local {
+		local[i].Line = local[i].Line - res.Line + 1
+	}
+	var fixed validator.Result
+	if content, _, err := Apply(res.Bytes, local); err == nil {
+		fixed = v.ValidateResourceForVersion(resource.Resource{Path: res.Path, Bytes: content, Line: res.Line}, result.KubernetesVersion)
+	}
+	// Fields are not renamed if the resource could not be validated again
+	validated := fixed.Status == validator.Valid || fixed.Status == validator.Warning || fixed.Status == validator.Invalid
+
+	kept := []Change{}
+	for i, c := range changes {
+		path, renamed := renames[i]
+		if !renamed {
+			kept = append(kept, c)
+			continue
+		}
+		invalid := slices.ContainsFunc(fixed.ValidationErrors, func(ve validator.ValidationError) bool {
+			return ve.Severity != validator.SeverityWarning && (ve.Path == path || strings.HasPrefix(ve.Path, path+"/"))
+		})
+		if validated && !invalid {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// valueChange returns a change replacing the scalar value at path by the value returned by
+// replace, which is given the value without its quotes, and whether it was quoted
+func valueChange(res *resource.Resource, path string, replace func(value string, quoted bool) (string, bool)) (Change, bool) {
+	line, start, end, ok := res.ValuePosition(path)
+	if !ok {
+		return Change{}, false
+	}
+	old := lineOf(res, line)[start:end]
+	value, quoted := unquote(old)
+	replacement, ok := replace(value, quoted)
+	if !ok {
+		return Change{}, false
+	}
+	return Change{Path: path, Line: line, Start: start, End: end, Old: old, New: replacement}, true
+}
+
+// convert returns value, of the JSON type got, written as one of the types want
+func convert(value string, quoted bool, got string, want []string) (string, bool) {
+	if got == "string" && quoted {
+		for _, w := range want {
+			switch {
+			case w == "integer" && integerRegexp.MatchString(value),
+				w == "number" && numberRegexp.MatchString(value),
+				w == "boolean" && (value == "true" || value == "false"):
+				return value, true
+			}
+		}
+		return "", false
+	}
+
+	if !quoted && slices.Contains(want, "string") && (got == "integer" || got == "number" || got == "boolean") {
+		return strconv.Quote(value), true
+	}
+	return "", false
+}
+
+// lineOf returns the line of the file the resource res was read from
+func lineOf(res *resource.Resource, line int) string {
+	return strings.TrimRight(strings.Split(string(res.Bytes), "\n")[line-res.Line], "\r")
+}
+
+func unquote(s string) (string, bool) {
+	if len(s) < 2 {
+		return s, false
+	}
+	switch s[0] {
+	case '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), true
+	case '"':
+		if u, err := strconv.Unquote(s); err == nil {
+			return u, true
+		}
+		return s[1 : len(s)-1], true
+	}
+	return s, false
+}
+
+// requote returns s with the same quotes as old
+func requote(old, s string) string {
+	if len(old) > 0 && (old[0] == '"' || old[0] == '\'') {
+		return string(old[0]) + s + string(old[0])
+	}
+	return s
+}
+
+// Apply applies the changes to content, the content of the file they were computed from, and
+// returns the changes it applied. Changes overlapping a change already applied are ignored.
+func Apply(content []byte, changes []Change) ([]byte, []Change, error) {
+	changes = slices.Clone(changes)
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Line != changes[j].Line {
+			return changes[i].Line < changes[j].Line
+		}
+		return changes[i].Start > changes[j].Start
+	})
+
+	lines := strings.Split(string(content), "\n")
+	applied := []Change{}
+	lastLine, lastStart := 0, 0
+	for _, c := range changes {
+		if c.Line == lastLine && c.End > lastStart {
+			continue
+		}
+		if c.Line < 1 || c.Line > len(lines) || c.End > len(lines[c.Line-1]) || lines[c.Line-1][c.Start:c.End] != c.Old {
+			return nil, nil, fmt.Errorf("line %d does not contain %s, the file was modified", c.Line, c.Old)
+		}
+		lines[c.Line-1] = lines[c.Line-1][:c.Start] + c.New + lines[c.Line-1][c.End:]
+		lastLine, lastStart = c.Line, c.Start
+		applied = append(applied, c)
+	}
+
+	return []byte(strings.Join(lines, "\n")), applied, nil
+}
+
+// Fixer collects the changes fixing the validation errors of the results written to it, and
+// applies them to the files the resources were read from when flushed, reporting the changes
+// to w. It implements the same interface as the outputs of kubeconform.
+type Fixer struct {
+	sync.Mutex
+	w       io.Writer
+	v       validator.Validator // validates the resources with renamed fields again
+	changes map[string][]Change // indexed by file path
+}
+
+// New returns a Fixer reporting the changes it makes to w. The resources are validated
+// again by v, the validator of the results, to check the fields it renames.
+func New(w io.Writer, v validator.Validator) *Fixer {
+	return &Fixer{w: w, v: v, changes: map[string][]Change{}}
+}
+
+// Write collects the changes fixing the validation errors of result
+func (f *Fixer) Write(result validator.Result) error {
+	changes := Changes(result, f.v)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	for _, c := range changes {
+		duplicate := slices.ContainsFunc(f.changes[result.Resource.Path], func(other Change) bool {
+			return other.Line == c.Line && other.Start == c.Start
+		})
+		if !duplicate {
+			f.changes[result.Resource.Path] = append(f.changes[result.Resource.Path], c)
+		}
+	}
+	return nil
+}
+
+// Flush rewrites the files with the changes collected, and reports them
+func (f *Fixer) Flush() error {
+	f.Lock()
+	defer f.Unlock()
+
+	paths := []string{}
+	for p := range f.changes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		changes, err := fixFile(p, f.changes[p])
+		if err != nil {
+			return fmt.Errorf("failed fixing %s: %s", p, err)
+		}
+
+		sort.SliceStable(changes, func(i, j int) bool {
+			if changes[i].Line != changes[j].Line {
+				return changes[i].Line < changes[j].Line
+			}
+			return changes[i].Start < changes[j].Start
+		})
+		for _, c := range changes {
+			fmt.Fprintf(f.w, "%s:%d - %s: %s: %s\n", p, c.Line, c.Resource, c.Path, c.Description)
+		}
+	}
+	f.changes = map[string][]Change{}
+
+	return nil
+}
+
+// fixFile applies the changes to the file at p, and returns the changes it applied. The fixed
+// content is written to a temporary file first, renamed to p once complete, so the file is
+// left unchanged if kubeconform fails or is interrupted while writing it. Symbolic links are
+// followed, so that the file they point to is fixed rather than replaced. The temporary file is
+// given the permissions and the owner of the file; if the owner can not be set, the file is
+// written in place.
+func fixFile(p string, changes []Change) ([]Change, error) {
+	p, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	fixed, applied, err := Apply(content, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name()) // fails once renamed
+	if _, err := tmp.Write(fixed); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := chown(tmp, fi); err != nil {
+		tmp.Close()
+		if err := os.WriteFile(p, fixed, fi.Mode().Perm()); err != nil {
+			return nil, err
+		}
+		return applied, nil
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
@@ -0,0 +1,249 @@
+package fix
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yannh/kubeconform/pkg/resource"
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+const ingress = `# The ingress of the web application
+apiVersion: extensions/v1beta1 # deprecated
+kind: Ingress
+metadata:
+  name: web
+spec:
+  backend:
+    service:
+      name: web
+      port:
+        number: "80"
+  rules:
+  - host: "example.com"
+    http:
+      paths:
+      - path: /
+        pathType: 42 # not a path type
+        backend:
+          service:
+            name: web
+            port:
+              number: 'eighty'
+`
+
+// newTestValidator returns a validator with a schema of Ingresses in which backends were renamed defaultBackend
+func newTestValidator(t *testing.T) validator.Validator {
+	dir := t.TempDir()
+	schema := `{
+  "type": "object",
+  "properties": {
+    "spec": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "defaultBackend": {"type": "object", "additionalProperties": false, "properties": {"service": {"type": "object"}}},
+        "rules": {"type": "array"}
+      }
+    }
+  }
+}`
+	if err := os.WriteFile(filepath.Join(dir, "ingress-extensions-v1beta1.json"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v, err := validator.New([]string{filepath.Join(dir, "{{ .ResourceKind }}{{ .KindSuffix }}.json")}, validator.Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestFix(t *testing.T) {
+	v := newTestValidator(t)
+
+	for _, testCase := range []struct {
+		name             string
+		validationErrors []validator.ValidationError
+		expect           string
+		expectChanges    int
+	}{
+		{
+			"deprecated apiVersion",
+			[]validator.ValidationError{{Path: "/apiVersion", Rule: validator.DeprecatedAPIVersionRule, Severity: validator.SeverityWarning}},
+			"apiVersion: networking.k8s.io/v1 # deprecated",
+			1,
+		},
+		{
+			"quoted integer",
+			[]validator.ValidationError{{Path: "/spec/backend/service/port/number", Msg: "got string, want integer", Keyword: "type"}},
+			"        number: 80",
+			1,
+		},
+		{
+			"integer instead of a string, followed by a comment",
+			[]validator.ValidationError{{Path: "/spec/rules/0/http/paths/0/pathType", Msg: "got integer, want string", Keyword: "type"}},
+			`        pathType: "42" # not a path type`,
+			1,
+		},
+		{
+			"renamed field",
+			[]validator.ValidationError{{Path: "/spec", Msg: "additional properties 'backend', 'tls2' not allowed", Keyword: "additionalProperties"}},
+			"  defaultBackend:",
+			1,
+		},
+		{
+			"field with the name of a renamed field, at another path",
+			[]validator.ValidationError{{Path: "/spec/rules/0/http/paths/0", Msg: "additional properties 'backend' not allowed", Keyword: "additionalProperties"}},
+			"",
+			0,
+		},
+		{
+			"value that can not be converted",
+			[]validator.ValidationError{{Path: "/spec/rules/0/http/paths/0/backend/service/port/number", Msg: "got string, want integer", Keyword: "type"}},
+			"",
+			0,
+		},
+		{
+			"errors that can not be fixed",
+			[]validator.ValidationError{
+				{Path: "/spec", Msg: "missing property 'rules'", Keyword: "required"},
+				{Path: "/spec/rules/0/host", Msg: "got string, want integer", Keyword: "type", Rule: "host-rule"},
+			},
+			"",
+			0,
+		},
+	} {
+		res := resource.Resource{Path: "ingress.yaml", Bytes: []byte(ingress), Line: 1}
+		changes := Changes(validator.Result{Resource: res, Status: validator.Invalid, ValidationErrors: testCase.validationErrors}, v)
+		if len(changes) != testCase.expectChanges {
+			t.Errorf("%s: expected %d changes, got %+v", testCase.name, testCase.expectChanges, changes)
+			continue
+		}
+
+		fixed, _, err := Apply([]byte(ingress), changes)
+		if err != nil {
+			t.Errorf("%s: failed applying changes: %s", testCase.name, err)
+			continue
+		}
+		if testCase.expect != "" && !bytes.Contains(fixed, []byte("\n"+testCase.expect+"\n")) {
+			t.Errorf("%s: expected a line %s, got:\n%s", testCase.name, testCase.expect, fixed)
+		}
+		if len(bytes.Split(fixed, []byte("\n"))) != len(bytes.Split([]byte(ingress), []byte("\n"))) {
+			t.Errorf("%s: expected the number of lines to be preserved, got:\n%s", testCase.name, fixed)
+		}
+	}
+}
+
+func TestRenameInvalidField(t *testing.T) {
+	doc := "apiVersion: extensions/v1beta1\nkind: Ingress\nmetadata:\n  name: web\nspec:\n  backend:\n    serviceName: web\n    servicePort: 80\n"
+	res := resource.Resource{Path: "ingress.yaml", Bytes: []byte(doc), Line: 1}
+	validationErrors := []validator.ValidationError{{Path: "/spec", Msg: "additional properties 'backend' not allowed", Keyword: "additionalProperties"}}
+
+	// The backend still uses the fields of extensions/v1beta1, it is as invalid once renamed defaultBackend
+	if changes := Changes(validator.Result{Resource: res, Status: validator.Invalid, ValidationErrors: validationErrors}, newTestValidator(t)); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestApplyModifiedFile(t *testing.T) {
+	changes := []Change{{Line: 1, Start: 12, End: 30, Old: "extensions/v1beta1", New: "networking.k8s.io/v1"}}
+	if _, _, err := Apply([]byte("apiVersion: networking.k8s.io/v1\n"), changes); err == nil {
+		t.Errorf("expected an error applying changes to a modified file")
+	}
+}
+
+func TestApplyOverlappingChanges(t *testing.T) {
+	changes := []Change{
+		{Path: "/a", Line: 1, Start: 3, End: 8, Old: "hello", New: "bye"},
+		{Path: "/b", Line: 1, Start: 6, End: 11, Old: "lo wo", New: "xx"},
+		{Path: "/c", Line: 2, Start: 0, End: 1, Old: "c", New: "C"},
+	}
+	fixed, applied, err := Apply([]byte("a: hello world\nc: d\n"), changes)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(fixed) != "a: helxxrld\nC: d\n" {
+		t.Errorf("unexpected content %s", fixed)
+	}
+	if !reflect.DeepEqual(applied, []Change{changes[1], changes[2]}) {
+		t.Errorf("expected the changes applied to be %+v, got %+v", []Change{changes[1], changes[2]}, applied)
+	}
+}
+
+func TestFixer(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "resources.yaml")
+	content := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n---\n" + ingress
+	if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	res := resource.Resource{Path: p, Bytes: []byte(ingress), Line: 6}
+	validationErrors := []validator.ValidationError{
+		{Path: "/apiVersion", Rule: validator.DeprecatedAPIVersionRule, Severity: validator.SeverityWarning},
+		{Path: "/spec/backend/service/port/number", Msg: "got string, want integer", Keyword: "type"},
+	}
+
+	w := new(bytes.Buffer)
+	f := New(w, newTestValidator(t))
+	f.Write(validator.Result{Resource: res, Status: validator.Invalid, ValidationErrors: validationErrors})
+	f.Write(validator.Result{Resource: res, Status: validator.Invalid, ValidationErrors: validationErrors})
+	if err := f.Flush(); err != nil {
+		t.Fatalf("failed fixing file: %s", err)
+	}
+
+	expectReport := p + ":7 - Ingress web: /apiVersion: replaced apiVersion extensions/v1beta1 with networking.k8s.io/v1, fields that differ between these versions are not migrated\n" +
+		p + ":16 - Ingress web: /spec/backend/service/port/number: converted \"80\" to 80\n"
+	if w.String() != expectReport {
+		t.Errorf("expected report:\n%s\ngot:\n%s", expectReport, w)
+	}
+
+	fixed, _ := os.ReadFile(p)
+	expectContent := bytes.Replace([]byte(content), []byte("extensions/v1beta1 #"), []byte("networking.k8s.io/v1 #"), 1)
+	expectContent = bytes.Replace(expectContent, []byte(`number: "80"`), []byte("number: 80"), 1)
+	if !bytes.Equal(fixed, expectContent) {
+		t.Errorf("expected:\n%s\ngot:\n%s", expectContent, fixed)
+	}
+
+	// The fixed file replaces the original one, with the same permissions
+	if fi, err := os.Stat(p); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expected the permissions of the file to be preserved, got %v: %v", fi.Mode().Perm(), err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(p)); len(entries) != 1 {
+		t.Errorf("expected no temporary file to be left, got %+v", entries)
+	}
+}
+
+func TestFixerSymlink(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "ingress.yaml")
+	if err := os.WriteFile(p, []byte(ingress), 0640); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.yaml")
+	if err := os.Symlink("ingress.yaml", link); err != nil {
+		t.Skipf("symbolic links not supported: %s", err)
+	}
+
+	res := resource.Resource{Path: link, Bytes: []byte(ingress), Line: 1}
+	validationErrors := []validator.ValidationError{{Path: "/spec/backend/service/port/number", Msg: "got string, want integer", Keyword: "type"}}
+	f := New(new(bytes.Buffer), newTestValidator(t))
+	f.Write(validator.Result{Resource: res, Status: validator.Invalid, ValidationErrors: validationErrors})
+	if err := f.Flush(); err != nil {
+		t.Fatalf("failed fixing file: %s", err)
+	}
+
+	// The file the link points to is fixed, the link is kept
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected %s to still be a symbolic link", link)
+	}
+	fixed, _ := os.ReadFile(p)
+	if !bytes.Contains(fixed, []byte("        number: 80\n")) {
+		t.Errorf("expected %s to be fixed, got:\n%s", p, fixed)
+	}
+	if fi, err := os.Stat(p); err != nil || fi.Mode().Perm() != 0640 {
+		t.Errorf("expected the permissions of the file to be preserved, got %v: %v", fi.Mode().Perm(), err)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package fix
+
+import (
+	"os"
+	"syscall"
+)
+
+// chown gives the file f the owner and the group of the file described by fi
+func chown(f *os.File, fi os.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return f.Chown(int(st.Uid), int(st.Gid))
+}
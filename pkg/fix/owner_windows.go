@@ -0,0 +1,10 @@
+//go:build windows
+
+package fix
+
+import "os"
+
+// chown does nothing, files created on Windows inherit the permissions of their folder
+func chown(f *os.File, fi os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,43 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestReadWriteMessage(t *testing.T) {
+	id := json.RawMessage("1")
+	var buf bytes.Buffer
+	if err := writeMessage(&buf, &message{ID: &id, Method: "initialize", Params: json.RawMessage(`{}`)}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := "Content-Length: 58\r\n\r\n"; !strings.HasPrefix(buf.String(), expected) {
+		t.Errorf("expected message to start with %q, got %q", expected, buf.String())
+	}
+
+	msg, err := readMessage(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if msg.Method != "initialize" || string(*msg.ID) != "1" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	for _, testCase := range []struct {
+		name  string
+		input string
+	}{
+		{"missing Content-Length", "Content-Type: application/json\r\n\r\n{}"},
+		{"invalid Content-Length", "Content-Length: abc\r\n\r\n{}"},
+		{"invalid header", "Content-Length 2\r\n\r\n{}"},
+		{"truncated body", "Content-Length: 10\r\n\r\n{}"},
+		{"invalid JSON", "Content-Length: 2\r\n\r\n{]"},
+	} {
+		if _, err := readMessage(bufio.NewReader(strings.NewReader(testCase.input))); err == nil {
+			t.Errorf("test \"%s\": expected an error", testCase.name)
+		}
+	}
+}
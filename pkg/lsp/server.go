@@ -0,0 +1,247 @@
+// Package lsp implements a Language Server, publishing the errors found by kubeconform
+// in the Kubernetes manifests opened in an editor as diagnostics.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/yannh/kubeconform/pkg/resource"
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+// Server is a Language Server validating documents with a validator. The validator,
+// and the schemas it has loaded, are kept for the lifetime of the server.
+type Server struct {
+	sync.Mutex
+	v        validator.Validator
+	version  string
+	w        io.Writer
+	shutdown bool
+}
+
+// NewServer returns a Language Server validating documents with v. version is
+// reported to the editor when initialising the connection.
+func NewServer(v validator.Validator, version string) *Server {
+	return &Server{v: v, version: version}
+}
+
+// Serve reads messages from r and writes responses and diagnostics to w, until the
+// editor sends the exit notification or closes r. An error is returned if the
+// editor exits without requesting a shutdown first.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.w = w
+	reader := bufio.NewReader(r)
+	for {
+		msg, err := readMessage(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var rpcErr *responseError
+			if errors.As(err, &rpcErr) {
+				if err := s.send(&message{ID: &nullID, Error: rpcErr}); err != nil {
+					return err
+				}
+				continue
+			}
+			return fmt.Errorf("failed reading message: %s", err)
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("received exit notification before shutdown")
+			}
+			return nil
+		}
+
+		if err := s.handle(msg); err != nil {
+			return fmt.Errorf("failed writing message: %s", err)
+		}
+	}
+}
+
+var nullID = json.RawMessage("null")
+
+// handle processes a request or a notification
+func (s *Server) handle(msg *message) error {
+	switch msg.Method {
+	case "initialize":
+		result := initializeResult{}
+		result.Capabilities.TextDocumentSync.OpenClose = true
+		result.Capabilities.TextDocumentSync.Change = 1
+		result.ServerInfo.Name = "kubeconform"
+		result.ServerInfo.Version = s.version
+		return s.reply(msg, result)
+
+	case "shutdown":
+		s.shutdown = true
+		return s.reply(msg, nil)
+
+	case "textDocument/didOpen":
+		params := didOpenTextDocumentParams{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		return s.publish(params.TextDocument.URI, Diagnostics(s.v, uriToPath(params.TextDocument.URI), params.TextDocument.Text))
+
+	case "textDocument/didChange":
+		params := didChangeTextDocumentParams{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return s.publish(params.TextDocument.URI, Diagnostics(s.v, uriToPath(params.TextDocument.URI), text))
+
+	case "textDocument/didClose":
+		params := didCloseTextDocumentParams{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		return s.publish(params.TextDocument.URI, []Diagnostic{})
+	}
+
+	if msg.ID != nil { // other notifications are ignored
+		return s.send(&message{ID: msg.ID, Error: &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %s not supported", msg.Method)}})
+	}
+	return nil
+}
+
+func (s *Server) send(msg *message) error {
+	s.Lock()
+	defer s.Unlock()
+	return writeMessage(s.w, msg)
+}
+
+func (s *Server) reply(req *message, result any) error {
+	if req.ID == nil {
+		return nil
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.send(&message{ID: req.ID, Result: body})
+}
+
+func (s *Server) publish(uri string, diagnostics []Diagnostic) error {
+	params, err := json.Marshal(publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+	if err != nil {
+		return err
+	}
+	return s.send(&message{Method: "textDocument/publishDiagnostics", Params: params})
+}
+
+// uriToPath returns the path of the file identified by a file:// URI, used in
+// error messages. Other URIs are returned unchanged.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// Diagnostics validates the resources in text, the content of the file at path, and
+// returns a diagnostic for each validation error, and for each resource that could not be validated
+func Diagnostics(v validator.Validator, path string, text string) []Diagnostic {
+	lines := strings.Split(text, "\n")
+	diagnostics := []Diagnostic{}
+
+	resources, _ := resource.FromStream(context.Background(), path, strings.NewReader(text))
+	for res := range resources {
+		result := v.ValidateResource(res)
+		switch result.Status {
+		case validator.Invalid, validator.Warning:
+			located := false
+			for _, ve := range result.ValidationErrors {
+				if ve.Line == 0 {
+					continue
+				}
+				diagnostics = append(diagnostics, errorDiagnostic(lines, ve))
+				located = true
+			}
+			if !located && result.Status == validator.Invalid {
+				diagnostics = append(diagnostics, resourceDiagnostic(lines, result))
+			}
+
+		case validator.Error:
+			diagnostics = append(diagnostics, resourceDiagnostic(lines, result))
+		}
+	}
+
+	return diagnostics
+}
+
+// errorDiagnostic returns a diagnostic for the validation error ve, reported at its
+// position. Errors from policy rules use the rule id as code.
+func errorDiagnostic(lines []string, ve validator.ValidationError) Diagnostic {
+	msg := ve.Msg
+	if ve.Path != "" {
+		msg = fmt.Sprintf("%s: %s", ve.Path, ve.Msg)
+	}
+	severity, code := SeverityError, ve.Keyword
+	if ve.Severity == validator.SeverityWarning {
+		severity = SeverityWarning
+	}
+	if ve.Rule != "" {
+		code = ve.Rule
+	}
+	return Diagnostic{
+		Range:    lineRange(lines, ve.Line, ve.Column),
+		Severity: severity,
+		Code:     code,
+		Source:   "kubeconform",
+		Message:  msg,
+	}
+}
+
+// resourceDiagnostic returns a diagnostic for the first line of the resource of result
+func resourceDiagnostic(lines []string, result validator.Result) Diagnostic {
+	line := result.Resource.Line
+	if line == 0 {
+		line = 1
+	}
+	msg := ""
+	if result.Err != nil {
+		msg = result.Err.Error()
+	}
+	return Diagnostic{
+		Range:    lineRange(lines, line, 1),
+		Severity: SeverityError,
+		Source:   "kubeconform",
+		Message:  msg,
+	}
+}
+
+// lineRange returns the range from column to the end of line, ignoring trailing spaces.
+// line and column are 1-based, column being a byte offset.
+func lineRange(lines []string, line, column int) Range {
+	if line > len(lines) {
+		return Range{Start: Position{Line: line - 1}, End: Position{Line: line - 1}}
+	}
+
+	text := strings.TrimRight(lines[line-1], " \t\r")
+	if column < 1 || column > len(text)+1 {
+		column = 1
+	}
+	start := utf16Length(text[:column-1])
+	return Range{
+		Start: Position{Line: line - 1, Character: start},
+		End:   Position{Line: line - 1, Character: start + utf16Length(text[column-1:])},
+	}
+}
+
+// utf16Length returns the length of s in UTF-16 code units, the unit of character offsets in LSP
+func utf16Length(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
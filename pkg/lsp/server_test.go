@@ -0,0 +1,177 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yannh/kubeconform/pkg/resource"
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+func newTestValidator(t *testing.T) validator.Validator {
+	dir := t.TempDir()
+	schema := `{
+  "type": "object",
+  "properties": {
+    "metadata": {"type": "object", "properties": {"name": {"type": "string"}}},
+    "spec": {"type": "object", "properties": {"replicas": {"type": "integer"}}}
+  }
+}`
+	if err := os.WriteFile(filepath.Join(dir, "deployment-apps-v1.json"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v, err := validator.New([]string{filepath.Join(dir, "{{ .ResourceKind }}{{ .KindSuffix }}.json")}, validator.Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestDiagnostics(t *testing.T) {
+	v := newTestValidator(t)
+
+	for _, testCase := range []struct {
+		name     string
+		text     string
+		expected []Diagnostic
+	}{
+		{
+			"valid resource",
+			"apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 2\n",
+			[]Diagnostic{},
+		},
+		{
+			"invalid field, in the second document",
+			"apiVersion: apps/v1\nkind: Deployment\n---\napiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: \"two\"  \n",
+			[]Diagnostic{
+				{
+					Range:    Range{Start: Position{Line: 6, Character: 2}, End: Position{Line: 6, Character: 17}},
+					Severity: SeverityError,
+					Code:     "type",
+					Source:   "kubeconform",
+					Message:  "/spec/replicas: got string, want integer",
+				},
+			},
+		},
+		{
+			"character offsets in UTF-16 code units",
+			"apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: 42} # 🚀\n",
+			[]Diagnostic{
+				{
+					Range:    Range{Start: Position{Line: 2, Character: 11}, End: Position{Line: 2, Character: 25}},
+					Severity: SeverityError,
+					Code:     "type",
+					Source:   "kubeconform",
+					Message:  "/metadata/name: got number, want string",
+				},
+			},
+		},
+		{
+			"resource that can not be validated",
+			"apiVersion: apps/v1\nkind: Deployment\n---\nkind: Service\n",
+			[]Diagnostic{
+				{
+					Range:    Range{Start: Position{Line: 3, Character: 0}, End: Position{Line: 3, Character: 13}},
+					Severity: SeverityError,
+					Source:   "kubeconform",
+					Message:  "error while parsing: missing 'apiVersion' key",
+				},
+			},
+		},
+	} {
+		got := Diagnostics(v, "deployment.yaml", testCase.text)
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf("test \"%s\": expected %+v, got %+v", testCase.name, testCase.expected, got)
+		}
+	}
+}
+
+type mockRuleEngine struct{}
+
+func (mockRuleEngine) Evaluate(sig resource.Signature, obj map[string]interface{}) []validator.ValidationError {
+	return []validator.ValidationError{{Path: "/spec/replicas", Msg: "should be at least 2", Rule: "replicas", Severity: validator.SeverityWarning}}
+}
+
+func TestDiagnosticsWithRules(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "deployment-apps-v1.json"), []byte(`{"type": "object"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v, err := validator.New([]string{filepath.Join(dir, "{{ .ResourceKind }}{{ .KindSuffix }}.json")}, validator.Opts{RuleEngine: mockRuleEngine{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Diagnostic{
+		{
+			Range:    Range{Start: Position{Line: 3, Character: 2}, End: Position{Line: 3, Character: 13}},
+			Severity: SeverityWarning,
+			Code:     "replicas",
+			Source:   "kubeconform",
+			Message:  "/spec/replicas: should be at least 2",
+		},
+	}
+	got := Diagnostics(v, "deployment.yaml", "apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: 1\n")
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestServe(t *testing.T) {
+	var in bytes.Buffer
+	for _, msg := range []string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {}}`,
+		`{"jsonrpc": "2.0", "method": "initialized", "params": {}}`,
+		`{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": {"textDocument": {"uri": "file:///manifests/deployment.yaml", "text": "apiVersion: apps/v1\nkind: Deployment\nspec:\n  replicas: two\n"}}}`,
+		`{"jsonrpc": "2.0", "method": "textDocument/didChange", "params": {"textDocument": {"uri": "file:///manifests/deployment.yaml"}, "contentChanges": [{"text": "apiVersion: apps/v1\nkind: Deployment\n"}]}}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "textDocument/hover", "params": {}}`,
+		`{"jsonrpc": "2.0", "method": "textDocument/didClose", "params": {"textDocument": {"uri": "file:///manifests/deployment.yaml"}}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "shutdown"}`,
+		`{"jsonrpc": "2.0", "method": "exit"}`,
+	} {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+
+	var out bytes.Buffer
+	if err := NewServer(newTestValidator(t), "1.0.0").Serve(&in, &out); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"textDocumentSync":{"openClose":true,"change":1}},"serverInfo":{"name":"kubeconform","version":"1.0.0"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///manifests/deployment.yaml","diagnostics":[{"range":{"start":{"line":3,"character":2},"end":{"line":3,"character":15}},"severity":1,"code":"type","source":"kubeconform","message":"/spec/replicas: got string, want integer"}]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///manifests/deployment.yaml","diagnostics":[]}}`,
+		`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"method textDocument/hover not supported"}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///manifests/deployment.yaml","diagnostics":[]}}`,
+		`{"jsonrpc":"2.0","id":3,"result":null}`,
+	}
+	reader := bufio.NewReader(&out)
+	for i, e := range expected {
+		msg, err := readMessage(reader)
+		if err != nil {
+			t.Fatalf("message %d: unexpected error: %s", i, err)
+		}
+		got, _ := json.Marshal(msg)
+		if string(got) != e {
+			t.Errorf("message %d: expected %s, got %s", i, e, got)
+		}
+	}
+	if out.Len() != 0 {
+		t.Errorf("unexpected messages: %s", out.String())
+	}
+}
+
+func TestServeExitWithoutShutdown(t *testing.T) {
+	in := strings.NewReader("Content-Length: 33\r\n\r\n{\"jsonrpc\":\"2.0\",\"method\":\"exit\"}")
+	var out bytes.Buffer
+	if err := NewServer(newTestValidator(t), "").Serve(in, &out); err == nil {
+		t.Errorf("expected an error when exiting without a shutdown request")
+	}
+}
@@ -24,6 +24,7 @@ type jsono struct {
 	verbose                             bool
 	results                             []oresult
 	nValid, nInvalid, nErrors, nSkipped int
+	nWarnings                           int
 }
 
 // JSON will output the results of the validation as a JSON
@@ -60,6 +61,12 @@ func (o *jsono) Write(result validator.Result) error {
 			msg = result.Err.Error()
 		}
 		o.nErrors++
+	case validator.Warning:
+		st = "statusWarning"
+		if result.Err != nil {
+			msg = result.Err.Error()
+		}
+		o.nWarnings++
 	case validator.Skipped:
 		st = "statusSkipped"
 		o.nSkipped++
@@ -91,23 +98,26 @@ func (o *jsono) Flush() error {
 		jsonObj := struct {
 			Resources []oresult `json:"resources"`
 			Summary   struct {
-				Valid   int `json:"valid"`
-				Invalid int `json:"invalid"`
-				Errors  int `json:"errors"`
-				Skipped int `json:"skipped"`
+				Valid    int `json:"valid"`
+				Invalid  int `json:"invalid"`
+				Errors   int `json:"errors"`
+				Skipped  int `json:"skipped"`
+				Warnings int `json:"warnings,omitempty"`
 			} `json:"summary"`
 		}{
 			Resources: o.results,
 			Summary: struct {
-				Valid   int `json:"valid"`
-				Invalid int `json:"invalid"`
-				Errors  int `json:"errors"`
-				Skipped int `json:"skipped"`
+				Valid    int `json:"valid"`
+				Invalid  int `json:"invalid"`
+				Errors   int `json:"errors"`
+				Skipped  int `json:"skipped"`
+				Warnings int `json:"warnings,omitempty"`
 			}{
-				Valid:   o.nValid,
-				Invalid: o.nInvalid,
-				Errors:  o.nErrors,
-				Skipped: o.nSkipped,
+				Valid:    o.nValid,
+				Invalid:  o.nInvalid,
+				Errors:   o.nErrors,
+				Skipped:  o.nSkipped,
+				Warnings: o.nWarnings,
 			},
 		}
 
@@ -2,6 +2,7 @@ package output
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
 
 	"github.com/yannh/kubeconform/pkg/resource"
@@ -117,8 +118,10 @@ metadata:
 					},
 					ValidationErrors: []validator.ValidationError{
 						{
-							Path: "foo",
-							Msg:  "bar",
+							Path:   "foo",
+							Msg:    "bar",
+							Line:   4,
+							Column: 3,
 						},
 					},
 				},
@@ -135,7 +138,9 @@ metadata:
       "validationErrors": [
         {
           "path": "foo",
-          "msg": "bar"
+          "msg": "bar",
+          "line": 4,
+          "column": 3
         }
       ]
     }
@@ -147,6 +152,59 @@ metadata:
     "skipped": 0
   }
 }
+`,
+		},
+		{
+			"a deployment with warnings, with summary",
+			true,
+			false,
+			false,
+			[]validator.Result{
+				{
+					Resource: resource.Resource{
+						Path: "deployment.yml",
+						Bytes: []byte(`apiVersion: apps/v1beta1
+kind: Deployment
+metadata:
+  name: "my-app"
+`),
+					},
+					Status: validator.Warning,
+					Err:    fmt.Errorf("rule deprecated-api-version: apps/v1beta1 Deployment is deprecated"),
+					ValidationErrors: []validator.ValidationError{
+						{Path: "/apiVersion", Msg: "apps/v1beta1 Deployment is deprecated", Line: 1, Column: 1, Rule: "deprecated-api-version", Severity: validator.SeverityWarning},
+					},
+				},
+			},
+			`{
+  "resources": [
+    {
+      "filename": "deployment.yml",
+      "kind": "Deployment",
+      "name": "my-app",
+      "version": "apps/v1beta1",
+      "status": "statusWarning",
+      "msg": "rule deprecated-api-version: apps/v1beta1 Deployment is deprecated",
+      "validationErrors": [
+        {
+          "path": "/apiVersion",
+          "msg": "apps/v1beta1 Deployment is deprecated",
+          "line": 1,
+          "column": 1,
+          "rule": "deprecated-api-version",
+          "severity": "warning"
+        }
+      ]
+    }
+  ],
+  "summary": {
+    "valid": 0,
+    "invalid": 0,
+    "errors": 0,
+    "skipped": 0,
+    "warnings": 1
+  }
+}
 `,
 		},
 	} {
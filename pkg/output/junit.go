@@ -52,6 +52,7 @@ type TestCase struct {
 	Skipped   *TestCaseSkipped `xml:"skipped,omitempty"`
 	Error     *TestCaseError   `xml:"error,omitempty"`
 	Failure   []TestCaseError  `xml:"failure,omitempty"`
+	SystemOut string           `xml:"system-out,omitempty"` // warnings reported for the resource, JUnit having no warning element
 }
 
 type TestCaseSkipped struct {
@@ -116,9 +117,11 @@ func (o *junito) Write(result validator.Result) error {
 
 	switch result.Status {
 	case validator.Valid:
+	case validator.Warning:
+		testCase.SystemOut = junitErrorList(result)
 	case validator.Invalid:
 		o.suites[i].Failures++
-		failure := TestCaseError{Message: result.Err.Error()}
+		failure := TestCaseError{Message: result.Err.Error(), Content: junitErrorList(result)}
 		testCase.Failure = append(testCase.Failure, failure)
 	case validator.Error:
 		o.suites[i].Errors++
@@ -136,6 +139,15 @@ func (o *junito) Write(result validator.Result) error {
 	return nil
 }
 
+// junitErrorList returns the validation errors of result, one per line
+func junitErrorList(result validator.Result) string {
+	content := ""
+	for _, e := range errorList(result) {
+		content += e + "\n"
+	}
+	return content
+}
+
 // Flush outputs the results as XML
 func (o *junito) Flush() error {
 	runtime := time.Now().Sub(o.startTime)
@@ -147,6 +147,37 @@ metadata:
 				"  </testsuite>\n" +
 				"</testsuites>\n",
 		},
+		{
+			"an invalid deployment, with the position of the errors",
+			false,
+			false,
+			false,
+			[]validator.Result{
+				{
+					Resource: resource.Resource{
+						Path: "deployment.yml",
+						Bytes: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: "my-app"
+`),
+					},
+					Status: validator.Invalid,
+					Err:    fmt.Errorf("deployment.yml is invalid"),
+					ValidationErrors: []validator.ValidationError{
+						{Path: "", Msg: "missing property 'spec'", Line: 1, Column: 1},
+						{Path: "/metadata/name", Msg: "got number, want string"},
+					},
+				},
+			},
+			"<testsuites name=\"kubeconform\" time=\"\" tests=\"1\" failures=\"1\" disabled=\"0\" errors=\"0\">\n" +
+				"  <testsuite name=\"deployment.yml\" id=\"1\" tests=\"1\" failures=\"1\" errors=\"0\" disabled=\"0\" skipped=\"0\">\n" +
+				"    <testcase name=\"my-app\" classname=\"Deployment@apps/v1\" time=\"\">\n" +
+				"      <failure message=\"deployment.yml is invalid\" type=\"\">deployment.yml:1:1: missing property &#39;spec&#39;&#xA;deployment.yml: /metadata/name: got number, want string&#xA;</failure>\n" +
+				"    </testcase>\n" +
+				"  </testsuite>\n" +
+				"</testsuites>\n",
+		},
 	} {
 		w := new(bytes.Buffer)
 		o := junitOutput(w, testCase.withSummary, testCase.isStdin, testCase.verbose)
@@ -0,0 +1,313 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+// matrixKey identifies a resource across the results of the different Kubernetes versions
+type matrixKey struct {
+	path, kind, namespace, name string
+	line                        int
+}
+
+type matrixRow struct {
+	path, kind, version, name string
+	line                      int
+	results                   map[string]validator.Result // indexed by Kubernetes version
+}
+
+type matrixo struct {
+	sync.Mutex
+	w           io.Writer
+	asJSON      bool
+	withSummary bool
+	isStdin     bool
+	verbose     bool
+	versions    []string
+	files       map[string]bool
+	rows        map[matrixKey]*matrixRow
+	errors      []validator.Result // results that are not tied to a version, such as discovery errors
+}
+
+// NewMatrix returns an output reporting the results of validating resources against several
+// versions of Kubernetes, with a row per resource and a column per version. Results must be
+// tagged with the version they were obtained with. Only the json, pretty and text formats
+// are supported.
+func NewMatrix(w io.Writer, outputFormat string, versions []string, printSummary, isStdin, verbose bool) (Output, error) {
+	if outputFormat != "json" && outputFormat != "pretty" && outputFormat != "text" {
+		return nil, fmt.Errorf("'outputFormat' must be 'json', 'pretty' or 'text' when validating against several Kubernetes versions")
+	}
+
+	return &matrixo{
+		w:           w,
+		asJSON:      outputFormat == "json",
+		withSummary: printSummary,
+		isStdin:     isStdin,
+		verbose:     verbose,
+		versions:    versions,
+		files:       map[string]bool{},
+		rows:        map[matrixKey]*matrixRow{},
+	}, nil
+}
+
+// Write only stores the result, the matrix is written when Flush is called
+func (o *matrixo) Write(result validator.Result) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if result.Resource.Path != "" {
+		o.files[result.Resource.Path] = true
+	}
+	if result.KubernetesVersion == "" {
+		o.errors = append(o.errors, result)
+		return nil
+	}
+	if result.Status == validator.Empty {
+		return nil
+	}
+
+	sig, _ := result.Resource.Signature()
+	k := matrixKey{path: result.Resource.Path, kind: sig.Kind, namespace: sig.Namespace, name: sig.Name, line: result.Resource.Line}
+	row, ok := o.rows[k]
+	if !ok {
+		row = &matrixRow{
+			path:    result.Resource.Path,
+			kind:    sig.Kind,
+			version: sig.Version,
+			name:    sig.Name,
+			line:    result.Resource.Line,
+			results: map[string]validator.Result{},
+		}
+		o.rows[k] = row
+	}
+	row.results[result.KubernetesVersion] = result
+
+	return nil
+}
+
+// visibleRows returns the rows to report, sorted by file and position: all of them in verbose
+// mode, otherwise the resources that are invalid, could not be validated or have warnings on a version
+func (o *matrixo) visibleRows() []*matrixRow {
+	rows := []*matrixRow{}
+	for _, row := range o.rows {
+		visible := o.verbose
+		for _, res := range row.results {
+			if res.Status == validator.Invalid || res.Status == validator.Error || res.Status == validator.Warning {
+				visible = true
+			}
+		}
+		if visible {
+			rows = append(rows, row)
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].path != rows[j].path {
+			return rows[i].path < rows[j].path
+		}
+		if rows[i].line != rows[j].line {
+			return rows[i].line < rows[j].line
+		}
+		return rows[i].kind+"/"+rows[i].name < rows[j].kind+"/"+rows[j].name
+	})
+	return rows
+}
+
+// counts returns the number of valid, invalid, errored, skipped resources and resources with warnings on version
+func (o *matrixo) counts(version string) (nValid, nInvalid, nErrors, nSkipped, nWarnings int) {
+	for _, row := range o.rows {
+		switch row.results[version].Status {
+		case validator.Valid:
+			nValid++
+		case validator.Invalid:
+			nInvalid++
+		case validator.Error:
+			nErrors++
+		case validator.Skipped:
+			nSkipped++
+		case validator.Warning:
+			nWarnings++
+		}
+	}
+	return
+}
+
+func matrixStatus(status validator.Status) string {
+	switch status {
+	case validator.Valid:
+		return "valid"
+	case validator.Invalid:
+		return "invalid"
+	case validator.Error:
+		return "error"
+	case validator.Skipped:
+		return "skipped"
+	case validator.Warning:
+		return "warning"
+	}
+	return "-"
+}
+
+func (o *matrixo) Flush() error {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.asJSON {
+		return o.flushJSON()
+	}
+	return o.flushText()
+}
+
+func (o *matrixo) flushText() error {
+	rows := o.visibleRows()
+
+	if len(rows) > 0 {
+		tw := tabwriter.NewWriter(o.w, 0, 0, 3, ' ', 0)
+		fmt.Fprintf(tw, "RESOURCE\t%s\n", strings.Join(o.versions, "\t"))
+		for _, row := range rows {
+			statuses := []string{}
+			for _, version := range o.versions {
+				statuses = append(statuses, matrixStatus(row.results[version].Status))
+			}
+			fmt.Fprintf(tw, "%s - %s %s\t%s\n", row.path, row.kind, row.name, strings.Join(statuses, "\t"))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			for _, version := range o.versions {
+				res := row.results[version]
+				switch res.Status {
+				case validator.Invalid:
+					fmt.Fprintf(o.w, "%s - %s %s is invalid on %s: %s\n", resultLocation(res), row.kind, row.name, version, res.Err)
+				case validator.Error:
+					fmt.Fprintf(o.w, "%s - %s %s failed validation on %s: %s\n", row.path, row.kind, row.name, version, res.Err)
+				case validator.Warning:
+					fmt.Fprintf(o.w, "%s - %s %s has warnings on %s: %s\n", resultLocation(res), row.kind, row.name, version, res.Err)
+				}
+			}
+		}
+	}
+
+	for _, res := range o.errors {
+		fmt.Fprintf(o.w, "%s - failed validation: %s\n", res.Resource.Path, res.Err)
+	}
+
+	if o.withSummary {
+		resourcesPlural := ""
+		if len(o.rows) > 1 {
+			resourcesPlural = "s"
+		}
+		if o.isStdin {
+			fmt.Fprintf(o.w, "Summary: %d resource%s found parsing stdin\n", len(o.rows), resourcesPlural)
+		} else {
+			filesPlural := ""
+			if len(o.files) > 1 {
+				filesPlural = "s"
+			}
+			fmt.Fprintf(o.w, "Summary: %d resource%s found in %d file%s\n", len(o.rows), resourcesPlural, len(o.files), filesPlural)
+		}
+		for _, version := range o.versions {
+			nValid, nInvalid, nErrors, nSkipped, nWarnings := o.counts(version)
+			warnings := ""
+			if nWarnings > 0 {
+				warnings = fmt.Sprintf(", Warnings: %d", nWarnings)
+			}
+			if _, err := fmt.Fprintf(o.w, "  %s - Valid: %d, Invalid: %d, Errors: %d, Skipped: %d%s\n", version, nValid, nInvalid, nErrors, nSkipped, warnings); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+type matrixVersionResult struct {
+	Status           string                      `json:"status"`
+	Msg              string                      `json:"msg"`
+	ValidationErrors []validator.ValidationError `json:"validationErrors,omitempty"`
+}
+
+type matrixResource struct {
+	Filename string                         `json:"filename"`
+	Kind     string                         `json:"kind"`
+	Name     string                         `json:"name"`
+	Version  string                         `json:"version"`
+	Results  map[string]matrixVersionResult `json:"results"` // indexed by Kubernetes version
+}
+
+type matrixSummary struct {
+	Valid    int `json:"valid"`
+	Invalid  int `json:"invalid"`
+	Errors   int `json:"errors"`
+	Skipped  int `json:"skipped"`
+	Warnings int `json:"warnings,omitempty"`
+}
+
+func (o *matrixo) flushJSON() error {
+	jsonObj := struct {
+		KubernetesVersions []string                 `json:"kubernetesVersions"`
+		Resources          []matrixResource         `json:"resources"`
+		Errors             []oresult                `json:"errors,omitempty"`
+		Summary            map[string]matrixSummary `json:"summary,omitempty"` // indexed by Kubernetes version
+	}{
+		KubernetesVersions: o.versions,
+		Resources:          []matrixResource{},
+	}
+
+	for _, row := range o.visibleRows() {
+		r := matrixResource{Filename: row.path, Kind: row.kind, Name: row.name, Version: row.version, Results: map[string]matrixVersionResult{}}
+		for version, res := range row.results {
+			vr := matrixVersionResult{ValidationErrors: res.ValidationErrors}
+			switch res.Status {
+			case validator.Valid:
+				vr.Status = "statusValid"
+			case validator.Invalid:
+				vr.Status = "statusInvalid"
+			case validator.Error:
+				vr.Status = "statusError"
+			case validator.Skipped:
+				vr.Status = "statusSkipped"
+			case validator.Warning:
+				vr.Status = "statusWarning"
+			}
+			if res.Err != nil {
+				vr.Msg = res.Err.Error()
+			}
+			r.Results[version] = vr
+		}
+		jsonObj.Resources = append(jsonObj.Resources, r)
+	}
+
+	for _, res := range o.errors {
+		msg := ""
+		if res.Err != nil {
+			msg = res.Err.Error()
+		}
+		jsonObj.Errors = append(jsonObj.Errors, oresult{Filename: res.Resource.Path, Status: "statusError", Msg: msg})
+	}
+
+	if o.withSummary {
+		jsonObj.Summary = map[string]matrixSummary{}
+		for _, version := range o.versions {
+			nValid, nInvalid, nErrors, nSkipped, nWarnings := o.counts(version)
+			jsonObj.Summary[version] = matrixSummary{Valid: nValid, Invalid: nInvalid, Errors: nErrors, Skipped: nSkipped, Warnings: nWarnings}
+		}
+	}
+
+	res, err := json.MarshalIndent(jsonObj, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(o.w, "%s\n", res)
+	return err
+}
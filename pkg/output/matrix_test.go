@@ -0,0 +1,136 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/yannh/kubeconform/pkg/resource"
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+func TestMatrixWrite(t *testing.T) {
+	deployment := resource.Resource{
+		Path: "deployment.yml",
+		Bytes: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: "my-app"
+`),
+	}
+	service := resource.Resource{
+		Path: "service.yml",
+		Bytes: []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: "my-service"
+`),
+	}
+	results := []validator.Result{
+		{Resource: service, Status: validator.Valid, KubernetesVersion: "1.28.0"},
+		{Resource: deployment, Status: validator.Valid, KubernetesVersion: "1.27.0"},
+		{
+			Resource:          deployment,
+			Status:            validator.Invalid,
+			Err:               fmt.Errorf("property \"spec\" is missing"),
+			KubernetesVersion: "1.28.0",
+		},
+		{Resource: service, Status: validator.Valid, KubernetesVersion: "1.27.0"},
+	}
+
+	for _, testCase := range []struct {
+		name        string
+		format      string
+		withSummary bool
+		verbose     bool
+		results     []validator.Result
+		expect      string
+	}{
+		{
+			"text, only the resources that are not valid on all versions",
+			"text",
+			false,
+			false,
+			results,
+			`RESOURCE                             1.27.0   1.28.0
+deployment.yml - Deployment my-app   valid    invalid
+deployment.yml - Deployment my-app is invalid on 1.28.0: property "spec" is missing
+`,
+		},
+		{
+			"text, verbose with summary",
+			"text",
+			true,
+			true,
+			results,
+			`RESOURCE                             1.27.0   1.28.0
+deployment.yml - Deployment my-app   valid    invalid
+service.yml - Service my-service     valid    valid
+deployment.yml - Deployment my-app is invalid on 1.28.0: property "spec" is missing
+Summary: 2 resources found in 2 files
+  1.27.0 - Valid: 2, Invalid: 0, Errors: 0, Skipped: 0
+  1.28.0 - Valid: 1, Invalid: 1, Errors: 0, Skipped: 0
+`,
+		},
+		{
+			"text, errors not tied to a version",
+			"text",
+			false,
+			false,
+			[]validator.Result{
+				{Resource: resource.Resource{Path: "missing.yml"}, Status: validator.Error, Err: fmt.Errorf("no such file")},
+			},
+			"missing.yml - failed validation: no such file\n",
+		},
+		{
+			"json",
+			"json",
+			false,
+			false,
+			results,
+			`{
+  "kubernetesVersions": [
+    "1.27.0",
+    "1.28.0"
+  ],
+  "resources": [
+    {
+      "filename": "deployment.yml",
+      "kind": "Deployment",
+      "name": "my-app",
+      "version": "apps/v1",
+      "results": {
+        "1.27.0": {
+          "status": "statusValid",
+          "msg": ""
+        },
+        "1.28.0": {
+          "status": "statusInvalid",
+          "msg": "property \"spec\" is missing"
+        }
+      }
+    }
+  ]
+}
+`,
+		},
+	} {
+		w := new(bytes.Buffer)
+		o, err := NewMatrix(w, testCase.format, []string{"1.27.0", "1.28.0"}, testCase.withSummary, false, testCase.verbose)
+		if err != nil {
+			t.Fatalf("%s: failed creating output: %s", testCase.name, err)
+		}
+		for _, res := range testCase.results {
+			o.Write(res)
+		}
+		o.Flush()
+
+		if w.String() != testCase.expect {
+			t.Errorf("%s - expected:\n%s\ngot:\n%s", testCase.name, testCase.expect, w)
+		}
+	}
+
+	if _, err := NewMatrix(new(bytes.Buffer), "junit", []string{"1.27.0", "1.28.0"}, false, false, false); err == nil {
+		t.Errorf("expected an error for an output format not supporting several versions")
+	}
+}
@@ -20,11 +20,45 @@ func New(w io.Writer, outputFormat string, printSummary, isStdin, verbose bool)
 		return junitOutput(w, printSummary, isStdin, verbose), nil
 	case outputFormat == "pretty":
 		return prettyOutput(w, printSummary, isStdin, verbose), nil
+	case outputFormat == "sarif":
+		return sarifOutput(w, printSummary, isStdin, verbose), nil
 	case outputFormat == "tap":
 		return tapOutput(w, printSummary, isStdin, verbose), nil
 	case outputFormat == "text":
 		return textOutput(w, printSummary, isStdin, verbose), nil
 	default:
-		return nil, fmt.Errorf("'outputFormat' must be 'json', 'junit', 'pretty', 'tap' or 'text'")
+		return nil, fmt.Errorf("'outputFormat' must be 'json', 'junit', 'pretty', 'sarif', 'tap' or 'text'")
 	}
 }
+
+// errorLocation returns the location of a validation error in the file at path,
+// in the format path:line:column, or path if the position of the error is unknown
+func errorLocation(path string, ve validator.ValidationError) string {
+	if ve.Line == 0 {
+		return path
+	}
+	return fmt.Sprintf("%s:%d:%d", path, ve.Line, ve.Column)
+}
+
+// errorList returns the validation errors of result, each prefixed with its location
+func errorList(result validator.Result) []string {
+	errs := []string{}
+	for _, ve := range result.ValidationErrors {
+		if ve.Path == "" {
+			errs = append(errs, fmt.Sprintf("%s: %s", errorLocation(result.Resource.Path, ve), ve.Msg))
+		} else {
+			errs = append(errs, fmt.Sprintf("%s: %s: %s", errorLocation(result.Resource.Path, ve), ve.Path, ve.Msg))
+		}
+	}
+	return errs
+}
+
+// resultLocation returns the location of the first validation error of a result
+func resultLocation(result validator.Result) string {
+	for _, ve := range result.ValidationErrors {
+		if ve.Line != 0 {
+			return errorLocation(result.Resource.Path, ve)
+		}
+	}
+	return result.Resource.Path
+}
@@ -16,6 +16,7 @@ type prettyo struct {
 	verbose                             bool
 	files                               map[string]bool
 	nValid, nInvalid, nErrors, nSkipped int
+	nWarnings                           int
 }
 
 // Text will output the results of the validation as a texto
@@ -30,6 +31,7 @@ func prettyOutput(w io.Writer, withSummary, isStdin, verbose bool) Output {
 		nInvalid:    0,
 		nErrors:     0,
 		nSkipped:    0,
+		nWarnings:   0,
 	}
 }
 
@@ -56,17 +58,20 @@ func (o *prettyo) Write(result validator.Result) error {
 		}
 		o.nValid++
 	case validator.Invalid:
-		fmt.Fprintf(o.w, "%s%s%s %s: %s%s %s is invalid: %s%s\n", cRed, multiplicationSign, reset, result.Resource.Path, cRed, sig.Kind, sig.Name, result.Err.Error(), reset)
+		fmt.Fprintf(o.w, "%s%s%s %s: %s%s %s is invalid: %s%s\n", cRed, multiplicationSign, reset, resultLocation(result), cRed, sig.Kind, sig.Name, result.Err.Error(), reset)
 
 		o.nInvalid++
 	case validator.Error:
-		fmt.Fprintf(o.w, "%s%s%s %s: ", cRed, multiplicationSign, reset, result.Resource.Path)
+		fmt.Fprintf(o.w, "%s%s%s %s: ", cRed, multiplicationSign, reset, resultLocation(result))
 		if sig.Kind != "" && sig.Name != "" {
 			fmt.Fprintf(o.w, "%s%s failed validation: %s %s%s\n", cRed, sig.Kind, sig.Name, result.Err.Error(), reset)
 		} else {
 			fmt.Fprintf(o.w, "%sfailed validation: %s %s%s\n", cRed, sig.Name, result.Err.Error(), reset)
 		}
 		o.nErrors++
+	case validator.Warning:
+		fmt.Fprintf(o.w, "%s!%s %s: %s%s %s has warnings: %s%s\n", cYellow, reset, resultLocation(result), cYellow, sig.Kind, sig.Name, result.Err.Error(), reset)
+		o.nWarnings++
 	case validator.Skipped:
 		if o.verbose {
 			fmt.Fprintf(o.w, "%s-%s %s: ", cYellow, reset, result.Resource.Path)
@@ -82,6 +87,14 @@ func (o *prettyo) Write(result validator.Result) error {
 	case validator.Empty: // sent to ensure we count the filename as parsed
 	}
 
+	// The first line only gives the location of the first error, when there are several
+	// they are listed on the following lines with their own location
+	if result.Status != validator.Skipped && len(result.ValidationErrors) > 1 {
+		for _, e := range errorList(result) {
+			fmt.Fprintf(o.w, "    %s\n", e)
+		}
+	}
+
 	return err
 }
 
@@ -89,7 +102,7 @@ func (o *prettyo) Flush() error {
 	var err error
 	if o.withSummary {
 		nFiles := len(o.files)
-		nResources := o.nValid + o.nInvalid + o.nErrors + o.nSkipped
+		nResources := o.nValid + o.nInvalid + o.nErrors + o.nSkipped + o.nWarnings
 		resourcesPlural := ""
 		if nResources > 1 {
 			resourcesPlural = "s"
@@ -98,10 +111,15 @@ func (o *prettyo) Flush() error {
 		if nFiles > 1 {
 			filesPlural = "s"
 		}
+		// Warnings are only counted when there are some, to keep the summary of runs without warnings unchanged
+		warnings := ""
+		if o.nWarnings > 0 {
+			warnings = fmt.Sprintf(", Warnings: %d", o.nWarnings)
+		}
 		if o.isStdin {
-			_, err = fmt.Fprintf(o.w, "Summary: %d resource%s found parsing stdin - Valid: %d, Invalid: %d, Errors: %d, Skipped: %d\n", nResources, resourcesPlural, o.nValid, o.nInvalid, o.nErrors, o.nSkipped)
+			_, err = fmt.Fprintf(o.w, "Summary: %d resource%s found parsing stdin - Valid: %d, Invalid: %d, Errors: %d, Skipped: %d%s\n", nResources, resourcesPlural, o.nValid, o.nInvalid, o.nErrors, o.nSkipped, warnings)
 		} else {
-			_, err = fmt.Fprintf(o.w, "Summary: %d resource%s found in %d file%s - Valid: %d, Invalid: %d, Errors: %d, Skipped: %d\n", nResources, resourcesPlural, nFiles, filesPlural, o.nValid, o.nInvalid, o.nErrors, o.nSkipped)
+			_, err = fmt.Fprintf(o.w, "Summary: %d resource%s found in %d file%s - Valid: %d, Invalid: %d, Errors: %d, Skipped: %d%s\n", nResources, resourcesPlural, nFiles, filesPlural, o.nValid, o.nInvalid, o.nErrors, o.nSkipped, warnings)
 		}
 	}
 
@@ -2,6 +2,7 @@ package output
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
 
 	"github.com/yannh/kubeconform/pkg/resource"
@@ -68,6 +69,36 @@ metadata:
 			"\033[32m✔\033[0m deployment.yml: \033[32mDeployment my-app is valid\033[0m\n" +
 				"Summary: 1 resource found in 1 file - Valid: 1, Invalid: 0, Errors: 0, Skipped: 0\n",
 		},
+		{
+			"an invalid deployment with several errors",
+			false,
+			false,
+			false,
+			[]validator.Result{
+				{
+					Resource: resource.Resource{
+						Path: "deployment.yml",
+						Bytes: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: "my-app"
+spec:
+  replicas: "3"
+  paused: "no"
+`),
+					},
+					Status: validator.Invalid,
+					Err:    fmt.Errorf("problem validating schema"),
+					ValidationErrors: []validator.ValidationError{
+						{Path: "/spec/replicas", Msg: "got string, want integer", Line: 6, Column: 3},
+						{Path: "/spec/paused", Msg: "got string, want boolean", Line: 7, Column: 3},
+					},
+				},
+			},
+			"\033[31m✖\033[0m deployment.yml:6:3: \033[31mDeployment my-app is invalid: problem validating schema\033[0m\n" +
+				"    deployment.yml:6:3: /spec/replicas: got string, want integer\n" +
+				"    deployment.yml:7:3: /spec/paused: got string, want boolean\n",
+		},
 	} {
 		w := new(bytes.Buffer)
 		o := prettyOutput(w, testCase.withSummary, testCase.isStdin, testCase.verbose)
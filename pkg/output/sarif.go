@@ -0,0 +1,256 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+// SARIF 2.1.0 log format, as consumed by code-scanning tools such as GitHub and GitLab
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifo struct {
+	w       io.Writer
+	rules   []sarifRule
+	ruleIDs map[string]struct{}
+	results []sarifResult
+}
+
+// sarifOutput will output the problems found during the validation as a SARIF log.
+// Valid resources are not part of the log, nor skipped resources without warnings,
+// so the summary and verbose options have no effect.
+func sarifOutput(w io.Writer, withSummary bool, isStdin, verbose bool) Output {
+	return &sarifo{
+		w:       w,
+		rules:   []sarifRule{},
+		ruleIDs: map[string]struct{}{},
+		results: []sarifResult{},
+	}
+}
+
+// sarifRuleID returns the id of the rule for a validation error: the id of the policy rule
+// reporting it, or the JSON schema keyword the resource does not satisfy
+func sarifRuleID(ve validator.ValidationError) string {
+	if ve.Rule != "" {
+		return ve.Rule
+	}
+	if ve.Keyword == "" {
+		return "schema"
+	}
+	return ve.Keyword
+}
+
+// sarifRuleDescription returns the description of the rule ruleID, reporting the validation error ve
+func sarifRuleDescription(ruleID string, ve validator.ValidationError) string {
+	switch {
+	case ruleID == "error":
+		return "Resource could not be validated"
+	case ve.Rule != "":
+		return fmt.Sprintf("Resource does not follow the rule %s", ve.Rule)
+	case ve.Keyword == "":
+		return "Resource does not match its JSON schema"
+	default:
+		return fmt.Sprintf("Resource does not satisfy the JSON schema keyword %s", ve.Keyword)
+	}
+}
+
+// sarifLevel returns the SARIF level of a validation error, from its severity
+func sarifLevel(ve validator.ValidationError) string {
+	if ve.Severity == validator.SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// sarifURI converts the path of a file to the URI of a SARIF artifact location
+func sarifURI(path string) string {
+	p := filepath.ToSlash(path)
+	if !filepath.IsAbs(path) {
+		return (&url.URL{Path: p}).String()
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p // Windows drive letter
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+func (o *sarifo) addResult(ruleID, description, level, path, msg string, line, column int) {
+	if _, ok := o.ruleIDs[ruleID]; !ok {
+		o.ruleIDs[ruleID] = struct{}{}
+		o.rules = append(o.rules, sarifRule{ID: ruleID, ShortDescription: sarifMessage{Text: description}})
+	}
+
+	location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: sarifURI(path)}}
+	if line > 0 {
+		location.Region = &sarifRegion{StartLine: line, StartColumn: column}
+	}
+
+	o.results = append(o.results, sarifResult{
+		RuleID:    ruleID,
+		Level:     level,
+		Message:   sarifMessage{Text: msg},
+		Locations: []sarifLocation{{PhysicalLocation: location}},
+	})
+}
+
+// Write will only write when Flush has been called
+func (o *sarifo) Write(result validator.Result) error {
+	sig, _ := result.Resource.Signature()
+	name := strings.TrimSpace(sig.Kind + " " + sig.Name)
+
+	verb := ""
+	switch result.Status {
+	case validator.Invalid:
+		verb = "is invalid"
+	case validator.Error:
+		verb = "failed validation"
+	case validator.Warning, validator.Skipped:
+	default:
+		return nil
+	}
+
+	// Each validation error is reported with its own rule, including the warnings
+	// of resources that are invalid, or skipped because their schema is missing
+	reported := false
+	for _, ve := range result.ValidationErrors {
+		if ve.Severity == validator.SeverityWarning {
+			o.addValidationError(result, name, "has a warning", ve)
+			continue
+		}
+		if verb != "" {
+			o.addValidationError(result, name, verb, ve)
+			reported = true
+		}
+	}
+	if verb == "" || reported {
+		return nil
+	}
+
+	// Results without a validation error reporting why they failed are reported at the
+	// first validation error located, or at the start of the resource
+	line, column := result.Resource.Line, 0
+	for _, ve := range result.ValidationErrors {
+		if ve.Line != 0 {
+			line, column = ve.Line, ve.Column
+			break
+		}
+	}
+
+	msg := ""
+	if result.Err != nil {
+		msg = result.Err.Error()
+	}
+	if result.Status == validator.Invalid {
+		o.addResult("schema", sarifRuleDescription("schema", validator.ValidationError{}), "error", result.Resource.Path, fmt.Sprintf("%s is invalid: %s", name, msg), line, column)
+		return nil
+	}
+
+	if name != "" {
+		msg = fmt.Sprintf("%s failed validation: %s", name, msg)
+	}
+	o.addResult("error", sarifRuleDescription("error", validator.ValidationError{}), "error", result.Resource.Path, msg, line, column)
+
+	return nil
+}
+
+// addValidationError adds a result for the validation error ve of the resource called name
+func (o *sarifo) addValidationError(result validator.Result, name, verb string, ve validator.ValidationError) {
+	msg := fmt.Sprintf("%s %s: %s", name, verb, ve.Msg)
+	if ve.Path != "" {
+		msg = fmt.Sprintf("%s %s: %s: %s", name, verb, ve.Path, ve.Msg)
+	}
+	line, column := ve.Line, ve.Column
+	if line == 0 {
+		line = result.Resource.Line
+	}
+	ruleID := sarifRuleID(ve)
+	o.addResult(ruleID, sarifRuleDescription(ruleID, ve), sarifLevel(ve), result.Resource.Path, msg, line, column)
+}
+
+// Flush outputs the results as a SARIF log
+func (o *sarifo) Flush() error {
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "kubeconform",
+						InformationURI: "https://github.com/yannh/kubeconform",
+						Rules:          o.rules,
+					},
+				},
+				Results: o.results,
+			},
+		},
+	}
+
+	res, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.w, "%s\n", res)
+
+	return nil
+}
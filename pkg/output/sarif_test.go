@@ -0,0 +1,448 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/yannh/kubeconform/pkg/resource"
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+func TestSARIFWrite(t *testing.T) {
+	for _, testCase := range []struct {
+		name    string
+		results []validator.Result
+		expect  string
+	}{
+		{
+			"a single valid deployment",
+			[]validator.Result{
+				{
+					Resource: resource.Resource{
+						Path: "deployment.yml",
+						Bytes: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: "my-app"
+`),
+					},
+					Status: validator.Valid,
+					Err:    nil,
+				},
+			},
+			`{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "kubeconform",
+          "informationUri": "https://github.com/yannh/kubeconform",
+          "rules": []
+        }
+      },
+      "results": []
+    }
+  ]
+}
+`,
+		},
+		{
+			"an invalid deployment and a resource that could not be validated",
+			[]validator.Result{
+				{
+					Resource: resource.Resource{
+						Path: "manifests/deployment.yml",
+						Bytes: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: "my-app"
+`),
+					},
+					Status: validator.Invalid,
+					Err:    &validator.ValidationError{Path: "/spec/replicas", Msg: "got string, want integer"},
+					ValidationErrors: []validator.ValidationError{
+						{Path: "", Msg: "missing property 'spec'", Keyword: "required"},
+						{Path: "/spec/replicas", Msg: "got string, want integer", Keyword: "type", Line: 6, Column: 3},
+					},
+				},
+				{
+					Resource: resource.Resource{
+						Path:  "/tmp/my file.yml",
+						Bytes: []byte(`apiVersion: v1`),
+						Line:  3,
+					},
+					Status: validator.Error,
+					Err:    fmt.Errorf("missing 'kind' key"),
+				},
+			},
+			`{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "kubeconform",
+          "informationUri": "https://github.com/yannh/kubeconform",
+          "rules": [
+            {
+              "id": "required",
+              "shortDescription": {
+                "text": "Resource does not satisfy the JSON schema keyword required"
+              }
+            },
+            {
+              "id": "type",
+              "shortDescription": {
+                "text": "Resource does not satisfy the JSON schema keyword type"
+              }
+            },
+            {
+              "id": "error",
+              "shortDescription": {
+                "text": "Resource could not be validated"
+              }
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "required",
+          "level": "error",
+          "message": {
+            "text": "Deployment my-app is invalid: missing property 'spec'"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "manifests/deployment.yml"
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "type",
+          "level": "error",
+          "message": {
+            "text": "Deployment my-app is invalid: /spec/replicas: got string, want integer"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "manifests/deployment.yml"
+                },
+                "region": {
+                  "startLine": 6,
+                  "startColumn": 3
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "error",
+          "level": "error",
+          "message": {
+            "text": "missing 'kind' key"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "file:///tmp/my%20file.yml"
+                },
+                "region": {
+                  "startLine": 3
+                }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+		{
+			"warnings of resources with warnings or skipped",
+			[]validator.Result{
+				{
+					Resource: resource.Resource{
+						Path: "ingress.yml",
+						Bytes: []byte(`apiVersion: extensions/v1beta1
+kind: Ingress
+metadata:
+  name: "web"
+`),
+					},
+					Status: validator.Warning,
+					Err:    fmt.Errorf("rule deprecated-api-version: extensions/v1beta1 Ingress is deprecated"),
+					ValidationErrors: []validator.ValidationError{
+						{Path: "/apiVersion", Msg: "extensions/v1beta1 Ingress is deprecated", Line: 1, Column: 1, Rule: "deprecated-api-version", Severity: validator.SeverityWarning},
+					},
+				},
+				{
+					Resource: resource.Resource{
+						Path: "crontab.yml",
+						Bytes: []byte(`apiVersion: stable.example.com/v1
+kind: CronTab
+metadata:
+  name: "cron"
+`),
+					},
+					Status: validator.Skipped,
+					ValidationErrors: []validator.ValidationError{
+						{Msg: "could not find schema for CronTab", Rule: "missing-schema", Severity: validator.SeverityWarning},
+					},
+				},
+			},
+			`{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "kubeconform",
+          "informationUri": "https://github.com/yannh/kubeconform",
+          "rules": [
+            {
+              "id": "deprecated-api-version",
+              "shortDescription": {
+                "text": "Resource does not follow the rule deprecated-api-version"
+              }
+            },
+            {
+              "id": "missing-schema",
+              "shortDescription": {
+                "text": "Resource does not follow the rule missing-schema"
+              }
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "deprecated-api-version",
+          "level": "warning",
+          "message": {
+            "text": "Ingress web has a warning: /apiVersion: extensions/v1beta1 Ingress is deprecated"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "ingress.yml"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 1
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "missing-schema",
+          "level": "warning",
+          "message": {
+            "text": "CronTab cron has a warning: could not find schema for CronTab"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "crontab.yml"
+                }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+		{
+			"results reported with the rules of their validation errors, or without validation errors",
+			[]validator.Result{
+				{
+					Resource: resource.Resource{
+						Path:  "service.yml",
+						Bytes: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n"),
+						Line:  5,
+					},
+					Status: validator.Invalid,
+					Err:    fmt.Errorf("problem validating schema"),
+				},
+				{
+					Resource: resource.Resource{
+						Path:  "service.yml",
+						Bytes: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n"),
+						Line:  10,
+					},
+					Status: validator.Error,
+					Err:    fmt.Errorf("duplicate resource v1/Service/default/web, also defined in service.yml:5"),
+					ValidationErrors: []validator.ValidationError{
+						{Path: "/metadata/name", Msg: "duplicate resource v1/Service/default/web, also defined in service.yml:5", Line: 13, Column: 3, Rule: "duplicate-resource"},
+					},
+				},
+				{
+					Resource: resource.Resource{
+						Path:  "ingress.yml",
+						Bytes: []byte("apiVersion: extensions/v1beta1\nkind: Ingress\nmetadata:\n  name: web\n"),
+						Line:  1,
+					},
+					Status: validator.Error,
+					Err:    fmt.Errorf("could not find schema for Ingress: extensions/v1beta1 Ingress was removed"),
+					ValidationErrors: []validator.ValidationError{
+						{Path: "/apiVersion", Msg: "extensions/v1beta1 Ingress was removed", Line: 1, Column: 1, Rule: "deprecated-api-version", Severity: validator.SeverityWarning},
+					},
+				},
+			},
+			`{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "kubeconform",
+          "informationUri": "https://github.com/yannh/kubeconform",
+          "rules": [
+            {
+              "id": "schema",
+              "shortDescription": {
+                "text": "Resource does not match its JSON schema"
+              }
+            },
+            {
+              "id": "duplicate-resource",
+              "shortDescription": {
+                "text": "Resource does not follow the rule duplicate-resource"
+              }
+            },
+            {
+              "id": "deprecated-api-version",
+              "shortDescription": {
+                "text": "Resource does not follow the rule deprecated-api-version"
+              }
+            },
+            {
+              "id": "error",
+              "shortDescription": {
+                "text": "Resource could not be validated"
+              }
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "schema",
+          "level": "error",
+          "message": {
+            "text": "Service web is invalid: problem validating schema"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "service.yml"
+                },
+                "region": {
+                  "startLine": 5
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "duplicate-resource",
+          "level": "error",
+          "message": {
+            "text": "Service web failed validation: /metadata/name: duplicate resource v1/Service/default/web, also defined in service.yml:5"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "service.yml"
+                },
+                "region": {
+                  "startLine": 13,
+                  "startColumn": 3
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "deprecated-api-version",
+          "level": "warning",
+          "message": {
+            "text": "Ingress web has a warning: /apiVersion: extensions/v1beta1 Ingress was removed"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "ingress.yml"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 1
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "error",
+          "level": "error",
+          "message": {
+            "text": "Ingress web failed validation: could not find schema for Ingress: extensions/v1beta1 Ingress was removed"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "ingress.yml"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 1
+                }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+	} {
+		w := new(bytes.Buffer)
+		o := sarifOutput(w, false, false, false)
+
+		for _, res := range testCase.results {
+			o.Write(res)
+		}
+		o.Flush()
+
+		if w.String() != testCase.expect {
+			t.Errorf("%s - expected:\n%s\ngot:\n%s", testCase.name, testCase.expect, w)
+		}
+	}
+}
@@ -42,6 +42,10 @@ func (o *tapo) Write(res validator.Result) error {
 		sig, _ := res.Resource.Signature()
 		fmt.Fprintf(o.w, "ok %d - %s (%s)\n", o.index, res.Resource.Path, sig.QualifiedName())
 
+	case validator.Warning:
+		sig, _ := res.Resource.Signature()
+		fmt.Fprintf(o.w, "ok %d - %s (%s)\n# warning: %s\n", o.index, res.Resource.Path, sig.QualifiedName(), res.Err.Error())
+
 	case validator.Invalid:
 		sig, _ := res.Resource.Signature()
 		fmt.Fprintf(o.w, "not ok %d - %s (%s): %s\n", o.index, res.Resource.Path, sig.QualifiedName(), res.Err.Error())
@@ -2,6 +2,7 @@ package output
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
 
 	"github.com/yannh/kubeconform/pkg/resource"
@@ -59,6 +60,27 @@ metadata:
 			},
 			"TAP version 13\nok 1 - deployment.yml (apps/v1/Deployment//my-app)\n1..1\n",
 		},
+		{
+			"a deployment with warnings",
+			false,
+			false,
+			false,
+			[]validator.Result{
+				{
+					Resource: resource.Resource{
+						Path: "deployment.yml",
+						Bytes: []byte(`apiVersion: apps/v1beta1
+kind: Deployment
+metadata:
+  name: "my-app"
+`),
+					},
+					Status: validator.Warning,
+					Err:    fmt.Errorf("rule deprecated-api-version: apps/v1beta1 Deployment is deprecated"),
+				},
+			},
+			"TAP version 13\nok 1 - deployment.yml (apps/v1beta1/Deployment//my-app)\n# warning: rule deprecated-api-version: apps/v1beta1 Deployment is deprecated\n1..1\n",
+		},
 	} {
 		w := new(bytes.Buffer)
 		o := tapOutput(w, testCase.withSummary, testCase.isStdin, testCase.verbose)
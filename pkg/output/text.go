@@ -16,6 +16,7 @@ type texto struct {
 	verbose                             bool
 	files                               map[string]bool
 	nValid, nInvalid, nErrors, nSkipped int
+	nWarnings                           int
 }
 
 // Text will output the results of the validation as a texto
@@ -30,6 +31,7 @@ func textOutput(w io.Writer, withSummary, isStdin, verbose bool) Output {
 		nInvalid:    0,
 		nErrors:     0,
 		nSkipped:    0,
+		nWarnings:   0,
 	}
 }
 
@@ -49,15 +51,18 @@ func (o *texto) Write(result validator.Result) error {
 		}
 		o.nValid++
 	case validator.Invalid:
-		_, err = fmt.Fprintf(o.w, "%s - %s %s is invalid: %s\n", result.Resource.Path, sig.Kind, sig.Name, result.Err)
+		_, err = fmt.Fprintf(o.w, "%s - %s %s is invalid: %s\n", resultLocation(result), sig.Kind, sig.Name, result.Err)
 		o.nInvalid++
 	case validator.Error:
 		if sig.Kind != "" && sig.Name != "" {
-			_, err = fmt.Fprintf(o.w, "%s - %s %s failed validation: %s\n", result.Resource.Path, sig.Kind, sig.Name, result.Err)
+			_, err = fmt.Fprintf(o.w, "%s - %s %s failed validation: %s\n", resultLocation(result), sig.Kind, sig.Name, result.Err)
 		} else {
 			_, err = fmt.Fprintf(o.w, "%s - failed validation: %s\n", result.Resource.Path, result.Err)
 		}
 		o.nErrors++
+	case validator.Warning:
+		_, err = fmt.Fprintf(o.w, "%s - %s %s has warnings: %s\n", resultLocation(result), sig.Kind, sig.Name, result.Err)
+		o.nWarnings++
 	case validator.Skipped:
 		if o.verbose {
 			_, err = fmt.Fprintf(o.w, "%s - %s %s skipped\n", result.Resource.Path, sig.Name, sig.Kind)
@@ -66,6 +71,16 @@ func (o *texto) Write(result validator.Result) error {
 	case validator.Empty: // sent to ensure we count the filename as parsed
 	}
 
+	// The first line only gives the location of the first error, when there are several
+	// they are listed on the following lines with their own location
+	if err == nil && result.Status != validator.Skipped && len(result.ValidationErrors) > 1 {
+		for _, e := range errorList(result) {
+			if _, err = fmt.Fprintf(o.w, "  %s\n", e); err != nil {
+				break
+			}
+		}
+	}
+
 	return err
 }
 
@@ -73,7 +88,7 @@ func (o *texto) Flush() error {
 	var err error
 	if o.withSummary {
 		nFiles := len(o.files)
-		nResources := o.nValid + o.nInvalid + o.nErrors + o.nSkipped
+		nResources := o.nValid + o.nInvalid + o.nErrors + o.nSkipped + o.nWarnings
 		resourcesPlural := ""
 		if nResources > 1 {
 			resourcesPlural = "s"
@@ -82,10 +97,15 @@ func (o *texto) Flush() error {
 		if nFiles > 1 {
 			filesPlural = "s"
 		}
+		// Warnings are only counted when there are some, to keep the summary of runs without warnings unchanged
+		warnings := ""
+		if o.nWarnings > 0 {
+			warnings = fmt.Sprintf(", Warnings: %d", o.nWarnings)
+		}
 		if o.isStdin {
-			_, err = fmt.Fprintf(o.w, "Summary: %d resource%s found parsing stdin - Valid: %d, Invalid: %d, Errors: %d, Skipped: %d\n", nResources, resourcesPlural, o.nValid, o.nInvalid, o.nErrors, o.nSkipped)
+			_, err = fmt.Fprintf(o.w, "Summary: %d resource%s found parsing stdin - Valid: %d, Invalid: %d, Errors: %d, Skipped: %d%s\n", nResources, resourcesPlural, o.nValid, o.nInvalid, o.nErrors, o.nSkipped, warnings)
 		} else {
-			_, err = fmt.Fprintf(o.w, "Summary: %d resource%s found in %d file%s - Valid: %d, Invalid: %d, Errors: %d, Skipped: %d\n", nResources, resourcesPlural, nFiles, filesPlural, o.nValid, o.nInvalid, o.nErrors, o.nSkipped)
+			_, err = fmt.Fprintf(o.w, "Summary: %d resource%s found in %d file%s - Valid: %d, Invalid: %d, Errors: %d, Skipped: %d%s\n", nResources, resourcesPlural, nFiles, filesPlural, o.nValid, o.nInvalid, o.nErrors, o.nSkipped, warnings)
 		}
 	}
 
@@ -2,6 +2,7 @@ package output
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
 
 	"github.com/yannh/kubeconform/pkg/resource"
@@ -67,6 +68,90 @@ metadata:
 			},
 			`deployment.yml - Deployment my-app is valid
 Summary: 1 resource found in 1 file - Valid: 1, Invalid: 0, Errors: 0, Skipped: 0
+`,
+		},
+		{
+			"an invalid deployment, with the position of the error",
+			false,
+			false,
+			false,
+			[]validator.Result{
+				{
+					Resource: resource.Resource{
+						Path: "deployment.yml",
+						Bytes: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: "my-app"
+spec:
+  replicas: "3"
+`),
+					},
+					Status: validator.Invalid,
+					Err:    fmt.Errorf("problem validating schema"),
+					ValidationErrors: []validator.ValidationError{
+						{Path: "/spec/replicas", Msg: "got string, want integer", Line: 6, Column: 3},
+					},
+				},
+			},
+			`deployment.yml:6:3 - Deployment my-app is invalid: problem validating schema
+`,
+		},
+		{
+			"an invalid deployment with several errors",
+			false,
+			false,
+			false,
+			[]validator.Result{
+				{
+					Resource: resource.Resource{
+						Path: "deployment.yml",
+						Bytes: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: "my-app"
+spec:
+  replicas: "3"
+  paused: "no"
+`),
+					},
+					Status: validator.Invalid,
+					Err:    fmt.Errorf("problem validating schema"),
+					ValidationErrors: []validator.ValidationError{
+						{Path: "/spec/replicas", Msg: "got string, want integer", Line: 6, Column: 3},
+						{Path: "/spec/paused", Msg: "got string, want boolean", Line: 7, Column: 3},
+					},
+				},
+			},
+			`deployment.yml:6:3 - Deployment my-app is invalid: problem validating schema
+  deployment.yml:6:3: /spec/replicas: got string, want integer
+  deployment.yml:7:3: /spec/paused: got string, want boolean
+`,
+		},
+		{
+			"a deployment with warnings, with summary",
+			true,
+			false,
+			false,
+			[]validator.Result{
+				{
+					Resource: resource.Resource{
+						Path: "deployment.yml",
+						Bytes: []byte(`apiVersion: apps/v1beta1
+kind: Deployment
+metadata:
+  name: "my-app"
+`),
+					},
+					Status: validator.Warning,
+					Err:    fmt.Errorf("rule deprecated-api-version: apps/v1beta1 Deployment is deprecated"),
+					ValidationErrors: []validator.ValidationError{
+						{Path: "/apiVersion", Msg: "apps/v1beta1 Deployment is deprecated", Line: 1, Column: 1, Rule: "deprecated-api-version", Severity: validator.SeverityWarning},
+					},
+				},
+			},
+			`deployment.yml:1:1 - Deployment my-app has warnings: rule deprecated-api-version: apps/v1beta1 Deployment is deprecated
+Summary: 1 resource found in 1 file - Valid: 0, Invalid: 0, Errors: 0, Skipped: 0, Warnings: 1
 `,
 		},
 	} {
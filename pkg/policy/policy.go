@@ -0,0 +1,253 @@
+// Package policy evaluates organisation-specific rules on Kubernetes resources, in addition
+// to their validation against the Kubernetes schemas. Rules are written either as JSON schema
+// fragments, or as CEL expressions, and only apply to the kinds they list.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	jsonschema "github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/yannh/kubeconform/pkg/cel"
+	"github.com/yannh/kubeconform/pkg/resource"
+	"github.com/yannh/kubeconform/pkg/validator"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"sigs.k8s.io/yaml"
+)
+
+// Rule is a rule resources must follow. Exactly one of Schema and Expression must be set.
+type Rule struct {
+	ID         string         `json:"id"`
+	Kinds      []string       `json:"kinds,omitempty"`      // kinds or GVKs the rule applies to, e.g. Pod or apps/v1/Deployment. All kinds if empty
+	Severity   string         `json:"severity,omitempty"`   // error (default) or warning
+	Message    string         `json:"message,omitempty"`    // message reported when the rule is not followed
+	Path       string         `json:"path,omitempty"`       // JSON pointer to the field errors of CEL rules, and schema rules with a message, are reported at
+	Schema     map[string]any `json:"schema,omitempty"`     // JSON schema the resource must match
+	Expression string         `json:"expression,omitempty"` // CEL expression that must evaluate to true, the resource being bound to object
+}
+
+// File is the format of the files rules are read from
+type File struct {
+	Rules []Rule `json:"rules"`
+}
+
+type compiledRule struct {
+	Rule
+	kinds      map[string]struct{}
+	schema     *jsonschema.Schema
+	expression *cel.Program
+}
+
+// Policy is a set of compiled rules. It implements validator.RuleEngine.
+type Policy struct {
+	rules []compiledRule
+}
+
+// New compiles rules in a Policy
+func New(rules []Rule) (*Policy, error) {
+	p := &Policy{}
+	ids := map[string]bool{}
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("rules must have an id")
+		}
+		if ids[rule.ID] {
+			return nil, fmt.Errorf("duplicate rule %s", rule.ID)
+		}
+		ids[rule.ID] = true
+
+		compiled, err := compile(rule)
+		if err != nil {
+			return nil, fmt.Errorf("failed compiling rule %s: %s", rule.ID, err)
+		}
+		p.rules = append(p.rules, compiled)
+	}
+
+	return p, nil
+}
+
+// FromFiles reads and compiles the rules of the YAML or JSON files at paths
+func FromFiles(paths ...string) (*Policy, error) {
+	rules := []Rule{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading rules file %s: %s", path, err)
+		}
+		f := File{}
+		if err := yaml.UnmarshalStrict(content, &f); err != nil {
+			return nil, fmt.Errorf("failed parsing rules file %s: %s", path, err)
+		}
+		rules = append(rules, f.Rules...)
+	}
+
+	return New(rules)
+}
+
+func compile(rule Rule) (compiledRule, error) {
+	c := compiledRule{Rule: rule, kinds: map[string]struct{}{}}
+	for _, kind := range rule.Kinds {
+		c.kinds[kind] = struct{}{}
+	}
+
+	switch rule.Severity {
+	case "":
+		c.Severity = validator.SeverityError
+	case validator.SeverityError, validator.SeverityWarning:
+	default:
+		return c, fmt.Errorf("severity must be %s or %s", validator.SeverityError, validator.SeverityWarning)
+	}
+
+	switch {
+	case rule.Schema != nil && rule.Expression != "":
+		return c, fmt.Errorf("only one of schema and expression can be set")
+
+	case rule.Schema != nil:
+		compiler := jsonschema.NewCompiler()
+		url := "rule://" + rule.ID + ".json"
+		if err := compiler.AddResource(url, rule.Schema); err != nil {
+			return c, err
+		}
+		schema, err := compiler.Compile(url)
+		if err != nil {
+			return c, err
+		}
+		c.schema = schema
+
+	case rule.Expression != "":
+		program, err := cel.Compile(rule.Expression, "object")
+		if err != nil {
+			return c, err
+		}
+		c.expression = program
+
+	default:
+		return c, fmt.Errorf("either schema or expression must be set")
+	}
+
+	return c, nil
+}
+
+func (r *compiledRule) appliesTo(sig resource.Signature) bool {
+	if len(r.kinds) == 0 {
+		return true
+	}
+	if _, ok := r.kinds[sig.GroupVersionKind()]; ok {
+		return true
+	}
+	_, ok := r.kinds[sig.Kind]
+	return ok
+}
+
+// Evaluate returns a validation error for each rule applying to the resource
+// obj that it does not follow
+func (p *Policy) Evaluate(sig resource.Signature, obj map[string]interface{}) []validator.ValidationError {
+	errs := []validator.ValidationError{}
+	for i := range p.rules {
+		rule := &p.rules[i]
+		if rule.appliesTo(sig) {
+			errs = append(errs, rule.evaluate(obj)...)
+		}
+	}
+	return errs
+}
+
+func (r *compiledRule) newError(path, msg, keyword string) validator.ValidationError {
+	if r.Message != "" {
+		msg = r.Message
+	}
+	return validator.ValidationError{
+		Path:     path,
+		Msg:      msg,
+		Keyword:  keyword,
+		Rule:     r.ID,
+		Severity: r.Severity,
+	}
+}
+
+func (r *compiledRule) evaluate(obj map[string]interface{}) []validator.ValidationError {
+	if r.expression != nil {
+		ok, err := r.expression.EvalBool(map[string]any{"object": obj})
+		if err != nil {
+			ve := r.newError(r.Path, "", "")
+			ve.Msg = fmt.Sprintf("failed evaluating expression: %s", err)
+			return []validator.ValidationError{ve}
+		}
+		if !ok {
+			return []validator.ValidationError{r.newError(r.Path, fmt.Sprintf("expression %s evaluated to false", r.Expression), "")}
+		}
+		return nil
+	}
+
+	err := r.schema.Validate(obj)
+	if err == nil {
+		return nil
+	}
+
+	var e *jsonschema.ValidationError
+	if !errors.As(err, &e) {
+		return []validator.ValidationError{r.newError("", err.Error(), "")}
+	}
+
+	errs := []validator.ValidationError{}
+	for _, cause := range leaves(e) {
+		path := ""
+		for _, f := range cause.InstanceLocation {
+			path = path + "/" + f
+		}
+		keyword := ""
+		if kp := cause.ErrorKind.KeywordPath(); len(kp) > 0 {
+			keyword = kp[0]
+		}
+		errs = append(errs, r.newError(path, cause.ErrorKind.LocalizedString(message.NewPrinter(language.English)), keyword))
+	}
+
+	// The message describes the rule as a whole, it is reported once rather than for each
+	// cause: at the path of the rule, or at the closest field containing all causes
+	if r.Message != "" && len(errs) > 1 {
+		ve := errs[0]
+		ve.Path = r.Path
+		if ve.Path == "" {
+			ve.Path = commonPath(errs)
+		}
+		for _, e := range errs[1:] {
+			if e.Keyword != ve.Keyword {
+				ve.Keyword = ""
+			}
+		}
+		return []validator.ValidationError{ve}
+	}
+	if r.Message != "" && r.Path != "" {
+		errs[0].Path = r.Path
+	}
+	return errs
+}
+
+// commonPath returns the longest JSON pointer that is a parent of, or equal to, the paths of all errs
+func commonPath(errs []validator.ValidationError) string {
+	common := strings.Split(errs[0].Path, "/")
+	for _, e := range errs[1:] {
+		fields := strings.Split(e.Path, "/")
+		i := 0
+		for i < len(common) && i < len(fields) && common[i] == fields[i] {
+			i++
+		}
+		common = common[:i]
+	}
+	return strings.Join(common, "/")
+}
+
+// leaves returns the validation errors at the bottom of the tree of causes of e
+func leaves(e *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(e.Causes) == 0 {
+		return []*jsonschema.ValidationError{e}
+	}
+	l := []*jsonschema.ValidationError{}
+	for _, cause := range e.Causes {
+		l = append(l, leaves(cause)...)
+	}
+	return l
+}
@@ -0,0 +1,187 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yannh/kubeconform/pkg/resource"
+	"github.com/yannh/kubeconform/pkg/validator"
+	"sigs.k8s.io/yaml"
+)
+
+const testRules = `
+rules:
+  - id: resource-limits
+    kinds: [apps/v1/Deployment]
+    message: containers must set resources.limits
+    path: /spec/template/spec/containers
+    expression: object.spec.template.spec.containers.all(c, has(c.resources) && has(c.resources.limits))
+  - id: no-host-network
+    kinds: [Pod, apps/v1/Deployment]
+    severity: warning
+    schema:
+      properties:
+        spec:
+          properties:
+            template:
+              properties:
+                spec:
+                  properties:
+                    hostNetwork:
+                      const: false
+  - id: team-label
+    schema:
+      required: [metadata]
+      properties:
+        metadata:
+          required: [labels]
+          properties:
+            labels:
+              required: [team]
+`
+
+func TestEvaluate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(testRules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := FromFiles(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, testCase := range []struct {
+		name     string
+		sig      resource.Signature
+		obj      string
+		expected []validator.ValidationError
+	}{
+		{
+			"compliant deployment",
+			resource.Signature{Kind: "Deployment", Version: "apps/v1"},
+			`{"metadata": {"labels": {"team": "a"}}, "spec": {"template": {"spec": {"containers": [{"resources": {"limits": {"cpu": 1}}}]}}}}`,
+			[]validator.ValidationError{},
+		},
+		{
+			"deployment breaking all rules",
+			resource.Signature{Kind: "Deployment", Version: "apps/v1"},
+			`{"metadata": {"labels": {}}, "spec": {"template": {"spec": {"hostNetwork": true, "containers": [{"name": "web"}]}}}}`,
+			[]validator.ValidationError{
+				{Path: "/spec/template/spec/containers", Msg: "containers must set resources.limits", Rule: "resource-limits", Severity: "error"},
+				{Path: "/spec/template/spec/hostNetwork", Msg: "value must be false", Keyword: "const", Rule: "no-host-network", Severity: "warning"},
+				{Path: "/metadata/labels", Msg: "missing property 'team'", Keyword: "required", Rule: "team-label", Severity: "error"},
+			},
+		},
+		{
+			"expression failing to evaluate",
+			resource.Signature{Kind: "Deployment", Version: "apps/v1"},
+			`{"metadata": {"labels": {"team": "a"}}}`,
+			[]validator.ValidationError{
+				{Path: "/spec/template/spec/containers", Msg: "failed evaluating expression: no such key: spec", Rule: "resource-limits", Severity: "error"},
+			},
+		},
+		{
+			"rules only apply to the kinds they list",
+			resource.Signature{Kind: "Deployment", Version: "apps/v1beta1"},
+			`{"metadata": {"labels": {"team": "a"}}, "spec": {"template": {"spec": {"hostNetwork": true}}}}`,
+			[]validator.ValidationError{},
+		},
+	} {
+		obj := map[string]any{}
+		if err := yaml.Unmarshal([]byte(testCase.obj), &obj); err != nil {
+			t.Fatal(err)
+		}
+		got := p.Evaluate(testCase.sig, obj)
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf("test \"%s\": expected %+v, got %+v", testCase.name, testCase.expected, got)
+		}
+	}
+}
+
+func TestEvaluateExpressionError(t *testing.T) {
+	p, err := New([]Rule{{ID: "replicas", Expression: "object.spec.replicas > 1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := p.Evaluate(resource.Signature{Kind: "Deployment", Version: "apps/v1"}, map[string]any{"spec": map[string]any{}})
+	expected := []validator.ValidationError{{Msg: "failed evaluating expression: no such key: replicas", Rule: "replicas", Severity: "error"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestEvaluateSchemaRuleMessage(t *testing.T) {
+	schema := map[string]any{
+		"properties": map[string]any{
+			"metadata": map[string]any{
+				"properties": map[string]any{
+					"name":   map[string]any{"type": "string"},
+					"labels": map[string]any{"type": "object"},
+				},
+			},
+			"spec": map[string]any{"required": []any{"replicas"}},
+		},
+	}
+	for _, testCase := range []struct {
+		name     string
+		rule     Rule
+		obj      string
+		expected []validator.ValidationError
+	}{
+		{
+			"message reported once, at the root",
+			Rule{ID: "documented", Message: "resources must be documented", Schema: schema},
+			`{"metadata": {"name": 1}, "spec": {}}`,
+			[]validator.ValidationError{{Path: "", Msg: "resources must be documented", Rule: "documented", Severity: "error"}},
+		},
+		{
+			"message reported once, at the field containing all causes",
+			Rule{ID: "documented", Message: "resources must be documented", Schema: schema},
+			`{"metadata": {"name": 1, "labels": []}, "spec": {"replicas": 1}}`,
+			[]validator.ValidationError{{Path: "/metadata", Msg: "resources must be documented", Keyword: "type", Rule: "documented", Severity: "error"}},
+		},
+		{
+			"message reported once, at the path of the rule",
+			Rule{ID: "documented", Message: "resources must be documented", Path: "/metadata/labels", Schema: schema},
+			`{"metadata": {"name": 1}, "spec": {}}`,
+			[]validator.ValidationError{{Path: "/metadata/labels", Msg: "resources must be documented", Rule: "documented", Severity: "error"}},
+		},
+	} {
+		p, err := New([]Rule{testCase.rule})
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj := map[string]any{}
+		if err := yaml.Unmarshal([]byte(testCase.obj), &obj); err != nil {
+			t.Fatal(err)
+		}
+		got := p.Evaluate(resource.Signature{Kind: "Deployment", Version: "apps/v1"}, obj)
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf("test \"%s\": expected %+v, got %+v", testCase.name, testCase.expected, got)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, testCase := range []struct {
+		name  string
+		rules []Rule
+		err   string
+	}{
+		{"missing id", []Rule{{Expression: "true"}}, "rules must have an id"},
+		{"duplicate id", []Rule{{ID: "a", Expression: "true"}, {ID: "a", Expression: "true"}}, "duplicate rule a"},
+		{"invalid severity", []Rule{{ID: "a", Severity: "info", Expression: "true"}}, "failed compiling rule a: severity must be error or warning"},
+		{"no check", []Rule{{ID: "a"}}, "failed compiling rule a: either schema or expression must be set"},
+		{"both checks", []Rule{{ID: "a", Expression: "true", Schema: map[string]any{}}}, "failed compiling rule a: only one of schema and expression can be set"},
+		{"invalid expression", []Rule{{ID: "a", Expression: "self.a"}}, "failed compiling rule a: failed compiling expression self.a: undeclared reference to 'self'"},
+		{"invalid schema", []Rule{{ID: "a", Schema: map[string]any{"type": 42}}}, "failed compiling rule a: "},
+	} {
+		_, err := New(testCase.rules)
+		if err == nil || !strings.HasPrefix(err.Error(), testCase.err) {
+			t.Errorf("test \"%s\": expected error %s, got %v", testCase.name, testCase.err, err)
+		}
+	}
+}
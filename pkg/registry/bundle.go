@@ -0,0 +1,315 @@
+package registry
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/yannh/kubeconform/pkg/loader"
+)
+
+// bundlePathTemplate is the path of schemas within a bundle, following the layout of kubernetes-json-schema
+const bundlePathTemplate = "{{ .NormalizedKubernetesVersion }}-standalone{{ .StrictSuffix }}/{{ .ResourceKind }}{{ .KindSuffix }}.json"
+
+// BundleEntryName returns the path of the schema for a resource within a bundle
+func BundleEntryName(resourceKind, resourceAPIVersion, k8sVersion string, strict bool) (string, error) {
+	return schemaPath(bundlePathTemplate, resourceKind, resourceAPIVersion, k8sVersion, strict)
+}
+
+// BundleRegistry serves schemas from a bundle, a tar, tar.gz or zip archive
+// containing schemas for one or more Kubernetes versions
+type BundleRegistry struct {
+	location string // prefix of the paths of the schemas, e.g. bundle://schemas.tar.gz
+	strict   bool
+	open     func() (bundleIndex, error) // indexes the files of the bundle
+
+	once  sync.Once
+	index bundleIndex
+	err   error
+}
+
+// bundleIndex reads the files of a bundle
+type bundleIndex interface {
+	// read returns the content of the file called name, and false if the bundle has no such file
+	read(name string) ([]byte, bool, error)
+}
+
+func newBundleRegistry(path string, strict bool) (*BundleRegistry, error) {
+	if _, err := bundleFormat(path); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed opening schema bundle %s: %s", path, err)
+	}
+
+	return &BundleRegistry{
+		location: "bundle://" + path,
+		strict:   strict,
+		open:     func() (bundleIndex, error) { return openBundle(path) },
+	}, nil
+}
+
+// DownloadSchema retrieves the schema for the resource from the bundle. The files of the
+// bundle are indexed the first time a schema is requested, and schemas read when requested.
+func (r *BundleRegistry) DownloadSchema(resourceKind, resourceAPIVersion, k8sVersion string) (string, any, error) {
+	r.once.Do(func() {
+		r.index, r.err = r.open()
+	})
+	if r.err != nil {
+		return "", nil, r.err
+	}
+
+	name, err := BundleEntryName(resourceKind, resourceAPIVersion, k8sVersion, r.strict)
+	if err != nil {
+		return "", nil, err
+	}
+
+	path := r.location + "/" + name
+	content, ok, err := r.index.read(name)
+	if err != nil {
+		return path, nil, err
+	}
+	if !ok {
+		return path, nil, loader.NewNotFoundError(fmt.Errorf("could not find schema %s in %s", name, r.location))
+	}
+
+	s, err := jsonschema.UnmarshalJSON(bytes.NewReader(content))
+	if err != nil {
+		return path, nil, loader.NewNonJSONResponseError(err)
+	}
+
+	return path, s, nil
+}
+
+// bundleFormat returns the format of the bundle at path, based on its extension
+func bundleFormat(path string) (string, error) {
+	switch {
+	case strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		return "tar.gz", nil
+	case strings.HasSuffix(path, ".tar"):
+		return "tar", nil
+	case strings.HasSuffix(path, ".zip"):
+		return "zip", nil
+	default:
+		return "", fmt.Errorf("unsupported schema bundle %s: bundles must be .tar, .tar.gz, .tgz or .zip files", path)
+	}
+}
+
+// openBundle indexes the files of the bundle at path, which stays open to read them. Files of zip
+// and tar bundles are read directly from the bundle. tar.gz bundles can not be read at random, so
+// they are decompressed to a temporary tar file first.
+func openBundle(path string) (bundleIndex, error) {
+	format, err := bundleFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "zip" {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed opening schema bundle %s: %s", path, err)
+		}
+		index := zipIndex{path: path, files: map[string]*zip.File{}}
+		for _, f := range zr.File {
+			if !f.FileInfo().IsDir() {
+				index.files[f.Name] = f
+			}
+		}
+		return index, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening schema bundle %s: %s", path, err)
+	}
+	if format == "tar" {
+		return indexTar(path, f)
+	}
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening schema bundle %s: %s", path, err)
+	}
+	defer gzr.Close()
+
+	tmp, err := os.CreateTemp("", "kubeconform-bundle-*.tar")
+	if err != nil {
+		return nil, fmt.Errorf("failed decompressing schema bundle %s: %s", path, err)
+	}
+	// The file stays readable once removed, except on Windows where it is left in the temporary folder
+	os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, gzr); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed decompressing schema bundle %s: %s", path, err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed decompressing schema bundle %s: %s", path, err)
+	}
+
+	return indexTar(path, tmp)
+}
+
+// zipIndex reads the files of a zip bundle
+type zipIndex struct {
+	path  string
+	files map[string]*zip.File
+}
+
+func (z zipIndex) read(name string) ([]byte, bool, error) {
+	f, ok := z.files[name]
+	if !ok {
+		return nil, false, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed reading %s from schema bundle %s: %s", name, z.path, err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed reading %s from schema bundle %s: %s", name, z.path, err)
+	}
+	return content, true, nil
+}
+
+// tarIndex reads the files of a tar bundle, from their offset in the bundle
+type tarIndex struct {
+	path  string
+	r     io.ReaderAt
+	files map[string]tarFile
+}
+
+type tarFile struct {
+	offset, size int64
+}
+
+func (t tarIndex) read(name string) ([]byte, bool, error) {
+	f, ok := t.files[name]
+	if !ok {
+		return nil, false, nil
+	}
+	content, err := io.ReadAll(io.NewSectionReader(t.r, f.offset, f.size))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed reading %s from schema bundle %s: %s", name, t.path, err)
+	}
+	return content, true, nil
+}
+
+// offsetReader keeps track of the offset of a file read by a tar.Reader
+type offsetReader struct {
+	f      *os.File
+	offset int64
+}
+
+func (o *offsetReader) Read(p []byte) (int, error) {
+	n, err := o.f.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+// Seek lets the tar.Reader skip the content of files, rather than read it
+func (o *offsetReader) Seek(offset int64, whence int) (int64, error) {
+	n, err := o.f.Seek(offset, whence)
+	if err == nil {
+		o.offset = n
+	}
+	return n, err
+}
+
+// indexTar indexes the files of the tar bundle f, read from the start, with the offset of their content
+func indexTar(path string, f *os.File) (tarIndex, error) {
+	index := tarIndex{path: path, r: f, files: map[string]tarFile{}}
+	or := &offsetReader{f: f}
+	tr := tar.NewReader(or)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return index, fmt.Errorf("failed reading schema bundle %s: %s", path, err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			index.files[hdr.Name] = tarFile{offset: or.offset, size: hdr.Size}
+		}
+	}
+
+	return index, nil
+}
+
+// WriteBundle writes schemas, indexed by name, to a bundle at path. The format of
+// the bundle depends on the extension of path: .tar, .tar.gz, .tgz or .zip.
+func WriteBundle(path string, schemas map[string][]byte) error {
+	format, err := bundleFormat(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed creating schema bundle %s: %s", path, err)
+	}
+	defer f.Close()
+
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if format == "zip" {
+		zw := zip.NewWriter(f)
+		for _, name := range names {
+			w, err := zw.Create(name)
+			if err != nil {
+				return fmt.Errorf("failed writing schema bundle %s: %s", path, err)
+			}
+			if _, err := w.Write(schemas[name]); err != nil {
+				return fmt.Errorf("failed writing schema bundle %s: %s", path, err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("failed writing schema bundle %s: %s", path, err)
+		}
+		return f.Close()
+	}
+
+	var w io.Writer = f
+	var gzw *gzip.Writer
+	if format == "tar.gz" {
+		gzw = gzip.NewWriter(f)
+		w = gzw
+	}
+
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(schemas[name])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed writing schema bundle %s: %s", path, err)
+		}
+		if _, err := tw.Write(schemas[name]); err != nil {
+			return fmt.Errorf("failed writing schema bundle %s: %s", path, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed writing schema bundle %s: %s", path, err)
+	}
+	if gzw != nil {
+		if err := gzw.Close(); err != nil {
+			return fmt.Errorf("failed writing schema bundle %s: %s", path, err)
+		}
+	}
+
+	return f.Close()
+}
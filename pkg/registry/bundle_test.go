@@ -0,0 +1,126 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yannh/kubeconform/pkg/loader"
+)
+
+func TestBundle(t *testing.T) {
+	schemas := map[string][]byte{
+		"master-standalone/deployment-apps-v1.json":               []byte(`{"type": "object"}`),
+		"v1.27.2-standalone-strict/deployment-apps-v1.json":       []byte(`{"type": "object", "additionalProperties": false}`),
+		"v1.27.2-standalone-strict/replicationcontroller-v1.json": []byte(`not json`),
+		// Long names are stored in additional headers in tar bundles
+		"v1.27.2-standalone-strict/" + strings.Repeat("a", 200) + ".json": []byte(`{"type": "string"}`),
+	}
+
+	for _, format := range []string{"tar", "tar.gz", "tgz", "zip"} {
+		path := filepath.Join(t.TempDir(), "schemas."+format)
+		if err := WriteBundle(path, schemas); err != nil {
+			t.Fatalf("%s: failed writing bundle: %s", format, err)
+		}
+
+		index, err := openBundle(path)
+		if err != nil {
+			t.Fatalf("%s: failed opening bundle: %s", format, err)
+		}
+		for name, content := range schemas {
+			if got, ok, err := index.read(name); err != nil || !ok || !reflect.DeepEqual(got, content) {
+				t.Errorf("%s: expected %s to be %s, got %s (%t, %v)", format, name, content, got, ok, err)
+			}
+		}
+		if _, ok, err := index.read("master-standalone/service-v1.json"); ok || err != nil {
+			t.Errorf("%s: expected no file for a missing schema, got %t, %v", format, ok, err)
+		}
+
+		reg, err := New("bundle://"+path, "", true, false, false)
+		if err != nil {
+			t.Fatalf("%s: failed creating registry: %s", format, err)
+		}
+
+		p, s, err := reg.DownloadSchema("Deployment", "apps/v1", "1.27.2")
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", format, err)
+		}
+		if p != "bundle://"+path+"/v1.27.2-standalone-strict/deployment-apps-v1.json" {
+			t.Errorf("%s: unexpected path %s", format, p)
+		}
+		if schema, ok := s.(map[string]any); !ok || schema["additionalProperties"] != false {
+			t.Errorf("%s: unexpected schema %+v", format, s)
+		}
+
+		if _, _, err := reg.DownloadSchema("Deployment", "apps/v1", "master"); err == nil {
+			t.Errorf("%s: expected an error for a missing schema", format)
+		} else if _, ok := err.(*loader.NotFoundError); !ok {
+			t.Errorf("%s: expected a NotFoundError for a missing schema, got %s", format, err)
+		}
+
+		if _, _, err := reg.DownloadSchema("ReplicationController", "v1", "1.27.2"); err == nil {
+			t.Errorf("%s: expected an error for an invalid schema", format)
+		} else if _, ok := err.(*loader.NotFoundError); !ok { // NewNonJSONResponseError returns a NotFoundError
+			t.Errorf("%s: expected the invalid schema to be skipped, got %s", format, err)
+		}
+	}
+
+	if _, err := New("bundle://schemas.rar", "", false, false, false); err == nil {
+		t.Errorf("expected an error for an unsupported bundle format")
+	}
+	if _, err := New("bundle://does-not-exist.tar.gz", "", false, false, false); err == nil {
+		t.Errorf("expected an error for a missing bundle")
+	}
+}
+
+func TestListKinds(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "v1.27.2-standalone-strict"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	definitions := `{
+  "definitions": {
+    "io.k8s.api.apps.v1.Deployment": {
+      "x-kubernetes-group-version-kind": [{"group": "apps", "kind": "Deployment", "version": "v1"}]
+    },
+    "io.k8s.api.core.v1.Pod": {
+      "x-kubernetes-group-version-kind": [{"group": "", "kind": "Pod", "version": "v1"}]
+    },
+    "io.k8s.apimachinery.pkg.apis.meta.v1.Status": {
+      "x-kubernetes-group-version-kind": [{"group": "", "kind": "Status", "version": "v1"}]
+    },
+    "io.k8s.api.autoscaling.v1.Scale": {
+      "x-kubernetes-group-version-kind": [{"group": "autoscaling", "kind": "Scale", "version": "v1"}]
+    },
+    "io.k8s.apimachinery.pkg.apis.meta.v1.DeleteOptions": {
+      "x-kubernetes-group-version-kind": [
+        {"group": "", "kind": "DeleteOptions", "version": "v1"},
+        {"group": "apps", "kind": "DeleteOptions", "version": "v1"}
+      ]
+    },
+    "io.k8s.api.core.v1.PodSpec": {}
+  }
+}`
+	if err := os.WriteFile(filepath.Join(dir, "v1.27.2-standalone-strict", "_definitions.json"), []byte(definitions), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ListKinds(dir, "1.27.2", true, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expect := []Manifest{
+		{Kind: "Deployment", Version: "apps/v1"},
+		{Kind: "Scale", Version: "autoscaling/v1"},
+		{Kind: "Pod", Version: "v1"},
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected %+v, got %+v", expect, got)
+	}
+
+	if _, err := ListKinds(dir, "master", false, false); err == nil {
+		t.Errorf("expected an error when no definitions are found")
+	}
+}
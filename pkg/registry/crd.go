@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/yannh/kubeconform/pkg/crd"
+	"github.com/yannh/kubeconform/pkg/loader"
+)
+
+// CRDRegistry is an in-memory registry, serving schemas generated from
+// CustomResourceDefinitions added while validating resources
+type CRDRegistry struct {
+	sync.RWMutex
+	strict  bool
+	schemas map[string]map[string]any // apiVersion/kind -> schema
+}
+
+// NewCRDRegistry creates a new, empty, CRDRegistry. In strict mode, the generated
+// schemas disallow additional properties, like the -strict schemas of kubernetes-json-schema.
+func NewCRDRegistry(strict bool) *CRDRegistry {
+	return &CRDRegistry{
+		strict:  strict,
+		schemas: map[string]map[string]any{},
+	}
+}
+
+// AddCRD converts the CustomResourceDefinition obj to JSON schemas and adds them to the
+// registry. It returns the kinds and apiVersions the registry now has schemas for.
+func (r *CRDRegistry) AddCRD(obj map[string]any) ([]Manifest, error) {
+	schemas, err := crd.ToSchemas(obj, crd.Opts{
+		DenyAdditionalProperties:     r.strict,
+		DenyRootAdditionalProperties: r.strict,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	manifests := []Manifest{}
+	for _, s := range schemas {
+		r.schemas[s.APIVersion()+"/"+s.Kind] = s.Schema
+		manifests = append(manifests, Manifest{Kind: s.Kind, Version: s.APIVersion()})
+	}
+
+	return manifests, nil
+}
+
+// DownloadSchema retrieves the schema generated for the resource, if one of the
+// CustomResourceDefinitions added to the registry defines it
+func (r *CRDRegistry) DownloadSchema(resourceKind, resourceAPIVersion, k8sVersion string) (string, any, error) {
+	r.RLock()
+	defer r.RUnlock()
+	path := "crd://" + resourceAPIVersion + "/" + resourceKind
+	s, ok := r.schemas[resourceAPIVersion+"/"+resourceKind]
+	if !ok {
+		return path, nil, loader.NewNotFoundError(fmt.Errorf("no CustomResourceDefinition found for %s %s", resourceAPIVersion, resourceKind))
+	}
+
+	return path, s, nil
+}
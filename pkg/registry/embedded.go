@@ -0,0 +1,58 @@
+package registry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+)
+
+// embeddedBundlesDir is the folder containing the schemas embedded in the binary when building
+// with the embedschemas build tag, generated with "make embedded-schemas". Each schema is
+// compressed on its own, with the path it has in bundles and a .gz extension, so it can be
+// read without reading the others.
+const embeddedBundlesDir = "embedded"
+
+// newEmbeddedRegistry creates a registry serving the schemas embedded in the binary
+func newEmbeddedRegistry(strict bool) (*BundleRegistry, error) {
+	if !EmbeddedSchemas {
+		return nil, fmt.Errorf("failed initialising schema location embedded: kubeconform was built without embedded schemas, see the embedschemas build tag")
+	}
+
+	return &BundleRegistry{
+		location: "embedded:",
+		strict:   strict,
+		open:     func() (bundleIndex, error) { return fsIndex{fsys: embeddedBundles, dir: embeddedBundlesDir}, nil },
+	}, nil
+}
+
+// fsIndex reads the gzipped schemas stored in the folder dir of fsys
+type fsIndex struct {
+	fsys fs.FS
+	dir  string
+}
+
+func (i fsIndex) read(name string) ([]byte, bool, error) {
+	compressed, err := fs.ReadFile(i.fsys, path.Join(i.dir, name+".gz"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed reading embedded schema %s: %s", name, err)
+	}
+
+	gzr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed reading embedded schema %s: %s", name, err)
+	}
+	defer gzr.Close()
+	content, err := io.ReadAll(gzr)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed reading embedded schema %s: %s", name, err)
+	}
+
+	return content, true, nil
+}
@@ -0,0 +1,10 @@
+//go:build !embedschemas
+
+package registry
+
+import "embed"
+
+// EmbeddedSchemas is true if kubeconform was built with schemas embedded in the binary
+const EmbeddedSchemas = false
+
+var embeddedBundles embed.FS
@@ -0,0 +1,11 @@
+//go:build embedschemas
+
+package registry
+
+import "embed"
+
+// EmbeddedSchemas is true if kubeconform was built with schemas embedded in the binary
+const EmbeddedSchemas = true
+
+//go:embed embedded
+var embeddedBundles embed.FS
@@ -0,0 +1,54 @@
+package registry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFSIndex(t *testing.T) {
+	gzipped := func(content string) []byte {
+		var buf bytes.Buffer
+		gzw := gzip.NewWriter(&buf)
+		gzw.Write([]byte(content))
+		gzw.Close()
+		return buf.Bytes()
+	}
+
+	index := fsIndex{
+		fsys: fstest.MapFS{
+			"embedded/master-standalone/deployment-apps-v1.json.gz": {Data: gzipped(`{"type": "object"}`)},
+			"embedded/v1.27.2-standalone/service-v1.json.gz":        {Data: gzipped(`{"type": "object"}`)},
+			"embedded/v1.27.2-standalone/pod-v1.json.gz":            {Data: []byte("not gzipped")},
+		},
+		dir: "embedded",
+	}
+
+	for _, testCase := range []struct {
+		name          string
+		expectContent string
+		expectFound   bool
+		expectErr     bool
+	}{
+		{"master-standalone/deployment-apps-v1.json", `{"type": "object"}`, true, false},
+		{"v1.27.2-standalone/service-v1.json", `{"type": "object"}`, true, false},
+		{"v1.27.2-standalone/deployment-apps-v1.json", "", false, false},
+		{"v1.27.2-standalone/pod-v1.json", "", false, true},
+	} {
+		content, found, err := index.read(testCase.name)
+		if string(content) != testCase.expectContent || found != testCase.expectFound || (err != nil) != testCase.expectErr {
+			t.Errorf("%s: expected %s, %t, error %t, got %s, %t, %v", testCase.name, testCase.expectContent, testCase.expectFound, testCase.expectErr, content, found, err)
+		}
+	}
+}
+
+func TestNewEmbedded(t *testing.T) {
+	_, err := New("embedded", "", false, false, false)
+	if EmbeddedSchemas && err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if !EmbeddedSchemas && err == nil {
+		t.Errorf("expected an error when built without embedded schemas")
+	}
+}
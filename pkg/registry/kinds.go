@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/yannh/kubeconform/pkg/loader"
+)
+
+// ListKinds returns the kinds and apiVersions a schema location has schemas for. It relies on
+// the _definitions.json file published next to the schemas by kubernetes-json-schema, and
+// will fail for schema locations that do not follow that layout. The kinds of the API
+// machinery, such as DeleteOptions or WatchEvent, which are not resources, are left out.
+func ListKinds(schemaLocation, k8sVersion string, strict, skipTLS bool) ([]Manifest, error) {
+	p, err := schemaPath(expandLocation(schemaLocation), "Deployment", "apps/v1", k8sVersion, strict)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.LastIndex(p, "/")
+	if i < 0 {
+		return nil, fmt.Errorf("failed finding definitions for schema location %s", schemaLocation)
+	}
+	definitionsPath := p[:i+1] + "_definitions.json"
+
+	var l jsonschema.URLLoader = loader.NewFileLoader()
+	if strings.HasPrefix(definitionsPath, "http") {
+		if l, err = loader.NewHTTPURLLoader(skipTLS, nil); err != nil {
+			return nil, fmt.Errorf("failed creating HTTP loader: %s", err)
+		}
+	}
+
+	doc, err := l.Load(definitionsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	root, _ := doc.(map[string]any)
+	definitions, ok := root["definitions"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("failed parsing %s: no definitions found", definitionsPath)
+	}
+
+	seen := map[Manifest]struct{}{}
+	manifests := []Manifest{}
+	for name, d := range definitions {
+		def, _ := d.(map[string]any)
+		gvks, _ := def["x-kubernetes-group-version-kind"].([]any)
+		// Types shared by all API groups, such as DeleteOptions, are declared with a kind per group
+		if strings.HasPrefix(name, "io.k8s.apimachinery.pkg.apis.meta.") || len(gvks) > 1 {
+			continue
+		}
+		for _, g := range gvks {
+			gvk, _ := g.(map[string]any)
+			group, _ := gvk["group"].(string)
+			version, _ := gvk["version"].(string)
+			kind, _ := gvk["kind"].(string)
+			if kind == "" || version == "" {
+				continue
+			}
+
+			m := Manifest{Kind: kind, Version: version}
+			if group != "" {
+				m.Version = group + "/" + version
+			}
+			if _, ok := seen[m]; !ok {
+				seen[m] = struct{}{}
+				manifests = append(manifests, m)
+			}
+		}
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		if manifests[i].Version != manifests[j].Version {
+			return manifests[i].Version < manifests[j].Version
+		}
+		return manifests[i].Kind < manifests[j].Kind
+	})
+
+	return manifests, nil
+}
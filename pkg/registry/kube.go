@@ -0,0 +1,227 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/yannh/kubeconform/pkg/cache"
+	"github.com/yannh/kubeconform/pkg/crd"
+	"github.com/yannh/kubeconform/pkg/loader"
+)
+
+// componentsPrefix is the prefix of the references between the schemas of an OpenAPI document
+const componentsPrefix = "#/components/schemas/"
+
+// KubeRegistry serves schemas read from the /openapi/v3 endpoint of a Kubernetes cluster,
+// including the schemas of the custom resources it defines. Schemas match the version of
+// the cluster, whatever the Kubernetes version validated against.
+type KubeRegistry struct {
+	location string // kube://, followed by the kubeconfig context
+	client   *kubeClient
+	strict   bool
+	debug    bool
+	cache    cache.Cache // caches the OpenAPI documents of group versions, which are immutable for a given hash
+
+	indexOnce sync.Once
+	index     map[string]string // group version, e.g. apis/apps/v1 -> URL of its OpenAPI document, relative to the server
+	indexErr  error
+
+	sync.Mutex
+	documents map[string]map[string]any // group version -> schemas of its OpenAPI document
+}
+
+func newKubeRegistry(kubeContext string, c cache.Cache, strict bool, skipTLS bool, debug bool) (*KubeRegistry, error) {
+	client, err := newKubeClient(kubeContext, skipTLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed initialising kube:// registry: %s", err)
+	}
+
+	return &KubeRegistry{
+		location:  "kube://" + kubeContext,
+		client:    client,
+		strict:    strict,
+		debug:     debug,
+		cache:     c,
+		documents: map[string]map[string]any{},
+	}, nil
+}
+
+// DownloadSchema returns a standalone schema for the resource, generated from the OpenAPI
+// document of its group version. The list of documents is retrieved the first time a
+// schema is requested.
+func (r *KubeRegistry) DownloadSchema(resourceKind, resourceAPIVersion, k8sVersion string) (string, any, error) {
+	group, version, found := strings.Cut(resourceAPIVersion, "/")
+	groupVersion := "apis/" + resourceAPIVersion
+	if !found {
+		group, version = "", resourceAPIVersion
+		groupVersion = "api/" + resourceAPIVersion
+	}
+	path := r.location + "/" + groupVersion + "/" + strings.ToLower(resourceKind)
+
+	r.indexOnce.Do(func() {
+		r.index, r.indexErr = r.loadIndex()
+	})
+	if r.indexErr != nil {
+		return path, nil, r.indexErr
+	}
+
+	url, ok := r.index[groupVersion]
+	if !ok {
+		return path, nil, loader.NewNotFoundError(fmt.Errorf("could not find %s in the OpenAPI documents of %s", resourceAPIVersion, r.location))
+	}
+
+	schemas, err := r.groupVersionSchemas(groupVersion, url)
+	if err != nil {
+		return path, nil, err
+	}
+
+	for name, s := range schemas {
+		if hasGroupVersionKind(s, group, version, resourceKind) {
+			schema := standaloneSchema(name, schemas)
+			if r.strict {
+				crd.DenyAdditionalProperties(schema, false)
+			}
+			return path, schema, nil
+		}
+	}
+
+	return path, nil, loader.NewNotFoundError(fmt.Errorf("could not find %s %s in the OpenAPI documents of %s", resourceAPIVersion, resourceKind, r.location))
+}
+
+// loadIndex returns the OpenAPI documents served by the cluster, indexed by group version
+func (r *KubeRegistry) loadIndex() (map[string]string, error) {
+	body, found, err := r.client.get("/openapi/v3")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("the cluster of %s does not serve /openapi/v3, Kubernetes 1.27 or later is required", r.location)
+	}
+
+	discovery := struct {
+		Paths map[string]struct {
+			ServerRelativeURL string `json:"serverRelativeURL"`
+		} `json:"paths"`
+	}{}
+	if err := json.Unmarshal(body, &discovery); err != nil {
+		return nil, fmt.Errorf("failed parsing OpenAPI discovery document of %s: %s", r.location, err)
+	}
+
+	index := map[string]string{}
+	for gv, p := range discovery.Paths {
+		index[gv] = p.ServerRelativeURL
+	}
+	return index, nil
+}
+
+// groupVersionSchemas returns the schemas of the OpenAPI document of a group version, reading
+// it from the cache if possible
+func (r *KubeRegistry) groupVersionSchemas(groupVersion, url string) (map[string]any, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if schemas, ok := r.documents[groupVersion]; ok {
+		return schemas, nil
+	}
+
+	key := r.client.server + url
+	var body []byte
+	if r.cache != nil {
+		if cached, err := r.cache.Get(key); err == nil {
+			body = cached.([]byte)
+		}
+	}
+	if body == nil {
+		if r.debug {
+			log.Printf("downloading OpenAPI document %s", key)
+		}
+		b, found, err := r.client.get(url)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, loader.NewNotFoundError(fmt.Errorf("could not find OpenAPI document %s", key))
+		}
+		body = b
+		if r.cache != nil {
+			if err := r.cache.Set(key, body); err != nil {
+				return nil, fmt.Errorf("failed to write cache to disk: %s", err)
+			}
+		}
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing OpenAPI document %s: %s", key, err)
+	}
+	components, _ := doc.(map[string]any)["components"].(map[string]any)
+	schemas, _ := components["schemas"].(map[string]any)
+	if schemas == nil {
+		schemas = map[string]any{}
+	}
+
+	r.documents[groupVersion] = schemas
+	return schemas, nil
+}
+
+// hasGroupVersionKind returns true if the OpenAPI schema s describes the resource group/version/kind
+func hasGroupVersionKind(s any, group, version, kind string) bool {
+	obj, _ := s.(map[string]any)
+	gvks, _ := obj["x-kubernetes-group-version-kind"].([]any)
+	for _, gvk := range gvks {
+		gvk, _ := gvk.(map[string]any)
+		if gvk["group"] == group && gvk["version"] == version && gvk["kind"] == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// standaloneSchema returns a copy of the schema called name, along with copies of the schemas
+// it references, directly or not, so that its references can be resolved without the
+// rest of the OpenAPI document
+func standaloneSchema(name string, schemas map[string]any) map[string]any {
+	referenced := map[string]any{}
+
+	var copyRefs func(v any) any
+	copyRefs = func(v any) any {
+		switch v := v.(type) {
+		case map[string]any:
+			c := make(map[string]any, len(v))
+			for k, val := range v {
+				c[k] = copyRefs(val)
+			}
+			if ref, ok := v["$ref"].(string); ok && strings.HasPrefix(ref, componentsPrefix) {
+				refName := strings.TrimPrefix(ref, componentsPrefix)
+				if _, done := referenced[refName]; !done {
+					if s, ok := schemas[refName]; ok {
+						referenced[refName] = nil // the schema might reference itself
+						referenced[refName] = copyRefs(s)
+					}
+				}
+			}
+			return c
+		case []any:
+			c := make([]any, len(v))
+			for i, val := range v {
+				c[i] = copyRefs(val)
+			}
+			return c
+		}
+		return v
+	}
+
+	root, _ := copyRefs(schemas[name]).(map[string]any)
+	if root == nil {
+		root = map[string]any{}
+	}
+	if len(referenced) > 0 {
+		root["components"] = map[string]any{"schemas": referenced}
+	}
+	return root
+}
@@ -0,0 +1,215 @@
+package registry
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/yannh/kubeconform/pkg/loader"
+	"sigs.k8s.io/yaml"
+)
+
+// newKubeServer returns a stand-in for the API server of a cluster, serving the OpenAPI
+// documents of testdata/openapi to clients authenticated with the token test-token
+func newKubeServer(t *testing.T) (*httptest.Server, map[string]int) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		name := "v3"
+		if gv, ok := strings.CutPrefix(r.URL.Path, "/openapi/v3/"); ok {
+			name = strings.ReplaceAll(gv, "/", "_")
+		} else if r.URL.Path != "/openapi/v3" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", "openapi", name+".json"))
+	}))
+	t.Cleanup(s.Close)
+
+	return s, requests
+}
+
+// writeKubeconfig writes a kubeconfig with a context called test, connecting to server, and sets $KUBECONFIG
+func writeKubeconfig(t *testing.T, server *httptest.Server) {
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	kc := map[string]any{
+		"apiVersion":      "v1",
+		"kind":            "Config",
+		"current-context": "test",
+		"clusters":        []any{map[string]any{"name": "test-cluster", "cluster": map[string]any{"server": server.URL, "certificate-authority-data": ca}}},
+		"contexts":        []any{map[string]any{"name": "test", "context": map[string]any{"cluster": "test-cluster", "user": "test-user"}}},
+		"users":           []any{map[string]any{"name": "test-user", "user": map[string]any{"tokenFile": "token"}}},
+	}
+	content, err := yaml.Marshal(kc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config"), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("test-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBECONFIG", filepath.Join(dir, "config"))
+}
+
+func TestKubeRegistry(t *testing.T) {
+	server, requests := newKubeServer(t)
+	writeKubeconfig(t, server)
+
+	reg, err := New("kube://", "", false, false, false)
+	if err != nil {
+		t.Fatalf("failed creating registry: %s", err)
+	}
+
+	for _, testCase := range []struct {
+		kind, apiVersion string
+		expectedPath     string
+		valid, invalid   string
+		notFound         bool
+	}{
+		{
+			kind:         "ConfigMap",
+			apiVersion:   "v1",
+			expectedPath: "kube:///api/v1/configmap",
+			valid:        `{"kind": "ConfigMap", "metadata": {"name": "cm", "ownerReferences": [{"apiVersion": "v1", "kind": "Pod", "name": "p", "uid": "1"}]}, "data": {"a": "b"}}`,
+			invalid:      `{"kind": "ConfigMap", "metadata": {"ownerReferences": [{"name": "p"}]}}`,
+		},
+		{
+			kind:         "Deployment",
+			apiVersion:   "apps/v1",
+			expectedPath: "kube:///apis/apps/v1/deployment",
+			valid:        `{"kind": "Deployment", "spec": {"replicas": 2, "strategy": {"type": "RollingUpdate", "rollingUpdate": {"maxSurge": "25%"}}}}`,
+			invalid:      `{"kind": "Deployment", "spec": {"replicas": "two"}}`,
+		},
+		{
+			kind:         "CronTab",
+			apiVersion:   "stable.example.com/v1",
+			expectedPath: "kube:///apis/stable.example.com/v1/crontab",
+			valid:        `{"kind": "CronTab", "spec": {"cronSpec": "* * * * */5", "replicas": 1}}`,
+			invalid:      `{"kind": "CronTab", "spec": {"cronSpec": 5}}`,
+		},
+		{kind: "StatefulSet", apiVersion: "apps/v1", expectedPath: "kube:///apis/apps/v1/statefulset", notFound: true},
+		{kind: "Widget", apiVersion: "example.com/v1", expectedPath: "kube:///apis/example.com/v1/widget", notFound: true},
+	} {
+		path, s, err := reg.DownloadSchema(testCase.kind, testCase.apiVersion, "master")
+		if path != testCase.expectedPath {
+			t.Errorf("%s: expected path %s, got %s", testCase.kind, testCase.expectedPath, path)
+		}
+		if testCase.notFound {
+			var nf *loader.NotFoundError
+			if !errors.As(err, &nf) {
+				t.Errorf("%s: expected a NotFoundError, got %v", testCase.kind, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: failed downloading schema: %s", testCase.kind, err)
+			continue
+		}
+
+		c := jsonschema.NewCompiler()
+		c.DefaultDraft(jsonschema.Draft4)
+		if err := c.AddResource(path, s); err != nil {
+			t.Fatal(err)
+		}
+		schema, err := c.Compile(path)
+		if err != nil {
+			t.Errorf("%s: failed compiling schema: %s", testCase.kind, err)
+			continue
+		}
+		for doc, expectValid := range map[string]bool{testCase.valid: true, testCase.invalid: false} {
+			instance, _ := jsonschema.UnmarshalJSON(strings.NewReader(doc))
+			if err := schema.Validate(instance); (err == nil) != expectValid {
+				t.Errorf("%s: expected %s to be valid: %t, got %v", testCase.kind, doc, expectValid, err)
+			}
+		}
+	}
+
+	if requests["/openapi/v3"] != 1 || requests["/openapi/v3/apis/apps/v1"] != 1 {
+		t.Errorf("expected OpenAPI documents to be downloaded once, got %v", requests)
+	}
+}
+
+func TestKubeRegistryCache(t *testing.T) {
+	server, requests := newKubeServer(t)
+	writeKubeconfig(t, server)
+	cacheFolder := t.TempDir()
+
+	for i := 0; i < 2; i++ {
+		reg, err := New("kube://test", cacheFolder, true, false, false)
+		if err != nil {
+			t.Fatalf("failed creating registry: %s", err)
+		}
+		_, s, err := reg.DownloadSchema("Deployment", "apps/v1", "master")
+		if err != nil {
+			t.Fatalf("failed downloading schema: %s", err)
+		}
+		if s.(map[string]any)["additionalProperties"] != false {
+			t.Errorf("expected strict schemas to disallow additional properties")
+		}
+	}
+
+	if requests["/openapi/v3"] != 2 || requests["/openapi/v3/apis/apps/v1"] != 1 {
+		t.Errorf("expected the document of apps/v1 to be read from the cache, got %v", requests)
+	}
+}
+
+func TestNewKubeRegistryErrors(t *testing.T) {
+	server, _ := newKubeServer(t)
+	writeKubeconfig(t, server)
+
+	for _, testCase := range []struct {
+		location string
+		err      string
+	}{
+		{"kube://missing", "failed initialising kube:// registry: could not find kubeconfig context missing"},
+	} {
+		if _, err := New(testCase.location, "", false, false, false); err == nil || err.Error() != testCase.err {
+			t.Errorf("%s: expected error %s, got %v", testCase.location, testCase.err, err)
+		}
+	}
+
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	if _, err := New("kube://", "", false, false, false); err == nil {
+		t.Errorf("expected an error without kubeconfig")
+	}
+}
+
+func TestStandaloneSchema(t *testing.T) {
+	schemas := map[string]any{
+		"A": map[string]any{"properties": map[string]any{"b": map[string]any{"$ref": componentsPrefix + "B"}}},
+		"B": map[string]any{"items": map[string]any{"$ref": componentsPrefix + "B"}},
+		"C": map[string]any{"type": "string"},
+	}
+
+	got := standaloneSchema("A", schemas)
+	expected := fmt.Sprint(map[string]any{
+		"properties": map[string]any{"b": map[string]any{"$ref": componentsPrefix + "B"}},
+		"components": map[string]any{"schemas": map[string]any{"B": schemas["B"]}},
+	})
+	if fmt.Sprint(got) != expected {
+		t.Errorf("expected %s, got %s", expected, fmt.Sprint(got))
+	}
+}
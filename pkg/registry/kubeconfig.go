@@ -0,0 +1,369 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// inClusterFolder contains the credentials of the service account of pods running in a cluster
+var inClusterFolder = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// kubeconfig is the subset of the kubeconfig format used to connect to a cluster
+// https://kubernetes.io/docs/concepts/configuration/organize-cluster-access-kubeconfig/
+type kubeconfig struct {
+	CurrentContext string `json:"current-context"`
+	Clusters       []struct {
+		Name    string      `json:"name"`
+		Cluster kubeCluster `json:"cluster"`
+	} `json:"clusters"`
+	Contexts []struct {
+		Name    string `json:"name"`
+		Context struct {
+			Cluster string `json:"cluster"`
+			User    string `json:"user"`
+		} `json:"context"`
+	} `json:"contexts"`
+	Users []struct {
+		Name string   `json:"name"`
+		User kubeUser `json:"user"`
+	} `json:"users"`
+}
+
+type kubeCluster struct {
+	Server                   string `json:"server"`
+	CertificateAuthority     string `json:"certificate-authority"`
+	CertificateAuthorityData []byte `json:"certificate-authority-data"`
+	InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify"`
+	TLSServerName            string `json:"tls-server-name"`
+}
+
+type kubeUser struct {
+	ClientCertificate     string    `json:"client-certificate"`
+	ClientCertificateData []byte    `json:"client-certificate-data"`
+	ClientKey             string    `json:"client-key"`
+	ClientKeyData         []byte    `json:"client-key-data"`
+	Token                 string    `json:"token"`
+	TokenFile             string    `json:"tokenFile"`
+	Username              string    `json:"username"`
+	Password              string    `json:"password"`
+	Exec                  *kubeExec `json:"exec"`
+}
+
+// kubeExec is a credential plugin, such as the ones used by managed Kubernetes services
+type kubeExec struct {
+	APIVersion string   `json:"apiVersion"`
+	Command    string   `json:"command"`
+	Args       []string `json:"args"`
+	Env        []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"env"`
+}
+
+// kubeClient sends authenticated requests to the API server of a cluster
+type kubeClient struct {
+	server   string
+	client   *http.Client
+	token    string
+	username string
+	password string
+}
+
+// get returns the body of the response to a GET request for path, relative to the server.
+// found is false if the server responded with a 404 status.
+func (c *kubeClient) get(path string) (body []byte, found bool, err error) {
+	req, err := http.NewRequest(http.MethodGet, c.server+path, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	} else if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed querying %s: %s", c.server+path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("error while querying %s - received HTTP status %d", c.server+path, resp.StatusCode)
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed reading response from %s: %s", c.server+path, err)
+	}
+	return body, true, nil
+}
+
+// kubeconfigPaths returns the kubeconfig files to read: the files listed in $KUBECONFIG,
+// or ~/.kube/config
+func kubeconfigPaths() []string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		paths := []string{}
+		for _, p := range filepath.SplitList(env) {
+			if p != "" {
+				paths = append(paths, p)
+			}
+		}
+		return paths
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	p := filepath.Join(home, ".kube", "config")
+	if _, err := os.Stat(p); err != nil {
+		return nil
+	}
+	return []string{p}
+}
+
+// loadKubeconfig reads and merges the kubeconfig files at paths. As with kubectl, the first
+// file setting a value wins. Relative paths are resolved from the folder of the file they are in.
+func loadKubeconfig(paths []string) (kubeconfig, error) {
+	merged := kubeconfig{}
+	for _, p := range paths {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return merged, fmt.Errorf("failed reading kubeconfig %s: %s", p, err)
+		}
+
+		kc := kubeconfig{}
+		if err := yaml.Unmarshal(content, &kc); err != nil {
+			return merged, fmt.Errorf("failed parsing kubeconfig %s: %s", p, err)
+		}
+
+		dir := filepath.Dir(p)
+		resolve := func(path *string) {
+			if *path != "" && !filepath.IsAbs(*path) {
+				*path = filepath.Join(dir, *path)
+			}
+		}
+		for i := range kc.Clusters {
+			resolve(&kc.Clusters[i].Cluster.CertificateAuthority)
+		}
+		for i := range kc.Users {
+			resolve(&kc.Users[i].User.ClientCertificate)
+			resolve(&kc.Users[i].User.ClientKey)
+			resolve(&kc.Users[i].User.TokenFile)
+		}
+
+		if merged.CurrentContext == "" {
+			merged.CurrentContext = kc.CurrentContext
+		}
+		merged.Clusters = append(merged.Clusters, kc.Clusters...)
+		merged.Contexts = append(merged.Contexts, kc.Contexts...)
+		merged.Users = append(merged.Users, kc.Users...)
+	}
+
+	return merged, nil
+}
+
+// newKubeClient returns a client for the cluster of the kubeconfig context kubeContext, or of
+// the current context if kubeContext is empty. Without kubeconfig, the credentials of the
+// service account are used when running in a cluster.
+func newKubeClient(kubeContext string, skipTLS bool) (*kubeClient, error) {
+	paths := kubeconfigPaths()
+	if len(paths) == 0 && kubeContext == "" && os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return inClusterClient(skipTLS)
+	}
+
+	kc, err := loadKubeconfig(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	if kubeContext == "" {
+		kubeContext = kc.CurrentContext
+	}
+	if kubeContext == "" {
+		return nil, fmt.Errorf("no kubeconfig context selected, and no current context set")
+	}
+
+	var clusterName, userName string
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == kubeContext {
+			clusterName, userName, found = c.Context.Cluster, c.Context.User, true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("could not find kubeconfig context %s", kubeContext)
+	}
+
+	var cluster *kubeCluster
+	for i := range kc.Clusters {
+		if kc.Clusters[i].Name == clusterName {
+			cluster = &kc.Clusters[i].Cluster
+			break
+		}
+	}
+	if cluster == nil || cluster.Server == "" {
+		return nil, fmt.Errorf("could not find cluster %s of kubeconfig context %s", clusterName, kubeContext)
+	}
+
+	user := kubeUser{}
+	for _, u := range kc.Users {
+		if u.Name == userName {
+			user = u.User
+			break
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: skipTLS || cluster.InsecureSkipTLSVerify,
+		ServerName:         cluster.TLSServerName,
+	}
+
+	caData := cluster.CertificateAuthorityData
+	if len(caData) == 0 && cluster.CertificateAuthority != "" {
+		if caData, err = os.ReadFile(cluster.CertificateAuthority); err != nil {
+			return nil, fmt.Errorf("failed reading certificate authority: %s", err)
+		}
+	}
+	if len(caData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("failed parsing certificate authority of cluster %s", clusterName)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if user.Exec != nil {
+		if err := runExecPlugin(user.Exec, &user); err != nil {
+			return nil, err
+		}
+	}
+
+	certData, keyData := user.ClientCertificateData, user.ClientKeyData
+	if len(certData) == 0 && user.ClientCertificate != "" {
+		if certData, err = os.ReadFile(user.ClientCertificate); err != nil {
+			return nil, fmt.Errorf("failed reading client certificate: %s", err)
+		}
+	}
+	if len(keyData) == 0 && user.ClientKey != "" {
+		if keyData, err = os.ReadFile(user.ClientKey); err != nil {
+			return nil, fmt.Errorf("failed reading client key: %s", err)
+		}
+	}
+	if len(certData) > 0 {
+		cert, err := tls.X509KeyPair(certData, keyData)
+		if err != nil {
+			return nil, fmt.Errorf("failed loading client certificate of user %s: %s", userName, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	token := user.Token
+	if token == "" && user.TokenFile != "" {
+		content, err := os.ReadFile(user.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading token file: %s", err)
+		}
+		token = string(bytes.TrimSpace(content))
+	}
+
+	return &kubeClient{
+		server:   cluster.Server,
+		client:   newKubeHTTPClient(tlsConfig),
+		token:    token,
+		username: user.Username,
+		password: user.Password,
+	}, nil
+}
+
+// inClusterClient returns a client authenticated with the service account of the pod
+func inClusterClient(skipTLS bool) (*kubeClient, error) {
+	token, err := os.ReadFile(filepath.Join(inClusterFolder, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("failed reading service account token: %s", err)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: skipTLS}
+	if caData, err := os.ReadFile(filepath.Join(inClusterFolder, "ca.crt")); err == nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(caData)
+		tlsConfig.RootCAs = pool
+	}
+
+	return &kubeClient{
+		server: "https://" + os.Getenv("KUBERNETES_SERVICE_HOST") + ":" + os.Getenv("KUBERNETES_SERVICE_PORT"),
+		client: newKubeHTTPClient(tlsConfig),
+		token:  string(bytes.TrimSpace(token)),
+	}, nil
+}
+
+func newKubeHTTPClient(tlsConfig *tls.Config) *http.Client {
+	return &http.Client{
+		Timeout: 60 * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+}
+
+// runExecPlugin runs the credential plugin e, and sets the token or client certificate
+// it returns on user
+// https://kubernetes.io/docs/reference/access-authn-authz/authentication/#client-go-credential-plugins
+func runExecPlugin(e *kubeExec, user *kubeUser) error {
+	cmd := exec.Command(e.Command, e.Args...)
+	cmd.Env = os.Environ()
+	for _, env := range e.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	execInfo, _ := json.Marshal(map[string]any{
+		"apiVersion": e.APIVersion,
+		"kind":       "ExecCredential",
+		"spec":       map[string]any{"interactive": false},
+	})
+	cmd.Env = append(cmd.Env, "KUBERNETES_EXEC_INFO="+string(execInfo))
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed running credential plugin %s: %s", e.Command, err)
+	}
+
+	credential := struct {
+		Status struct {
+			Token                 string `json:"token"`
+			ClientCertificateData string `json:"clientCertificateData"`
+			ClientKeyData         string `json:"clientKeyData"`
+		} `json:"status"`
+	}{}
+	if err := json.Unmarshal(out, &credential); err != nil {
+		return fmt.Errorf("failed parsing output of credential plugin %s: %s", e.Command, err)
+	}
+
+	user.Token = credential.Status.Token
+	if credential.Status.ClientCertificateData != "" {
+		user.ClientCertificateData = []byte(credential.Status.ClientCertificateData)
+		user.ClientKeyData = []byte(credential.Status.ClientKeyData)
+	}
+	return nil
+}
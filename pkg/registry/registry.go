@@ -68,16 +68,22 @@ func schemaPath(tpl, resourceKind, resourceAPIVersion, k8sVersion string, strict
 	return buf.String(), nil
 }
 
-func New(schemaLocation string, cacheFolder string, strict bool, skipTLS bool, debug bool) (Registry, error) {
+// expandLocation returns the full template of a schema location
+func expandLocation(schemaLocation string) string {
 	if schemaLocation == "default" {
-		schemaLocation = "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .NormalizedKubernetesVersion }}-standalone{{ .StrictSuffix }}/{{ .ResourceKind }}{{ .KindSuffix }}.json"
+		return "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .NormalizedKubernetesVersion }}-standalone{{ .StrictSuffix }}/{{ .ResourceKind }}{{ .KindSuffix }}.json"
 	} else if !strings.HasSuffix(schemaLocation, "json") { // If we dont specify a full templated path, we assume the paths of our fork of kubernetes-json-schema
-		schemaLocation += "/{{ .NormalizedKubernetesVersion }}-standalone{{ .StrictSuffix }}/{{ .ResourceKind }}{{ .KindSuffix }}.json"
+		return schemaLocation + "/{{ .NormalizedKubernetesVersion }}-standalone{{ .StrictSuffix }}/{{ .ResourceKind }}{{ .KindSuffix }}.json"
 	}
+	return schemaLocation
+}
 
-	// try to compile the schemaLocation template to ensure it is valid
-	if _, err := schemaPath(schemaLocation, "Deployment", "v1", "master", true); err != nil {
-		return nil, fmt.Errorf("failed initialising schema location registry: %s", err)
+func New(schemaLocation string, cacheFolder string, strict bool, skipTLS bool, debug bool) (Registry, error) {
+	if path, ok := strings.CutPrefix(schemaLocation, "bundle://"); ok {
+		return newBundleRegistry(path, strict)
+	}
+	if schemaLocation == "embedded" {
+		return newEmbeddedRegistry(strict)
 	}
 
 	var c cache.Cache = nil
@@ -93,6 +99,17 @@ func New(schemaLocation string, cacheFolder string, strict bool, skipTLS bool, d
 		c = cache.NewOnDiskCache(cacheFolder)
 	}
 
+	if kubeContext, ok := strings.CutPrefix(schemaLocation, "kube://"); ok {
+		return newKubeRegistry(kubeContext, c, strict, skipTLS, debug)
+	}
+
+	schemaLocation = expandLocation(schemaLocation)
+
+	// try to compile the schemaLocation template to ensure it is valid
+	if _, err := schemaPath(schemaLocation, "Deployment", "v1", "master", true); err != nil {
+		return nil, fmt.Errorf("failed initialising schema location registry: %s", err)
+	}
+
 	if strings.HasPrefix(schemaLocation, "http") {
 		httpLoader, err := loader.NewHTTPURLLoader(skipTLS, c)
 		if err != nil {
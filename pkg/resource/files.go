@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 func isYAMLFile(info os.FileInfo) bool {
@@ -18,6 +19,13 @@ func isJSONFile(info os.FileInfo) bool {
 	return !info.IsDir() && (strings.HasSuffix(strings.ToLower(info.Name()), ".json"))
 }
 
+// DiscoveryOpts configures how resources are discovered by FromFilesWithOpts
+type DiscoveryOpts struct {
+	IgnoreFilePatterns []string   // regular expressions specifying paths to ignore
+	Render             RenderOpts // rendering of Helm charts and Kustomize folders
+	Readers            int        // number of files read concurrently, 1 if not set
+}
+
 type DiscoveryError struct {
 	Path string
 	Err  error
@@ -40,11 +48,17 @@ func isIgnored(path string, ignoreFilePatterns []string) (bool, error) {
 	return false, nil
 }
 
-func findFilesInFolders(ctx context.Context, paths []string, ignoreFilePatterns []string) (chan string, chan error) {
+// findFilesInFolders sends the files to read on the files channel. When rendering
+// is enabled, folders containing a Helm chart or a Kustomize configuration are sent
+// instead of the files they contain.
+func findFilesInFolders(ctx context.Context, paths []string, opts DiscoveryOpts) (chan string, chan error) {
 	files := make(chan string)
 	errors := make(chan error)
+	ignoreFilePatterns := opts.IgnoreFilePatterns
 
 	go func() {
+		skipFilesIn := map[string]struct{}{}
+		kustomizations := []string{}
 		for _, path := range paths {
 			// we handle errors in the walk function directly
 			// so it should be safe to discard the outer error
@@ -59,10 +73,18 @@ func findFilesInFolders(ctx context.Context, paths []string, ignoreFilePatterns
 					return err
 				}
 
+				if i.IsDir() && opts.Render.Enabled {
+					return renderableFolder(p, opts, files, skipFilesIn, &kustomizations)
+				}
+
 				if !isYAMLFile(i) && !isJSONFile(i) {
 					return nil
 				}
 
+				if _, skip := skipFilesIn[filepath.Dir(p)]; skip {
+					return nil
+				}
+
 				ignored, err := isIgnored(p, ignoreFilePatterns)
 				if err != nil {
 					return err
@@ -81,23 +103,66 @@ func findFilesInFolders(ctx context.Context, paths []string, ignoreFilePatterns
 			}
 		}
 
+		for _, k := range topLevelKustomizations(kustomizations) {
+			if ctx.Err() != nil {
+				break
+			}
+			files <- k
+		}
+
 		close(files)
 	}()
 
 	return files, errors
 }
 
+// renderableFolder sends the folder p to the files channel if it contains a Helm chart, or a
+// selected Kustomize overlay, and returns filepath.SkipDir so its files are not read individually.
+// Kustomize folders that are not selected are added to skipFilesIn. If no overlay is selected, Kustomize
+// folders are added to kustomizations instead, and only the ones not used by another are rendered.
+func renderableFolder(p string, opts DiscoveryOpts, files chan<- string, skipFilesIn map[string]struct{}, kustomizations *[]string) error {
+	ignored, err := isIgnored(p, opts.IgnoreFilePatterns)
+	if err != nil {
+		return err
+	}
+	if ignored {
+		return filepath.SkipDir
+	}
+
+	switch {
+	case isHelmChart(p):
+		files <- p
+		return filepath.SkipDir // subcharts are rendered with their parent chart
+
+	case isKustomization(p):
+		if len(opts.Render.KustomizeOverlays) == 0 {
+			*kustomizations = append(*kustomizations, p)
+			return filepath.SkipDir
+		}
+		if opts.Render.isSelectedOverlay(p) {
+			files <- p
+			return filepath.SkipDir
+		}
+		// Files of a Kustomize folder that is not rendered are not valid resources
+		// on their own, but its sub-folders might contain overlays
+		skipFilesIn[p] = struct{}{}
+	}
+
+	return nil
+}
+
 func findResourcesInReader(p string, f io.Reader, resources chan<- Resource, errors chan<- error, buf []byte) {
 	maxBufSize := 256 * 1024 * 1024
 	scanner := bufio.NewScanner(f)
 	// We start with a buf that is 4MB, scanner will resize it up to 256MB if needed
 	// https://github.com/golang/go/blob/aeea5bacbf79fb945edbeac6cd7630dd70c4d9ce/src/bufio/scan.go#L191
 	scanner.Buffer(buf, maxBufSize)
-	scanner.Split(SplitYAMLDocument)
+	lines := newLineCounter()
+	scanner.Split(lines.split)
 	nRes := 0
 	for scanner.Scan() {
 		if len(scanner.Text()) > 0 {
-			res := Resource{Path: p, Bytes: []byte(scanner.Text())}
+			res := Resource{Path: p, Bytes: []byte(scanner.Text()), Line: lines.line}
 			for _, subres := range res.Resources() {
 				resources <- subres
 				nRes++
@@ -124,19 +189,70 @@ func findResourcesInFile(p string, resources chan<- Resource, errors chan<- erro
 	findResourcesInReader(p, f, resources, errors, buf)
 }
 
+func renderFolder(p string, opts RenderOpts, resources chan<- Resource, errors chan<- error) {
+	var res []Resource
+	var err error
+	if isHelmChart(p) {
+		res, err = renderHelmChart(p, opts)
+	} else {
+		res, err = renderKustomization(p)
+	}
+	if err != nil {
+		errors <- DiscoveryError{p, err}
+		return
+	}
+
+	for _, r := range res {
+		resources <- r
+	}
+	if len(res) == 0 {
+		resources <- Resource{Path: p, Bytes: []byte{}}
+	}
+}
+
+// FromFiles finds resources in the files and folders given in paths, skipping
+// files matching one of ignoreFilePatterns
 func FromFiles(ctx context.Context, paths []string, ignoreFilePatterns []string) (<-chan Resource, <-chan error) {
+	return FromFilesWithOpts(ctx, paths, DiscoveryOpts{IgnoreFilePatterns: ignoreFilePatterns})
+}
+
+// FromFilesWithOpts finds resources in the files and folders given in paths. Files are read
+// and split into resources concurrently by a pool of opts.Readers goroutines, so resources of
+// different files are not sent in a particular order.
+func FromFilesWithOpts(ctx context.Context, paths []string, opts DiscoveryOpts) (<-chan Resource, <-chan error) {
 	resources := make(chan Resource)
 
-	files, errors := findFilesInFolders(ctx, paths, ignoreFilePatterns)
+	files, errors := findFilesInFolders(ctx, paths, opts)
 
-	go func() {
-		initialBufSize := 4 * 1024 * 1024   // This is the initial size - scanner will resize if needed
-		buf := make([]byte, initialBufSize) // We reuse the same buffer to avoid multiple large memory allocations
+	readers := opts.Readers
+	if readers < 1 {
+		readers = 1
+	}
 
-		for p := range files {
-			findResourcesInFile(p, resources, errors, buf)
-		}
+	wg := sync.WaitGroup{}
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			initialBufSize := 4 * 1024 * 1024   // This is the initial size - scanner will resize if needed
+			buf := make([]byte, initialBufSize) // Each reader reuses its buffer to avoid multiple large memory allocations
 
+			for p := range files {
+				if ctx.Err() != nil {
+					continue // files are drained so that the search for files can stop
+				}
+				if fi, err := os.Stat(p); opts.Render.Enabled && err == nil && fi.IsDir() {
+					renderFolder(p, opts.Render, resources, errors)
+					continue
+				}
+				findResourcesInFile(p, resources, errors, buf)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
 		close(errors)
 		close(resources)
 	}()
@@ -1,7 +1,12 @@
 package resource
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
@@ -202,3 +207,79 @@ lorem: ipsum
 		}
 	}
 }
+
+func TestFromFilesWithReaders(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{}
+	expect := []string{}
+	for i := 0; i < 50; i++ {
+		files[fmt.Sprintf("app-%d/resources.yaml", i)] = fmt.Sprintf("kind: ConfigMap\nmetadata:\n  name: config-%d\n---\nkind: Secret\nmetadata:\n  name: secret-%d\n", i, i)
+		expect = append(expect, fmt.Sprintf("ConfigMap config-%d", i), fmt.Sprintf("Secret secret-%d", i))
+	}
+	writeFiles(t, root, files)
+	if err := os.Symlink(filepath.Join(root, "missing.yaml"), filepath.Join(root, "broken.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(expect)
+
+	for _, readers := range []int{0, 1, 8} {
+		resources, errs := FromFilesWithOpts(context.Background(), []string{root}, DiscoveryOpts{Readers: readers})
+		got := []string{}
+		gotErrs := []error{}
+		for resources != nil || errs != nil {
+			select {
+			case res, ok := <-resources:
+				if !ok {
+					resources = nil
+					continue
+				}
+				sig, _ := res.Signature()
+				got = append(got, sig.Kind+" "+sig.Name)
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				gotErrs = append(gotErrs, err)
+			}
+		}
+
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, expect) {
+			t.Errorf("%d readers: expected %d resources, got %+v", readers, len(expect), got)
+		}
+		if len(gotErrs) != 1 {
+			t.Errorf("%d readers: expected an error for the broken link, got %+v", readers, gotErrs)
+			continue
+		}
+		if de, ok := gotErrs[0].(DiscoveryError); !ok || de.Path != filepath.Join(root, "broken.yaml") {
+			t.Errorf("%d readers: expected a DiscoveryError for the broken link, got %+v", readers, gotErrs[0])
+		}
+	}
+}
+
+func TestFromFilesCancelled(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{}
+	for i := 0; i < 50; i++ {
+		files[fmt.Sprintf("resources-%d.yaml", i)] = "kind: ConfigMap\nmetadata:\n  name: config\n"
+	}
+	writeFiles(t, root, files)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resources, errs := FromFilesWithOpts(ctx, []string{root}, DiscoveryOpts{Readers: 4})
+	go func() {
+		for range errs {
+		}
+	}()
+
+	<-resources
+	cancel()
+	n := 1
+	for range resources {
+		n++
+	}
+	if n >= 50 {
+		t.Errorf("expected discovery to stop when the context is cancelled, got %d resources", n)
+	}
+}
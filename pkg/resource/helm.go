@@ -0,0 +1,670 @@
+package resource
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"sigs.k8s.io/yaml"
+)
+
+// Charts are rendered in-process, like helm template renders them: templates are Go templates
+// using the Sprig functions and the functions Helm adds, such as include, tpl and toYaml. The
+// release is named release-name and installed in the default namespace, and lookup returns
+// no resources, as there is no cluster to read them from.
+const (
+	helmReleaseName      = "release-name"
+	helmReleaseNamespace = "default"
+	helmKubeVersion      = "v1.33.0"
+)
+
+// helmAPIVersions are the API versions templates can check with .Capabilities.APIVersions.Has
+var helmAPIVersions = []string{
+	"v1",
+	"admissionregistration.k8s.io/v1",
+	"apiextensions.k8s.io/v1",
+	"apiregistration.k8s.io/v1",
+	"apps/v1",
+	"authentication.k8s.io/v1",
+	"authorization.k8s.io/v1",
+	"autoscaling/v1",
+	"autoscaling/v2",
+	"batch/v1",
+	"certificates.k8s.io/v1",
+	"coordination.k8s.io/v1",
+	"discovery.k8s.io/v1",
+	"events.k8s.io/v1",
+	"flowcontrol.apiserver.k8s.io/v1",
+	"networking.k8s.io/v1",
+	"node.k8s.io/v1",
+	"policy/v1",
+	"rbac.authorization.k8s.io/v1",
+	"scheduling.k8s.io/v1",
+	"storage.k8s.io/v1",
+}
+
+// chartMetadata is the content of the Chart.yaml file of a chart, available to templates as .Chart
+type chartMetadata struct {
+	APIVersion   string            `json:"apiVersion,omitempty"`
+	Name         string            `json:"name,omitempty"`
+	Version      string            `json:"version,omitempty"`
+	KubeVersion  string            `json:"kubeVersion,omitempty"`
+	Description  string            `json:"description,omitempty"`
+	Type         string            `json:"type,omitempty"`
+	Keywords     []string          `json:"keywords,omitempty"`
+	Home         string            `json:"home,omitempty"`
+	Sources      []string          `json:"sources,omitempty"`
+	Dependencies []chartDependency `json:"dependencies,omitempty"`
+	Maintainers  []chartMaintainer `json:"maintainers,omitempty"`
+	Icon         string            `json:"icon,omitempty"`
+	AppVersion   string            `json:"appVersion,omitempty"`
+	Deprecated   bool              `json:"deprecated,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+type chartDependency struct {
+	Name       string   `json:"name"`
+	Version    string   `json:"version,omitempty"`
+	Repository string   `json:"repository,omitempty"`
+	Condition  string   `json:"condition,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Alias      string   `json:"alias,omitempty"`
+}
+
+type chartMaintainer struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// chartFile is a file of a chart, named with its slash-separated path relative to the chart
+type chartFile struct {
+	name string
+	data []byte
+}
+
+// chart is a Helm chart, read from a folder, or from a packaged subchart
+type chart struct {
+	Metadata  chartMetadata
+	path      string                 // folder the chart was read from, or path of the package
+	values    map[string]interface{} // default values, from values.yaml
+	templates []chartFile            // files of the templates folder
+	crds      []chartFile            // files of the crds folder
+	files     chartFiles             // other files, available to templates as .Files
+	subcharts []*chart
+}
+
+// loadChartDir reads the chart in the folder dir, and its subcharts
+func loadChartDir(dir string) (*chart, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = b
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newChart(dir, files)
+}
+
+// loadChartArchive reads the packaged chart p, a gzipped tar archive of the chart folder
+func loadChartArchive(p string, b []byte) (*chart, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed reading chart archive %s: %s", p, err)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed reading chart archive %s: %s", p, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		// Files are stored in a folder named after the chart
+		_, name, ok := strings.Cut(path.Clean(hdr.Name), "/")
+		if !ok {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading chart archive %s: %s", p, err)
+		}
+		files[name] = data
+	}
+
+	return newChart(p, files)
+}
+
+// newChart creates the chart read from p, from its files indexed by their slash-separated path
+func newChart(p string, files map[string][]byte) (*chart, error) {
+	metadata, ok := files["Chart.yaml"]
+	if !ok {
+		return nil, fmt.Errorf("%s does not contain a Chart.yaml", p)
+	}
+
+	c := &chart{path: p, values: map[string]interface{}{}, files: chartFiles{}}
+	if err := yaml.Unmarshal(metadata, &c.Metadata); err != nil {
+		return nil, fmt.Errorf("failed reading %s: %s", filepath.Join(p, "Chart.yaml"), err)
+	}
+	if c.Metadata.Name == "" {
+		return nil, fmt.Errorf("%s does not set the name of the chart", filepath.Join(p, "Chart.yaml"))
+	}
+	if values, ok := files["values.yaml"]; ok {
+		if err := yaml.Unmarshal(values, &c.values); err != nil {
+			return nil, fmt.Errorf("failed reading %s: %s", filepath.Join(p, "values.yaml"), err)
+		}
+		if c.values == nil {
+			c.values = map[string]interface{}{}
+		}
+	}
+
+	subcharts := map[string]map[string][]byte{}
+	archives := []string{}
+	for name, data := range files {
+		switch {
+		case name == "Chart.yaml" || name == "values.yaml" || name == "Chart.lock":
+		case strings.HasPrefix(name, "templates/"):
+			c.templates = append(c.templates, chartFile{name, data})
+		case strings.HasPrefix(name, "charts/"):
+			sub, rest, ok := strings.Cut(strings.TrimPrefix(name, "charts/"), "/")
+			if !ok {
+				if strings.HasSuffix(sub, ".tgz") {
+					archives = append(archives, sub)
+				}
+				continue
+			}
+			if subcharts[sub] == nil {
+				subcharts[sub] = map[string][]byte{}
+			}
+			subcharts[sub][rest] = data
+		default:
+			if strings.HasPrefix(name, "crds/") {
+				c.crds = append(c.crds, chartFile{name, data})
+			}
+			c.files[name] = data
+		}
+	}
+	sort.Slice(c.templates, func(i, j int) bool { return c.templates[i].name < c.templates[j].name })
+	sort.Slice(c.crds, func(i, j int) bool { return c.crds[i].name < c.crds[j].name })
+
+	dirs := make([]string, 0, len(subcharts))
+	for dir := range subcharts {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		if _, ok := subcharts[dir]["Chart.yaml"]; !ok {
+			continue
+		}
+		sub, err := newChart(filepath.Join(p, "charts", dir), subcharts[dir])
+		if err != nil {
+			return nil, err
+		}
+		c.subcharts = append(c.subcharts, sub)
+	}
+
+	sort.Strings(archives)
+	for _, archive := range archives {
+		sub, err := loadChartArchive(filepath.Join(p, "charts", archive), files["charts/"+archive])
+		if err != nil {
+			return nil, err
+		}
+		c.subcharts = append(c.subcharts, sub)
+	}
+
+	return c, nil
+}
+
+// dependency returns the dependency of the chart c declared for the subchart sub, if any
+func (c *chart) dependency(sub *chart) (chartDependency, bool) {
+	for _, dep := range c.Metadata.Dependencies {
+		if dep.Name == sub.Metadata.Name {
+			return dep, true
+		}
+	}
+	return chartDependency{}, false
+}
+
+// enabledSubcharts returns the subcharts of c enabled by the values of c, renamed if their
+// dependency sets an alias. Subcharts are disabled by the condition, or the tags, of their
+// dependency, and subcharts without a dependency are always enabled.
+func (c *chart) enabledSubcharts(values map[string]interface{}) []*chart {
+	enabled := []*chart{}
+	for _, sub := range c.subcharts {
+		dep, ok := c.dependency(sub)
+		if !ok {
+			enabled = append(enabled, sub)
+			continue
+		}
+		if !dependencyEnabled(dep, values) {
+			continue
+		}
+		if dep.Alias != "" {
+			aliased := *sub
+			aliased.Metadata.Name = dep.Alias
+			sub = &aliased
+		}
+		enabled = append(enabled, sub)
+	}
+	return enabled
+}
+
+// dependencyEnabled evaluates the condition and the tags of the dependency dep against the
+// values of the parent chart. The first path of the condition set to a boolean decides,
+// otherwise the dependency is enabled unless all its tags are disabled.
+func dependencyEnabled(dep chartDependency, values map[string]interface{}) bool {
+	for _, cond := range strings.Split(dep.Condition, ",") {
+		if b, ok := lookupValue(values, strings.TrimSpace(cond)).(bool); ok {
+			return b
+		}
+	}
+
+	if len(dep.Tags) == 0 {
+		return true
+	}
+	tags, _ := values["tags"].(map[string]interface{})
+	for _, tag := range dep.Tags {
+		if b, ok := tags[tag].(bool); !ok || b {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupValue returns the value at the dot-separated path p of values, nil if there is none
+func lookupValue(values map[string]interface{}, p string) interface{} {
+	if p == "" {
+		return nil
+	}
+	var v interface{} = values
+	for _, key := range strings.Split(p, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+// mergeValues returns the values dst, overridden by the values src. Maps are merged
+// recursively, and keys set to null in src are removed, like Helm merges values files.
+func mergeValues(dst, src map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(dst))
+	for k, v := range dst {
+		merged[k] = v
+	}
+	for k, v := range src {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := merged[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			merged[k] = mergeValues(dstMap, srcMap)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// coalesceValues merges the values given to the chart c with its default values. The values
+// of each enabled subchart are set under the name of the subchart, merged with its own default
+// values, and the global values of c are passed down to the subcharts.
+func coalesceValues(c *chart, values map[string]interface{}) map[string]interface{} {
+	values = mergeValues(c.values, values)
+	global, _ := values["global"].(map[string]interface{})
+	for _, sub := range c.enabledSubcharts(values) {
+		subValues, _ := values[sub.Metadata.Name].(map[string]interface{})
+		subGlobal, _ := subValues["global"].(map[string]interface{})
+		subValues = mergeValues(subValues, map[string]interface{}{"global": mergeValues(subGlobal, global)})
+		values[sub.Metadata.Name] = coalesceValues(sub, subValues)
+	}
+	return values
+}
+
+// readValuesFiles reads and merges the values files, the last one taking precedence
+func readValuesFiles(valuesFiles []string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, valuesFile := range valuesFiles {
+		b, err := os.ReadFile(valuesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading values file: %s", err)
+		}
+		v := map[string]interface{}{}
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return nil, fmt.Errorf("failed reading values file %s: %s", valuesFile, err)
+		}
+		values = mergeValues(values, v)
+	}
+	return values, nil
+}
+
+// chartTemplate is a template of a chart, or of one of its subcharts, with the data it is executed with
+type chartTemplate struct {
+	name string                 // name of the template, e.g. mychart/charts/redis/templates/service.yaml
+	path string                 // path of the file the template was read from
+	data map[string]interface{} // top-level object of the template
+}
+
+// chartTemplates returns the templates of the chart c and of its enabled subcharts, prefixing
+// their names with prefix, and the CRDs they include. values are the coalesced values of c.
+func chartTemplates(c *chart, prefix string, values map[string]interface{}, tmpls map[string]string) ([]chartTemplate, []Resource) {
+	prefix = path.Join(prefix, c.Metadata.Name)
+	templates := []chartTemplate{}
+	crds := []Resource{}
+	for _, f := range c.crds {
+		crds = append(crds, splitRenderedOutput(f.data, filepath.Join(c.path, filepath.FromSlash(f.name)))...)
+	}
+	for _, f := range c.templates {
+		name := path.Join(prefix, f.name)
+		tmpls[name] = string(f.data)
+		templates = append(templates, chartTemplate{
+			name: name,
+			path: filepath.Join(c.path, filepath.FromSlash(f.name)),
+			data: map[string]interface{}{
+				"Values":       values,
+				"Chart":        c.Metadata,
+				"Files":        c.files,
+				"Release":      helmRelease(),
+				"Capabilities": helmCapabilities(),
+				"Template":     map[string]interface{}{"Name": name, "BasePath": path.Join(prefix, "templates")},
+			},
+		})
+	}
+
+	for _, sub := range c.enabledSubcharts(values) {
+		subValues, _ := values[sub.Metadata.Name].(map[string]interface{})
+		subTemplates, subCRDs := chartTemplates(sub, path.Join(prefix, "charts"), subValues, tmpls)
+		templates = append(templates, subTemplates...)
+		crds = append(crds, subCRDs...)
+	}
+
+	return templates, crds
+}
+
+func helmRelease() map[string]interface{} {
+	return map[string]interface{}{
+		"Name":      helmReleaseName,
+		"Namespace": helmReleaseNamespace,
+		"IsInstall": true,
+		"IsUpgrade": false,
+		"Revision":  1,
+		"Service":   "Helm",
+	}
+}
+
+// helmKubernetesVersion is the version of Kubernetes templates read from .Capabilities.KubeVersion
+type helmKubernetesVersion struct {
+	Version string
+	Major   string
+	Minor   string
+}
+
+func (v helmKubernetesVersion) String() string { return v.Version }
+
+// GitVersion is kept for the charts written for Helm 2
+func (v helmKubernetesVersion) GitVersion() string { return v.Version }
+
+// helmVersionSet are the API versions of .Capabilities.APIVersions
+type helmVersionSet []string
+
+// Has returns true if the API version, such as apps/v1, or the resource, such as
+// apps/v1/Deployment, is available
+func (s helmVersionSet) Has(apiVersion string) bool {
+	for _, v := range s {
+		if v == apiVersion {
+			return true
+		}
+		if kind, ok := strings.CutPrefix(apiVersion, v+"/"); ok && kind != "" && !strings.Contains(kind, "/") && kind[0] >= 'A' && kind[0] <= 'Z' {
+			return true
+		}
+	}
+	return false
+}
+
+func helmCapabilities() map[string]interface{} {
+	major, minor, _ := strings.Cut(strings.TrimPrefix(helmKubeVersion, "v"), ".")
+	minor, _, _ = strings.Cut(minor, ".")
+	return map[string]interface{}{
+		"KubeVersion": helmKubernetesVersion{Version: helmKubeVersion, Major: major, Minor: minor},
+		"APIVersions": helmVersionSet(helmAPIVersions),
+	}
+}
+
+// chartFiles are the files of a chart that are not templates, available to templates as .Files
+type chartFiles map[string][]byte
+
+// Get returns the content of the file name, an empty string if it does not exist
+func (f chartFiles) Get(name string) string {
+	return string(f[name])
+}
+
+// GetBytes returns the content of the file name, nil if it does not exist
+func (f chartFiles) GetBytes(name string) []byte {
+	return f[name]
+}
+
+// Lines returns the lines of the file name
+func (f chartFiles) Lines(name string) []string {
+	if f[name] == nil {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(string(f[name]), "\n"), "\n")
+}
+
+// Glob returns the files matching pattern
+func (f chartFiles) Glob(pattern string) chartFiles {
+	matching := chartFiles{}
+	for name, data := range f {
+		if ok, _ := path.Match(pattern, name); ok {
+			matching[name] = data
+		}
+	}
+	return matching
+}
+
+// AsConfig returns the files as the YAML data of a ConfigMap, indexed by file name
+func (f chartFiles) AsConfig() string {
+	m := map[string]string{}
+	for name, data := range f {
+		m[path.Base(name)] = string(data)
+	}
+	return toYAML(m)
+}
+
+// AsSecrets returns the files as the YAML data of a Secret, indexed by file name
+func (f chartFiles) AsSecrets() string {
+	m := map[string]string{}
+	for name, data := range f {
+		m[path.Base(name)] = base64.StdEncoding.EncodeToString(data)
+	}
+	return toYAML(m)
+}
+
+func toYAML(v interface{}) string {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(string(b), "\n")
+}
+
+func fromYAML(s string) map[string]interface{} {
+	m := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(s), &m); err != nil {
+		m["Error"] = err.Error()
+	}
+	return m
+}
+
+func fromYAMLArray(s string) []interface{} {
+	a := []interface{}{}
+	if err := yaml.Unmarshal([]byte(s), &a); err != nil {
+		a = []interface{}{err.Error()}
+	}
+	return a
+}
+
+func toJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func fromJSON(s string) map[string]interface{} {
+	m := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		m["Error"] = err.Error()
+	}
+	return m
+}
+
+func fromJSONArray(s string) []interface{} {
+	a := []interface{}{}
+	if err := json.Unmarshal([]byte(s), &a); err != nil {
+		a = []interface{}{err.Error()}
+	}
+	return a
+}
+
+// helmFuncs returns the functions available to the templates of charts: the Sprig functions,
+// except the ones reading environment variables, and the functions added by Helm. include and
+// tpl execute templates of t.
+func helmFuncs(t *template.Template) template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	delete(funcs, "env")
+	delete(funcs, "expandenv")
+
+	for name, f := range map[string]interface{}{
+		"toYaml":        toYAML,
+		"fromYaml":      fromYAML,
+		"fromYamlArray": fromYAMLArray,
+		"toJson":        toJSON,
+		"fromJson":      fromJSON,
+		"fromJsonArray": fromJSONArray,
+		"include": func(name string, data interface{}) (string, error) {
+			var buf strings.Builder
+			if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+		"tpl": func(text string, data interface{}) (string, error) {
+			clone, err := t.Clone()
+			if err != nil {
+				return "", err
+			}
+			tt, err := clone.New("tpl").Parse(text)
+			if err != nil {
+				return "", fmt.Errorf("failed parsing tpl template: %s", err)
+			}
+			var buf strings.Builder
+			if err := tt.Execute(&buf, data); err != nil {
+				return "", err
+			}
+			return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+		},
+		"required": func(msg string, v interface{}) (interface{}, error) {
+			if v == nil {
+				return nil, errors.New(msg)
+			}
+			if s, ok := v.(string); ok && s == "" {
+				return nil, errors.New(msg)
+			}
+			return v, nil
+		},
+		"lookup": func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+			return map[string]interface{}{}, nil
+		},
+	} {
+		funcs[name] = f
+	}
+
+	return funcs
+}
+
+// renderHelmChart renders the chart in the folder dir, with the values of the values files,
+// like helm template --include-crds. Each resource is given the path of the template, or of
+// the CRD, it was read from, such as dir/templates/deployment.yaml.
+func renderHelmChart(dir string, opts RenderOpts) ([]Resource, error) {
+	c, err := loadChartDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading Helm chart: %s", err)
+	}
+
+	values, err := readValuesFiles(opts.HelmValuesFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpls := map[string]string{}
+	templates, resources := chartTemplates(c, "", coalesceValues(c, values), tmpls)
+
+	t := template.New("chart").Option("missingkey=zero")
+	t.Funcs(helmFuncs(t))
+	names := make([]string, 0, len(tmpls))
+	for name := range tmpls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := t.New(name).Parse(tmpls[name]); err != nil {
+			return nil, fmt.Errorf("failed parsing template: %s", err)
+		}
+	}
+
+	for _, tmpl := range templates {
+		// Templates starting with an underscore only define named templates
+		base := path.Base(tmpl.name)
+		if strings.HasPrefix(base, "_") || base == "NOTES.txt" {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := t.ExecuteTemplate(&buf, tmpl.name, tmpl.data); err != nil {
+			return nil, fmt.Errorf("failed rendering template: %s", err)
+		}
+		out := bytes.ReplaceAll(buf.Bytes(), []byte("<no value>"), []byte{})
+		resources = append(resources, splitRenderedOutput(out, tmpl.path)...)
+	}
+
+	return resources, nil
+}
@@ -0,0 +1,185 @@
+package resource
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	yaml "go.yaml.in/yaml/v3"
+)
+
+// nodePosition is a node of the YAML document of a resource, with its position relative to
+// the beginning of the document
+type nodePosition struct {
+	line, column int        // position of the node, or of its key for mapping values, starting at 1
+	key          *yaml.Node // key of the node for mapping values, nil for sequence items and the root
+	value        *yaml.Node
+	flow         bool // whether the node is an entry of a flow mapping or sequence
+}
+
+// nodeIndex holds the nodes of a resource indexed by JSON pointer. It is shared by the
+// copies of a resource, and built the first time a position is needed.
+type nodeIndex struct {
+	once  sync.Once
+	nodes map[string]nodePosition
+}
+
+// node returns the node at path, a JSON pointer such as /spec/replicas as used in validation errors
+func (res *Resource) node(path string) (nodePosition, bool) {
+	if res.nodes == nil {
+		res.nodes = &nodeIndex{}
+	}
+	res.nodes.once.Do(func() {
+		res.nodes.nodes = locate(res.Bytes)
+	})
+	n, ok := res.nodes.nodes[path]
+	return n, ok
+}
+
+// locate returns the nodes of the document doc, indexed by JSON pointer. Keys are not escaped in
+// the pointers, like in the paths of validation errors. If a key is repeated, the first one is kept.
+func locate(doc []byte) map[string]nodePosition {
+	nodes := map[string]nodePosition{}
+	var root yaml.Node
+	if err := yaml.Unmarshal(doc, &root); err != nil || len(root.Content) == 0 {
+		return nodes
+	}
+
+	var walk func(path string, n nodePosition)
+	walk = func(path string, n nodePosition) {
+		if _, ok := nodes[path]; ok {
+			return
+		}
+		nodes[path] = n
+
+		flow := n.value.Style&yaml.FlowStyle != 0
+		switch n.value.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.value.Content); i += 2 {
+				key, value := n.value.Content[i], n.value.Content[i+1]
+				walk(path+"/"+key.Value, nodePosition{line: key.Line, column: key.Column, key: key, value: value, flow: flow})
+			}
+		case yaml.SequenceNode:
+			for i, item := range n.value.Content {
+				walk(path+"/"+strconv.Itoa(i), nodePosition{line: item.Line, column: item.Column, value: item, flow: flow})
+			}
+		}
+	}
+	walk("", nodePosition{line: root.Content[0].Line, column: root.Content[0].Column, value: root.Content[0]})
+
+	return nodes
+}
+
+// Position returns the line and column, in the file the resource was read from, of the
+// value at path, a JSON pointer such as /spec/replicas as used in validation errors, or
+// of its key for mapping values. If path can not be found in the resource, the position
+// of its closest parent is returned. Line and column are 0 if the position of the
+// resource in its file is unknown.
+func (res *Resource) Position(path string) (line, column int) {
+	if res.Line == 0 {
+		return 0, 0
+	}
+
+	for {
+		if n, ok := res.node(path); ok {
+			return res.Line + n.line - 1, n.column
+		}
+		if path == "" {
+			return 0, 0
+		}
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			path = path[:i]
+		} else {
+			path = ""
+		}
+	}
+}
+
+// ValuePosition returns the line, in the file the resource was read from, of the scalar value
+// at path, and the byte offsets of the start and end of the value in that line, quotes included.
+// Only values of block mappings and sequences written on a single line are supported: ok is false
+// for other values, such as block scalars, collections, entries of flow collections, or values
+// with anchors or tags, and if the position of the resource in its file is unknown.
+func (res *Resource) ValuePosition(path string) (line, start, end int, ok bool) {
+	n, found := res.node(path)
+	if res.Line == 0 || !found || n.flow {
+		return 0, 0, 0, false
+	}
+	return res.scalarPosition(n.value)
+}
+
+// KeyPosition returns the line, in the file the resource was read from, of the key of the
+// mapping entry at path, and the byte offsets of the start and end of the key in that line,
+// quotes included. ok is false for keys of flow mappings, and if the position of the
+// resource in its file is unknown.
+func (res *Resource) KeyPosition(path string) (line, start, end int, ok bool) {
+	n, found := res.node(path)
+	if res.Line == 0 || !found || n.flow || n.key == nil {
+		return 0, 0, 0, false
+	}
+	return res.scalarPosition(n.key)
+}
+
+// scalarPosition returns the line, in the file the resource was read from, of the scalar
+// node n, and the byte offsets of its start and end in that line, if n is a plain or quoted
+// scalar written on a single line, without an anchor or a tag
+func (res *Resource) scalarPosition(n *yaml.Node) (line, start, end int, ok bool) {
+	if n.Kind != yaml.ScalarNode || n.Anchor != "" || n.Style&(yaml.TaggedStyle|yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return 0, 0, 0, false
+	}
+
+	lines := strings.Split(string(res.Bytes), "\n")
+	if n.Line < 1 || n.Line > len(lines) {
+		return 0, 0, 0, false
+	}
+	text := strings.TrimRight(lines[n.Line-1], "\r")
+
+	// Columns are counted in characters, offsets in bytes
+	start = 0
+	for i := 1; i < n.Column && start < len(text); i++ {
+		_, size := utf8.DecodeRuneInString(text[start:])
+		start += size
+	}
+	if start >= len(text) {
+		return 0, 0, 0, false
+	}
+
+	if n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		e := closingQuote(text, start)
+		if e < 0 {
+			return 0, 0, 0, false
+		}
+		return res.Line + n.Line - 1, start, e + 1, true
+	}
+
+	// Plain scalars spanning multiple lines are folded, their value differs from the text of the line
+	value := text[start:]
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	if i := strings.Index(value, ": "); i >= 0 {
+		value = value[:i]
+	}
+	value = strings.TrimRight(strings.TrimSuffix(strings.TrimRight(value, " \t"), ":"), " \t")
+	if value != n.Value {
+		return 0, 0, 0, false
+	}
+	return res.Line + n.Line - 1, start, start + len(value), true
+}
+
+// closingQuote returns the index of the quote closing the string starting at index start of s
+func closingQuote(s string, start int) int {
+	q := s[start]
+	for j := start + 1; j < len(s); j++ {
+		switch {
+		case q == '"' && s[j] == '\\':
+			j++
+		case q == '\'' && s[j] == '\'' && j+1 < len(s) && s[j+1] == '\'':
+			j++
+		case s[j] == q:
+			return j
+		}
+	}
+	return -1
+}
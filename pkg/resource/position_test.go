@@ -0,0 +1,162 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPosition(t *testing.T) {
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-app
+  labels: &labels
+    app.kubernetes.io/name: my-app
+  annotations:
+    description: |
+      replicas: 3
+      a multi-line description
+spec:
+  replicas: "3"
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx
+        ports:
+          - containerPort: 80
+          - containerPort: "http"
+      - name: "sidecar"
+        args: ["--port", 8080]
+        env: {name: FOO, value: 42}
+      volumes:
+      -
+        name: data
+      - - nested
+        - sequence
+`
+
+	json := `{
+  "apiVersion": "v1",
+  "kind": "Service",
+  "spec": {
+    "ports": [
+      {"port": 80},
+      {
+        "port": "http"
+      }
+    ]
+  }
+}`
+
+	for _, testCase := range []struct {
+		name         string
+		doc          string
+		line         int
+		path         string
+		expectLine   int
+		expectColumn int
+	}{
+		{"root", deployment, 1, "", 1, 1},
+		{"top-level key", deployment, 1, "/spec", 11, 1},
+		{"nested key", deployment, 1, "/spec/replicas", 12, 3},
+		{"key containing a slash", deployment, 1, "/metadata/labels/app.kubernetes.io/name", 6, 5},
+		{"key after a block scalar", deployment, 1, "/spec/template", 13, 3},
+		{"block scalar content is not parsed", deployment, 1, "/metadata/annotations/description/replicas", 8, 5},
+		{"sequence item", deployment, 1, "/spec/template/spec/containers/1", 21, 9},
+		{"key in a sequence item", deployment, 1, "/spec/template/spec/containers/0/image", 17, 9},
+		{"indented sequence", deployment, 1, "/spec/template/spec/containers/0/ports/1/containerPort", 20, 13},
+		{"quoted key value", deployment, 1, "/spec/template/spec/containers/1/name", 21, 9},
+		{"flow sequence item", deployment, 1, "/spec/template/spec/containers/1/args/1", 22, 26},
+		{"flow mapping key", deployment, 1, "/spec/template/spec/containers/1/env/value", 23, 26},
+		{"sequence item on the next line", deployment, 1, "/spec/template/spec/volumes/0/name", 26, 9},
+		{"nested sequence", deployment, 1, "/spec/template/spec/volumes/1/1", 28, 11},
+		{"missing key, position of the parent", deployment, 1, "/spec/template/spec/containers/0/resources", 16, 9},
+		{"offset of the resource in the file", deployment, 10, "/spec/replicas", 21, 3},
+		{"unknown resource position", deployment, 0, "/spec/replicas", 0, 0},
+		{"JSON document", json, 1, "/spec/ports/1/port", 8, 9},
+		{"JSON sequence item", json, 1, "/spec/ports/0", 6, 7},
+		{"JSON root", json, 5, "", 5, 1},
+	} {
+		res := Resource{Bytes: []byte(testCase.doc), Line: testCase.line}
+		line, column := res.Position(testCase.path)
+		if line != testCase.expectLine || column != testCase.expectColumn {
+			t.Errorf("%s: expected %s at %d:%d, got %d:%d", testCase.name, testCase.path, testCase.expectLine, testCase.expectColumn, line, column)
+		}
+	}
+}
+
+func TestValueAndKeyPosition(t *testing.T) {
+	doc := `apiVersion: apps/v1 # comment
+kind: Deployment
+metadata:
+  name: "my-app"
+  labels: &labels
+    app: web
+spec:
+  replicas: '3'  # three
+  template:
+    spec:
+      containers:
+      - name: app
+        args:
+        - --port
+        - 8080
+        env: {name: FOO, value: 42}
+        "command": [sh]
+      description: |
+        text
+      ünïcode: wörld # comment
+`
+	lines := strings.Split(doc, "\n")
+
+	for _, testCase := range []struct {
+		name        string
+		path        string
+		key         bool
+		expectLine  int
+		expectText  string
+		expectFound bool
+	}{
+		{"value followed by a comment", "/apiVersion", false, 1, "apps/v1", true},
+		{"quoted value", "/metadata/name", false, 4, `"my-app"`, true},
+		{"single-quoted value followed by a comment", "/spec/replicas", false, 8, "'3'", true},
+		{"value in a sequence item", "/spec/template/spec/containers/0/name", false, 12, "app", true},
+		{"sequence item", "/spec/template/spec/containers/0/args/1", false, 15, "8080", true},
+		{"mapping", "/spec/template/spec/containers/0", false, 0, "", false},
+		{"value with an anchor", "/metadata/labels", false, 0, "", false},
+		{"flow mapping", "/spec/template/spec/containers/0/env", false, 0, "", false},
+		{"flow mapping value", "/spec/template/spec/containers/0/env/value", false, 0, "", false},
+		{"block scalar", "/spec/template/spec/description", false, 0, "", false},
+		{"missing value", "/spec/template/spec/volumes", false, 0, "", false},
+		{"key", "/spec/replicas", true, 8, "replicas", true},
+		{"key in a sequence item", "/spec/template/spec/containers/0/name", true, 12, "name", true},
+		{"quoted key", "/spec/template/spec/containers/0/command", true, 17, `"command"`, true},
+		{"sequence item is not a key", "/spec/template/spec/containers/0/args/0", true, 0, "", false},
+		{"key of a flow mapping", "/spec/template/spec/containers/0/env/name", true, 0, "", false},
+		{"value after multi-byte characters", "/spec/template/spec/ünïcode", false, 20, "wörld", true},
+		{"key with multi-byte characters", "/spec/template/spec/ünïcode", true, 20, "ünïcode", true},
+	} {
+		res := Resource{Bytes: []byte(doc), Line: 1}
+		position := res.ValuePosition
+		if testCase.key {
+			position = res.KeyPosition
+		}
+		line, start, end, ok := position(testCase.path)
+		if ok != testCase.expectFound {
+			t.Errorf("%s: expected found to be %t, got %t", testCase.name, testCase.expectFound, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if line != testCase.expectLine || lines[line-1][start:end] != testCase.expectText {
+			t.Errorf("%s: expected %s at line %d, got %s at line %d", testCase.name, testCase.expectText, testCase.expectLine, lines[line-1][start:end], line)
+		}
+	}
+
+	res := Resource{Bytes: []byte(doc)}
+	if _, _, _, ok := res.ValuePosition("/apiVersion"); ok {
+		t.Errorf("expected no position for a resource at an unknown position")
+	}
+}
@@ -0,0 +1,143 @@
+package resource
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+// RenderOpts configures how Helm charts and Kustomize folders found while
+// discovering resources are rendered
+type RenderOpts struct {
+	Enabled           bool     // render folders containing a Chart.yaml or a kustomization.yaml
+	HelmValuesFiles   []string // values files used when rendering Helm charts, the last one taking precedence
+	KustomizeOverlays []string // if set, only render Kustomize folders with one of these names
+}
+
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+func isHelmChart(dir string) bool {
+	fi, err := os.Stat(filepath.Join(dir, "Chart.yaml"))
+	return err == nil && !fi.IsDir()
+}
+
+func isKustomization(dir string) bool {
+	return kustomizationFile(dir) != ""
+}
+
+// kustomizationFile returns the path of the Kustomize configuration of the folder dir,
+// or an empty string if dir does not contain one
+func kustomizationFile(dir string) string {
+	for _, name := range kustomizationFiles {
+		p := filepath.Join(dir, name)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// isSelectedOverlay returns true if the Kustomize folder dir should be rendered
+func (o RenderOpts) isSelectedOverlay(dir string) bool {
+	if len(o.KustomizeOverlays) == 0 {
+		return true
+	}
+	for _, overlay := range o.KustomizeOverlays {
+		if filepath.Base(dir) == overlay {
+			return true
+		}
+	}
+	return false
+}
+
+// kustomizationRefs returns the local folders the Kustomize folder dir uses as
+// resources, bases or components. Remote resources and files are ignored.
+func kustomizationRefs(dir string) []string {
+	b, err := os.ReadFile(kustomizationFile(dir))
+	if err != nil {
+		return nil
+	}
+	var k struct {
+		Resources  []string `json:"resources"`
+		Bases      []string `json:"bases"`
+		Components []string `json:"components"`
+	}
+	if err := yaml.Unmarshal(b, &k); err != nil {
+		return nil
+	}
+
+	refs := []string{}
+	for _, ref := range append(append(k.Resources, k.Bases...), k.Components...) {
+		p := filepath.Join(dir, filepath.FromSlash(ref))
+		if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+			refs = append(refs, filepath.Clean(p))
+		}
+	}
+	return refs
+}
+
+// topLevelKustomizations returns the Kustomize folders of dirs that are not used by
+// another folder of dirs. Bases and components are rendered as part of the overlays
+// using them, rendering them on their own would validate their resources twice.
+func topLevelKustomizations(dirs []string) []string {
+	used := map[string]struct{}{}
+	for _, dir := range dirs {
+		for _, ref := range kustomizationRefs(dir) {
+			used[ref] = struct{}{}
+		}
+	}
+
+	topLevel := []string{}
+	for _, dir := range dirs {
+		if _, ok := used[filepath.Clean(dir)]; !ok {
+			topLevel = append(topLevel, dir)
+		}
+	}
+	return topLevel
+}
+
+// renderKustomization renders the Kustomize folder dir, like kustomize build. The
+// resources are given the path of the Kustomize configuration of dir.
+func renderKustomization(dir string) ([]Resource, error) {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	m, err := k.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed rendering Kustomize folder: %s", err)
+	}
+
+	out, err := m.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("failed rendering Kustomize folder: %s", err)
+	}
+
+	path := kustomizationFile(dir)
+	if path == "" {
+		path = dir
+	}
+
+	return splitRenderedOutput(out, path), nil
+}
+
+// splitRenderedOutput splits a multi-document YAML stream in resources, with the path p
+func splitRenderedOutput(out []byte, p string) []Resource {
+	resources := []Resource{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 256*1024*1024)
+	scanner.Split(SplitYAMLDocument)
+	for scanner.Scan() {
+		doc := bytes.TrimSpace(scanner.Bytes())
+		if len(doc) == 0 {
+			continue
+		}
+		res := Resource{Path: p, Bytes: append([]byte{}, doc...)}
+		resources = append(resources, res.Resources()...)
+	}
+
+	return resources
+}
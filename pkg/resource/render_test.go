@@ -0,0 +1,334 @@
+package resource
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// chartArchive returns a packaged chart, containing files in a folder named after the chart
+func chartArchive(t *testing.T, name string, files map[string]string) string {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for p, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name + "/" + p, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// describe returns the path of each resource, relative to root, with its kind and name
+func describe(root string, resources []Resource) []string {
+	got := []string{}
+	for _, res := range resources {
+		rel, _ := filepath.Rel(root, res.Path)
+		sig, _ := res.Signature()
+		got = append(got, strings.TrimSpace(filepath.ToSlash(rel)+": "+sig.Kind+" "+sig.Name))
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestRenderHelmChart(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		// The folder of the chart is not named after the chart
+		"web/Chart.yaml": `apiVersion: v2
+name: mychart
+version: 0.1.0
+appVersion: "1.2.3"
+dependencies:
+- name: cache
+  condition: cache.enabled
+- name: db
+  alias: database
+`,
+		"web/values.yaml":                   "replicas: 1\nservice:\n  enabled: true\n  port: 80\ncache:\n  enabled: false\nglobal:\n  team: web\n",
+		"web/values-production.yaml":        "replicas: 3\nservice:\n  port: null\ncache:\n  enabled: true\n",
+		"web/templates/_helpers.tpl":        "{{- define \"mychart.fullname\" -}}{{ .Release.Name }}-{{ .Chart.Name }}{{- end -}}\n",
+		"web/templates/NOTES.txt":           "Installed {{ .Chart.Name }}\n",
+		"web/templates/empty.yaml":          "{{- if .Values.notSet }}\nkind: Pod\n{{- end }}\n",
+		"web/templates/configmap.yaml":      "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ include \"mychart.fullname\" . }}\ndata:\n  port: {{ .Values.service.port | default 8080 | quote }}\n  version: {{ .Chart.AppVersion }}\n  motd: {{ .Files.Get \"files/motd\" | trim | quote }}\n",
+		"web/templates/deployment.yaml":     "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: {{ include \"mychart.fullname\" . }}\nspec:\n  replicas: {{ .Values.replicas }}\n",
+		"web/templates/service.yaml":        "{{- if .Values.service.enabled }}\napiVersion: v1\nkind: Service\nmetadata:\n  name: {{ tpl \"{{ .Release.Name }}-svc\" . }}\n{{- end }}\n",
+		"web/files/motd":                    "hello\n",
+		"web/crds/backup.yaml":              "apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: backups.example.com\n",
+		"web/charts/cache/Chart.yaml":       "apiVersion: v2\nname: cache\nversion: 0.1.0\n",
+		"web/charts/cache/templates/x.yaml": "apiVersion: v1\nkind: Service\nmetadata:\n  name: cache-{{ .Values.global.team }}\n",
+		"web/charts/db-0.1.0.tgz": chartArchive(t, "db", map[string]string{
+			"Chart.yaml":          "apiVersion: v2\nname: db\nversion: 0.1.0\n",
+			"values.yaml":         "port: 5432\n",
+			"templates/pods.yaml": "apiVersion: v1\nkind: Pod\nmetadata:\n  name: {{ .Chart.Name }}-{{ .Values.port }}\n---\napiVersion: v1\nkind: Pod\nmetadata:\n  name: second\n",
+		}),
+	})
+
+	for _, testCase := range []struct {
+		name        string
+		valuesFiles []string
+		expect      []string
+		expectData  map[string]interface{}
+	}{
+		{
+			"default values",
+			nil,
+			[]string{
+				"web/charts/db-0.1.0.tgz/templates/pods.yaml: Pod database-5432",
+				"web/charts/db-0.1.0.tgz/templates/pods.yaml: Pod second",
+				"web/crds/backup.yaml: CustomResourceDefinition backups.example.com",
+				"web/templates/configmap.yaml: ConfigMap release-name-mychart",
+				"web/templates/deployment.yaml: Deployment release-name-mychart",
+				"web/templates/service.yaml: Service release-name-svc",
+			},
+			map[string]interface{}{"port": "80", "version": "1.2.3", "motd": "hello"},
+		},
+		{
+			"values file",
+			[]string{"web/values-production.yaml"},
+			[]string{
+				"web/charts/cache/templates/x.yaml: Service cache-web",
+				"web/charts/db-0.1.0.tgz/templates/pods.yaml: Pod database-5432",
+				"web/charts/db-0.1.0.tgz/templates/pods.yaml: Pod second",
+				"web/crds/backup.yaml: CustomResourceDefinition backups.example.com",
+				"web/templates/configmap.yaml: ConfigMap release-name-mychart",
+				"web/templates/deployment.yaml: Deployment release-name-mychart",
+				"web/templates/service.yaml: Service release-name-svc",
+			},
+			map[string]interface{}{"port": "8080", "version": "1.2.3", "motd": "hello"},
+		},
+	} {
+		valuesFiles := []string{}
+		for _, f := range testCase.valuesFiles {
+			valuesFiles = append(valuesFiles, filepath.Join(root, f))
+		}
+
+		resources, err := renderHelmChart(filepath.Join(root, "web"), RenderOpts{Enabled: true, HelmValuesFiles: valuesFiles})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", testCase.name, err)
+		}
+
+		if got := describe(root, resources); !reflect.DeepEqual(got, testCase.expect) {
+			t.Errorf("%s: expected %+v, got %+v", testCase.name, testCase.expect, got)
+		}
+		for _, res := range resources {
+			obj, _ := res.Object()
+			if obj["kind"] == "ConfigMap" && !reflect.DeepEqual(obj["data"], testCase.expectData) {
+				t.Errorf("%s: expected ConfigMap data %+v, got %+v", testCase.name, testCase.expectData, obj["data"])
+			}
+			if obj["kind"] == "Deployment" && testCase.valuesFiles != nil && obj["spec"].(map[string]interface{})["replicas"] != float64(3) {
+				t.Errorf("%s: expected 3 replicas, got %+v", testCase.name, obj["spec"])
+			}
+		}
+	}
+}
+
+func TestRenderHelmChartErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		name   string
+		files  map[string]string
+		expect string
+	}{
+		{
+			"required value",
+			map[string]string{
+				"Chart.yaml":          "apiVersion: v2\nname: mychart\nversion: 0.1.0\n",
+				"templates/pod.yaml":  "image: {{ required \"image is required\" .Values.image }}\n",
+				"templates/pod2.yaml": "kind: Pod\n",
+			},
+			"image is required",
+		},
+		{
+			"template not parsing",
+			map[string]string{
+				"Chart.yaml":         "apiVersion: v2\nname: mychart\nversion: 0.1.0\n",
+				"templates/pod.yaml": "{{ if }}\n",
+			},
+			"failed parsing template",
+		},
+		{
+			"chart without a name",
+			map[string]string{
+				"Chart.yaml": "apiVersion: v2\nversion: 0.1.0\n",
+			},
+			"does not set the name of the chart",
+		},
+	} {
+		root := t.TempDir()
+		writeFiles(t, root, testCase.files)
+		_, err := renderHelmChart(root, RenderOpts{Enabled: true})
+		if err == nil || !strings.Contains(err.Error(), testCase.expect) {
+			t.Errorf("%s: expected an error containing %s, got %v", testCase.name, testCase.expect, err)
+		}
+	}
+}
+
+func TestRenderKustomization(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"base/kustomization.yaml":           "resources:\n- configmap.yaml\n",
+		"base/configmap.yaml":               "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n",
+		"production/kustomization.yaml":     "resources:\n- ../base\n- service.yaml\nnamePrefix: production-\nnamespace: prod\n",
+		"production/service.yaml":           "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+		"broken/kustomization.yaml":         "resources:\n- does-not-exist.yaml\n",
+		"production-alt/kustomization.yaml": "resources: [../production]\n",
+	})
+
+	resources, err := renderKustomization(filepath.Join(root, "production"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expect := []string{
+		"production/kustomization.yaml: ConfigMap production-config",
+		"production/kustomization.yaml: Service production-web",
+	}
+	if got := describe(root, resources); !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected %+v, got %+v", expect, got)
+	}
+	for _, res := range resources {
+		if sig, _ := res.Signature(); sig.Namespace != "prod" {
+			t.Errorf("expected %s to be in the namespace prod, got %s", sig.Name, sig.Namespace)
+		}
+	}
+
+	if _, err := renderKustomization(filepath.Join(root, "broken")); err == nil {
+		t.Errorf("expected an error rendering a Kustomize folder using a missing resource")
+	}
+
+	dirs := []string{filepath.Join(root, "base"), filepath.Join(root, "production"), filepath.Join(root, "production-alt")}
+	expectTopLevel := []string{filepath.Join(root, "production-alt")}
+	if got := topLevelKustomizations(dirs); !reflect.DeepEqual(got, expectTopLevel) {
+		t.Errorf("expected top-level Kustomize folders %+v, got %+v", expectTopLevel, got)
+	}
+}
+
+func TestFromFilesWithRender(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"plain.yaml":                                  "apiVersion: v1\nkind: Service\nmetadata:\n  name: plain\n",
+		"mychart/Chart.yaml":                          "apiVersion: v2\nname: mychart\nversion: 0.1.0\n",
+		"mychart/templates/deployment.yaml":           "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: {{ .Release.Name }}\n",
+		"app/base/kustomization.yaml":                 "resources: [configmap.yaml]\n",
+		"app/base/configmap.yaml":                     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: base\n",
+		"app/overlays/production/kustomization.yaml":  "resources: [../../base]\nnamePrefix: production-\n",
+		"app/overlays/development/kustomization.yaml": "resources: [../../base]\nnamePrefix: development-\n",
+	})
+
+	for _, testCase := range []struct {
+		name   string
+		opts   DiscoveryOpts
+		expect []string
+	}{
+		{
+			"rendering disabled",
+			DiscoveryOpts{},
+			[]string{
+				"app/base/configmap.yaml: ConfigMap base",
+				"app/base/kustomization.yaml:",
+				"app/overlays/development/kustomization.yaml:",
+				"app/overlays/production/kustomization.yaml:",
+				"mychart/Chart.yaml:",
+				"mychart/templates/deployment.yaml: Deployment",
+				"plain.yaml: Service plain",
+			},
+		},
+		{
+			"rendering all charts and Kustomize overlays",
+			DiscoveryOpts{Render: RenderOpts{Enabled: true}},
+			[]string{
+				"app/overlays/development/kustomization.yaml: ConfigMap development-base",
+				"app/overlays/production/kustomization.yaml: ConfigMap production-base",
+				"mychart/templates/deployment.yaml: Deployment release-name",
+				"plain.yaml: Service plain",
+			},
+		},
+		{
+			"rendering a single overlay",
+			DiscoveryOpts{Render: RenderOpts{Enabled: true, KustomizeOverlays: []string{"production"}}},
+			[]string{
+				"app/overlays/production/kustomization.yaml: ConfigMap production-base",
+				"mychart/templates/deployment.yaml: Deployment release-name",
+				"plain.yaml: Service plain",
+			},
+		},
+	} {
+		resources, errs := FromFilesWithOpts(context.Background(), []string{root}, testCase.opts)
+		all := []Resource{}
+		for resources != nil || errs != nil {
+			select {
+			case res, ok := <-resources:
+				if !ok {
+					resources = nil
+					continue
+				}
+				all = append(all, res)
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				t.Errorf("%s: unexpected error: %s", testCase.name, err)
+			}
+		}
+
+		if got := describe(root, all); !reflect.DeepEqual(got, testCase.expect) {
+			t.Errorf("%s: expected %+v, got %+v", testCase.name, testCase.expect, got)
+		}
+	}
+}
+
+func TestFromFilesWithRenderError(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"mychart/Chart.yaml":         "apiVersion: v2\nname: mychart\nversion: 0.1.0\n",
+		"mychart/templates/pod.yaml": "{{ fail \"not supported\" }}\n",
+	})
+
+	resources, errs := FromFilesWithOpts(context.Background(), []string{root}, DiscoveryOpts{
+		Render: RenderOpts{Enabled: true},
+	})
+	go func() {
+		for range resources {
+		}
+	}()
+
+	nErrs := 0
+	for err := range errs {
+		de, ok := err.(DiscoveryError)
+		if !ok || de.Path != filepath.Join(root, "mychart") {
+			t.Errorf("expected a DiscoveryError for the chart folder, got %+v", err)
+		}
+		nErrs++
+	}
+	if nErrs != 1 {
+		t.Errorf("expected 1 error, got %d", nErrs)
+	}
+}
@@ -5,14 +5,23 @@ import (
 	"strings"
 
 	"sigs.k8s.io/yaml"
+	yamlv2 "sigs.k8s.io/yaml/goyaml.v2"
 )
 
-// Resource represents a Kubernetes resource within a file
+// Resource represents a Kubernetes resource within a file. Resources are decoded once, when
+// first needed, and carry their decoded object and signature from then on: copies of a
+// decoded resource do not decode it again.
 type Resource struct {
-	Path   string
-	Bytes  []byte
-	sig    *Signature // Cache signature parsing
-	sigErr error      // Cache potential signature parsing error
+	Path      string
+	Bytes     []byte
+	Line      int                    // Line of the file the resource starts at, 0 if unknown
+	sig       *Signature             // Cache signature parsing
+	sigErr    error                  // Cache potential signature parsing error
+	decoded   bool                   // whether Bytes were decoded into obj
+	obj       map[string]interface{} // Bytes decoded, nil for empty resources
+	objErr    error                  // error decoding Bytes
+	strictErr error                  // error decoding Bytes in strict mode, such as a duplicated key
+	nodes     *nodeIndex             // positions of the nodes of Bytes, built when first needed
 }
 
 // Signature is a key representing a Kubernetes resource
@@ -32,12 +41,81 @@ func (sig *Signature) QualifiedName() string {
 	return fmt.Sprintf("%s/%s/%s/%s", sig.Version, sig.Kind, sig.Namespace, sig.Name)
 }
 
+// IsCRD returns true if the resource is a CustomResourceDefinition
+func (sig *Signature) IsCRD() bool {
+	return sig.Kind == "CustomResourceDefinition" && strings.HasPrefix(sig.Version, "apiextensions.k8s.io/")
+}
+
 // Signature computes a signature for a resource, based on its Kind, Version, Namespace & Name
 func (res *Resource) Signature() (*Signature, error) {
 	if res.sig != nil {
 		return res.sig, res.sigErr
 	}
 
+	obj, err := res.Object()
+	if err != nil { // Exit if there was an error unmarshalling
+		res.sig, res.sigErr = partialSignature(res.Bytes), err
+		return res.sig, res.sigErr
+	}
+
+	res.sig, res.sigErr = signature(obj)
+	return res.sig, res.sigErr
+}
+
+// Object returns the resource decoded, or nil if the resource is empty. The object is
+// shared by all the copies of the resource, and must not be modified.
+func (res *Resource) Object() (map[string]interface{}, error) {
+	res.decode()
+	return res.obj, res.objErr
+}
+
+// StrictObject returns the resource decoded, like Object, or an error if it would not
+// be decoded in strict mode, for example because it contains a duplicated key
+func (res *Resource) StrictObject() (map[string]interface{}, error) {
+	res.decode()
+	if res.objErr == nil && res.strictErr != nil {
+		return nil, res.strictErr
+	}
+	return res.obj, res.objErr
+}
+
+// decode decodes Bytes, unless the resource was already decoded. Resources are decoded in
+// strict mode first, and again in lax mode only if that fails, so that a single pass is
+// needed for valid resources whether the validation is strict or not.
+func (res *Resource) decode() {
+	if res.decoded {
+		return
+	}
+	res.decoded = true
+	if res.nodes == nil {
+		res.nodes = &nodeIndex{} // so that the copies of the resource share its positions
+	}
+
+	if err := yaml.UnmarshalStrict(res.Bytes, &res.obj); err != nil {
+		res.strictErr = err
+		res.obj = nil
+		res.objErr = yaml.Unmarshal(res.Bytes, &res.obj)
+	}
+}
+
+// field returns the value of the key name of m, matching the key case-insensitively if
+// there is no exact match, as keys were matched when decoding signatures into structs
+func field(m map[string]interface{}, name string) interface{} {
+	if v, ok := m[name]; ok {
+		return v
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// partialSignature returns the fields of the signature that can be read from a resource that
+// could not be decoded, for example because a value can not be converted to JSON, so that the
+// error can still be reported with the kind and name of the resource
+func partialSignature(b []byte) *Signature {
 	resource := struct {
 		APIVersion string `yaml:"apiVersion"`
 		Kind       string `yaml:"kind"`
@@ -45,34 +123,39 @@ func (res *Resource) Signature() (*Signature, error) {
 			Name         string `yaml:"name"`
 			Namespace    string `yaml:"namespace"`
 			GenerateName string `yaml:"generateName"`
-		} `yaml:"Metadata"`
+		} `yaml:"metadata"`
 	}{}
-	err := yaml.Unmarshal(res.Bytes, &resource)
+	// Fields decoded before an error are kept, and fields not in the struct are not converted
+	yamlv2.Unmarshal(b, &resource)
 
 	name := resource.Metadata.Name
 	if resource.Metadata.GenerateName != "" {
 		name = resource.Metadata.GenerateName + "{{ generateName }}"
 	}
+	return &Signature{Kind: resource.Kind, Version: resource.APIVersion, Namespace: resource.Metadata.Namespace, Name: name}
+}
 
-	// We cache the result to not unmarshall every time we want to access the signature
-	res.sig = &Signature{Kind: resource.Kind, Version: resource.APIVersion, Namespace: resource.Metadata.Namespace, Name: name}
-
-	if err != nil { // Exit if there was an error unmarshalling
-		res.sigErr = err
-		return res.sig, res.sigErr
+// signature returns the signature of the resource decoded into obj. The signature is
+// returned with the fields that could be read, even if an error is returned.
+func signature(obj map[string]interface{}) (*Signature, error) {
+	sig := &Signature{}
+	sig.Kind, _ = field(obj, "kind").(string)
+	sig.Version, _ = field(obj, "apiVersion").(string)
+	if metadata, ok := field(obj, "metadata").(map[string]interface{}); ok {
+		sig.Name, _ = field(metadata, "name").(string)
+		sig.Namespace, _ = field(metadata, "namespace").(string)
+		if generateName, ok := field(metadata, "generateName").(string); ok && generateName != "" {
+			sig.Name = generateName + "{{ generateName }}"
+		}
 	}
 
-	if res.sig.Kind == "" {
-		res.sigErr = fmt.Errorf("missing 'kind' key")
-		return res.sig, res.sigErr
+	if sig.Kind == "" {
+		return sig, fmt.Errorf("missing 'kind' key")
 	}
-
-	if res.sig.Version == "" {
-		res.sigErr = fmt.Errorf("missing 'apiVersion' key")
-		return res.sig, res.sigErr
+	if sig.Version == "" {
+		return sig, fmt.Errorf("missing 'apiVersion' key")
 	}
-
-	return res.sig, res.sigErr
+	return sig, nil
 }
 
 func (res *Resource) SignatureFromMap(m map[string]interface{}) (*Signature, error) {
@@ -112,18 +195,14 @@ func (res *Resource) SignatureFromMap(m map[string]interface{}) (*Signature, err
 func (res *Resource) Resources() []Resource {
 	resources := []Resource{}
 	if s, err := res.Signature(); err == nil && strings.ToLower(s.Kind) == "list" {
-		// A single file of type List
-		list := struct {
-			Version string
-			Kind    string
-			Items   []interface{}
-		}{}
-
-		yaml.Unmarshal(res.Bytes, &list)
-
-		for _, item := range list.Items {
+		// A single file of type List, its items are decoded already
+		items, _ := field(res.obj, "items").([]interface{})
+		for _, item := range items {
 			r := Resource{Path: res.Path}
 			r.Bytes, _ = yaml.Marshal(item)
+			if obj, ok := item.(map[string]interface{}); ok {
+				r.decoded, r.obj = true, obj
+			}
 			resources = append(resources, r)
 		}
 		return resources
@@ -1,6 +1,7 @@
 package resource_test
 
 import (
+	"errors"
 	"log"
 	"reflect"
 	"testing"
@@ -33,20 +34,51 @@ spec:
 				Kind:      "Deployment",
 				Version:   "apps/v1",
 				Namespace: "default",
+				Name:      "myService",
 			},
 			err: nil,
 		},
+		{
+			name: "resource that can not be converted to JSON",
+			have: []byte(`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+  namespace: default
+data:
+  token: !!binary not-base64
+`),
+			want: resource.Signature{
+				Kind:      "Secret",
+				Version:   "v1",
+				Namespace: "default",
+				Name:      "credentials",
+			},
+			err: errors.New("error converting YAML to JSON: yaml: !!binary value contains invalid base64 data"),
+		},
+		{
+			name: "invalid YAML",
+			have: []byte(`
+apiVersion: v1
+kind: ConfigMap
+data: {a: 1
+`),
+			want: resource.Signature{},
+			err:  errors.New("error converting YAML to JSON: yaml: line 4: did not find expected ',' or '}'"),
+		},
 	}
 
 	for _, testCase := range testCases {
 		res := resource.Resource{Bytes: testCase.have}
 		sig, err := res.Signature()
-		if err != nil && err.Error() != testCase.err.Error() {
-			t.Errorf("test \"%s\" - received error: %s", testCase.name, err)
+		if (err == nil) != (testCase.err == nil) || (err != nil && err.Error() != testCase.err.Error()) {
+			t.Errorf("test \"%s\" - received error: %v, expected %v", testCase.name, err, testCase.err)
 		}
 		if sig.Version != testCase.want.Version ||
 			sig.Kind != testCase.want.Kind ||
-			sig.Namespace != testCase.want.Namespace {
+			sig.Namespace != testCase.want.Namespace ||
+			sig.Name != testCase.want.Name {
 			t.Errorf("test \"%s\": received %+v, expected %+v", testCase.name, sig, testCase.want)
 		}
 	}
@@ -155,3 +187,44 @@ Items:
 		}
 	}
 }
+
+func TestObject(t *testing.T) {
+	for _, testCase := range []struct {
+		name            string
+		b               string
+		expectName      string
+		expectErr       bool
+		expectStrictErr bool
+	}{
+		{"resource", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n", "config", false, false},
+		{"duplicated key", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  name: other\n", "other", false, true},
+		{"keys in another case", "apiVersion: v1\nKind: ConfigMap\nMetadata:\n  Name: config\n", "config", false, false},
+		{"invalid YAML", "apiVersion: v1\nkind: [ConfigMap\n", "", true, true},
+	} {
+		res := resource.Resource{Bytes: []byte(testCase.b)}
+		if _, err := res.Object(); (err != nil) != testCase.expectErr {
+			t.Errorf("%s: expected error %t, got %v", testCase.name, testCase.expectErr, err)
+		}
+		if _, err := res.StrictObject(); (err != nil) != testCase.expectStrictErr {
+			t.Errorf("%s: expected strict error %t, got %v", testCase.name, testCase.expectStrictErr, err)
+		}
+		if sig, _ := res.Signature(); sig.Name != testCase.expectName {
+			t.Errorf("%s: expected name %s, got %s", testCase.name, testCase.expectName, sig.Name)
+		}
+	}
+}
+
+func TestResourcesOfListDecoded(t *testing.T) {
+	res := resource.Resource{Bytes: []byte("apiVersion: v1\nkind: List\nitems:\n- apiVersion: v1\n  kind: ConfigMap\n  metadata:\n    name: config\n")}
+	subres := res.Resources()
+	if len(subres) != 1 {
+		t.Fatalf("expected 1 resource, got %d", len(subres))
+	}
+	obj, err := subres[0].Object()
+	if err != nil || obj["kind"] != "ConfigMap" {
+		t.Errorf("expected the item to be decoded, got %+v: %v", obj, err)
+	}
+	if sig, err := subres[0].Signature(); err != nil || sig.Name != "config" {
+		t.Errorf("expected signature of the item, got %+v: %v", sig, err)
+	}
+}
@@ -41,6 +41,25 @@ func SplitYAMLDocument(data []byte, atEOF bool) (advance int, token []byte, err
 	return 0, nil, nil
 }
 
+// lineCounter splits a YAML stream in documents using SplitYAMLDocument, keeping
+// track of the line each document starts at
+type lineCounter struct {
+	line, next int
+}
+
+func newLineCounter() *lineCounter {
+	return &lineCounter{line: 1, next: 1}
+}
+
+func (lc *lineCounter) split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, token, err = SplitYAMLDocument(data, atEOF)
+	if token != nil {
+		lc.line = lc.next
+		lc.next += bytes.Count(data[:advance], []byte("\n"))
+	}
+	return advance, token, err
+}
+
 // FromStream reads resources from a byte stream, usually here stdin
 func FromStream(ctx context.Context, path string, r io.Reader) (<-chan Resource, <-chan error) {
 	resources := make(chan Resource)
@@ -53,7 +72,8 @@ func FromStream(ctx context.Context, path string, r io.Reader) (<-chan Resource,
 		scanner := bufio.NewScanner(r)
 		buf := make([]byte, initialBufSize)
 		scanner.Buffer(buf, maxBufSize) // Resize up to 256MB
-		scanner.Split(SplitYAMLDocument)
+		lines := newLineCounter()
+		scanner.Split(lines.split)
 
 	SCAN:
 		for scanner.Scan() {
@@ -62,7 +82,7 @@ func FromStream(ctx context.Context, path string, r io.Reader) (<-chan Resource,
 				break SCAN
 			default:
 			}
-			res := Resource{Path: path, Bytes: scanner.Bytes()}
+			res := Resource{Path: path, Bytes: scanner.Bytes(), Line: lines.line}
 			for _, subres := range res.Resources() {
 				resources <- subres
 			}
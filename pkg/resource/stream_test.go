@@ -39,6 +39,7 @@ kind: ReplicationController
 				Resources: []resource.Resource{
 					{
 						Path: "myfile",
+						Line: 1,
 						Bytes: []byte(`---
 apiVersion: v1
 kind: ReplicationController
@@ -60,10 +61,12 @@ apiVersion: v2
 				Resources: []resource.Resource{
 					{
 						Path:  "myfile",
+						Line:  1,
 						Bytes: []byte(`apiVersion: v1`),
 					},
 					{
 						Path: "myfile",
+						Line: 3,
 						Bytes: []byte(`apiVersion: v2
 `),
 					},
@@ -88,16 +91,19 @@ kind: CronJob
 				Resources: []resource.Resource{
 					{
 						Path: "myfile",
+						Line: 1,
 						Bytes: []byte(`apiVersion: v1
 kind: ReplicationController`),
 					},
 					{
 						Path: "myfile",
+						Line: 4,
 						Bytes: []byte(`apiVersion: v1
 kind: Deployment`),
 					},
 					{
 						Path: "myfile",
+						Line: 7,
 						Bytes: []byte(`apiVersion: v2
 kind: CronJob
 `),
@@ -120,11 +126,13 @@ kind: Deployment
 				Resources: []resource.Resource{
 					{
 						Path: "myfile",
+						Line: 1,
 						Bytes: []byte(`apiVersion: v1
 kind: ReplicationController`),
 					},
 					{
 						Path: "myfile",
+						Line: 4,
 						Bytes: []byte(`apiVersion: v1
 kind: Deployment
 `),
@@ -154,6 +162,9 @@ kind: Deployment
 				if !bytes.Equal(v.Bytes, testCase.Want.Resources[i].Bytes) {
 					t.Errorf("test %d - for resource %d, got '%s', expected '%s'", testi, i, string(res[i].Bytes), string(testCase.Want.Resources[i].Bytes))
 				}
+				if v.Line != testCase.Want.Resources[i].Line {
+					t.Errorf("test %d - for resource %d, got line %d, expected %d", testi, i, v.Line, testCase.Want.Resources[i].Line)
+				}
 			}
 
 			wg.Done()
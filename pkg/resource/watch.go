@@ -0,0 +1,203 @@
+package resource
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// watchDelay is how long Watch waits for further changes before sending a batch of
+// changed files, as editors often write a file in several steps
+var watchDelay = 100 * time.Millisecond
+
+// newWatcher creates the fsWatcher used by Watch
+var newWatcher = newFSWatcher
+
+// fsEvent is a change to a file or folder, reported by an fsWatcher. When overflow is
+// set, changes were lost and all watched paths need to be scanned again.
+type fsEvent struct {
+	path     string
+	isDir    bool
+	overflow bool
+}
+
+// fsWatcher is implemented using inotify on Linux, and by polling on other platforms
+type fsWatcher interface {
+	add(dir string) error // watch the files in the folder dir, not recursively
+	events() <-chan fsEvent
+	errors() <-chan error
+	close() error
+}
+
+// Watch watches the files and folders given in paths, and sends the YAML and JSON
+// files that are created or modified on the returned channel, in batches of sorted paths.
+// Files matching one of opts.IgnoreFilePatterns are not sent. Both channels are
+// closed once ctx is done.
+func Watch(ctx context.Context, paths []string, opts DiscoveryOpts) (<-chan []string, <-chan error) {
+	changes := make(chan []string)
+	errors := make(chan error)
+
+	go func() {
+		defer close(errors)
+		defer close(changes)
+
+		w, err := newWatcher()
+		if err != nil {
+			errors <- err
+			return
+		}
+		defer w.close()
+
+		files := map[string]bool{} // files given explicitly in paths
+		dirs := []string{}         // folders given in paths
+		for _, p := range paths {
+			p = filepath.Clean(p)
+			fi, err := os.Stat(p)
+			if err != nil {
+				errors <- DiscoveryError{p, err}
+				return
+			}
+			if !fi.IsDir() {
+				// Watching the parent folder also catches editors replacing the file
+				files[p] = true
+				if err := w.add(filepath.Dir(p)); err != nil {
+					errors <- DiscoveryError{p, err}
+					return
+				}
+				continue
+			}
+			dirs = append(dirs, p)
+			if _, err := addRecursively(w, p, opts); err != nil {
+				errors <- DiscoveryError{p, err}
+				return
+			}
+		}
+
+		isWatched := func(p string) bool {
+			if files[p] {
+				return true
+			}
+			for _, dir := range dirs {
+				if rel, err := filepath.Rel(dir, p); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+					return true
+				}
+			}
+			return false
+		}
+
+		pending := map[string]struct{}{}
+
+		// scan watches the folder dir and its sub-folders, and marks the files they
+		// contain as changed. It returns false once ctx is done.
+		scan := func(dir string) bool {
+			found, err := addRecursively(w, dir, opts)
+			if err != nil {
+				select {
+				case errors <- DiscoveryError{dir, err}:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			for _, p := range found {
+				pending[p] = struct{}{}
+			}
+			return true
+		}
+
+		timer := time.NewTimer(watchDelay)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case err := <-w.errors():
+				select {
+				case errors <- err:
+				case <-ctx.Done():
+					return
+				}
+
+			case ev := <-w.events():
+				switch {
+				case ev.overflow:
+					// Changes were lost, every watched file might have changed
+					for p := range files {
+						if isResourceFile(p, opts) {
+							pending[p] = struct{}{}
+						}
+					}
+					for _, dir := range dirs {
+						if !scan(dir) {
+							return
+						}
+					}
+				case !isWatched(ev.path):
+					continue
+				case ev.isDir:
+					// Files might have been written to the new folder before it was watched
+					if !scan(ev.path) {
+						return
+					}
+				case isResourceFile(ev.path, opts):
+					pending[ev.path] = struct{}{}
+				}
+				if len(pending) > 0 {
+					timer.Reset(watchDelay)
+				}
+
+			case <-timer.C:
+				batch := make([]string, 0, len(pending))
+				for p := range pending {
+					if _, err := os.Stat(p); err == nil {
+						batch = append(batch, p)
+					}
+				}
+				pending = map[string]struct{}{}
+				if len(batch) == 0 {
+					continue
+				}
+				sort.Strings(batch)
+				select {
+				case changes <- batch:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return changes, errors
+}
+
+// isResourceFile returns true if the file at p is a YAML or JSON file that is not ignored
+func isResourceFile(p string, opts DiscoveryOpts) bool {
+	fi, err := os.Stat(p)
+	if err != nil || (!isYAMLFile(fi) && !isJSONFile(fi)) {
+		return false
+	}
+	ignored, err := isIgnored(p, opts.IgnoreFilePatterns)
+	return err == nil && !ignored
+}
+
+// addRecursively watches the folder dir and its sub-folders, and returns the
+// YAML and JSON files they contain
+func addRecursively(w fsWatcher, dir string, opts DiscoveryOpts) ([]string, error) {
+	found := []string{}
+	err := filepath.Walk(dir, func(p string, i os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if i.IsDir() {
+			return w.add(p)
+		}
+		if isResourceFile(p, opts) {
+			found = append(found, p)
+		}
+		return nil
+	})
+	return found, err
+}
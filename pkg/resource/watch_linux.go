@@ -0,0 +1,129 @@
+//go:build linux
+
+package resource
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask selects the inotify events signalling that a file was written, or that a
+// file or folder was created or moved into a watched folder
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE
+
+type inotifyWatcher struct {
+	sync.Mutex
+	f       *os.File
+	fd      int
+	watches map[int32]string // watch descriptor -> folder
+	evs     chan fsEvent
+	errs    chan error
+	done    chan struct{}
+}
+
+func newFSWatcher() (fsWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed initialising inotify: %s", err)
+	}
+
+	w := &inotifyWatcher{
+		// The file descriptor is non-blocking, so reads go through the runtime poller
+		// and are interrupted when the file is closed
+		f:       os.NewFile(uintptr(fd), "inotify"),
+		fd:      fd,
+		watches: map[int32]string{},
+		evs:     make(chan fsEvent),
+		errs:    make(chan error),
+		done:    make(chan struct{}),
+	}
+	go w.read()
+
+	return w, nil
+}
+
+func (w *inotifyWatcher) add(dir string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+	if err != nil {
+		return fmt.Errorf("failed watching %s: %s", dir, err)
+	}
+	w.watches[int32(wd)] = dir
+	return nil
+}
+
+func (w *inotifyWatcher) events() <-chan fsEvent { return w.evs }
+
+func (w *inotifyWatcher) errors() <-chan error { return w.errs }
+
+func (w *inotifyWatcher) close() error {
+	close(w.done)
+	return w.f.Close()
+}
+
+func (w *inotifyWatcher) send(ev fsEvent) bool {
+	select {
+	case w.evs <- ev:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// read decodes the events read from the inotify file descriptor until the watcher is closed
+func (w *inotifyWatcher) read() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				select {
+				case w.errs <- fmt.Errorf("failed reading inotify events: %s", err):
+				case <-w.done:
+				}
+			}
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := string(bytes.TrimRight(buf[nameStart:nameStart+int(raw.Len)], "\x00"))
+			offset = nameStart + int(raw.Len)
+
+			if raw.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				// The kernel queue was full and events were dropped
+				if !w.send(fsEvent{overflow: true}) {
+					return
+				}
+				continue
+			}
+			if name == "" {
+				continue
+			}
+			isDir := raw.Mask&syscall.IN_ISDIR != 0
+			if raw.Mask&syscall.IN_CREATE != 0 && !isDir {
+				continue // the file will be reported once written
+			}
+
+			w.Lock()
+			dir, ok := w.watches[raw.Wd]
+			w.Unlock()
+			if !ok {
+				continue
+			}
+
+			if !w.send(fsEvent{path: filepath.Join(dir, name), isDir: isDir}) {
+				return
+			}
+		}
+	}
+}
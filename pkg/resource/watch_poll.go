@@ -0,0 +1,122 @@
+//go:build !linux
+
+package resource
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// pollInterval is how often folders are listed to find changes, on platforms without inotify
+var pollInterval = 500 * time.Millisecond
+
+type pollWatcher struct {
+	sync.Mutex
+	modTimes map[string]map[string]time.Time // folder -> file -> modification time
+	evs      chan fsEvent
+	errs     chan error
+	done     chan struct{}
+}
+
+func newFSWatcher() (fsWatcher, error) {
+	w := &pollWatcher{
+		modTimes: map[string]map[string]time.Time{},
+		evs:      make(chan fsEvent),
+		errs:     make(chan error),
+		done:     make(chan struct{}),
+	}
+	go w.poll()
+
+	return w, nil
+}
+
+func (w *pollWatcher) add(dir string) error {
+	modTimes, err := listModTimes(dir)
+	if err != nil {
+		return err
+	}
+
+	w.Lock()
+	defer w.Unlock()
+	w.modTimes[dir] = modTimes
+	return nil
+}
+
+func (w *pollWatcher) events() <-chan fsEvent { return w.evs }
+
+func (w *pollWatcher) errors() <-chan error { return w.errs }
+
+func (w *pollWatcher) close() error {
+	close(w.done)
+	return nil
+}
+
+func listModTimes(dir string) (map[string]time.Time, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	modTimes := map[string]time.Time{}
+	for _, entry := range entries {
+		if fi, err := entry.Info(); err == nil {
+			modTimes[entry.Name()] = fi.ModTime()
+		}
+	}
+	return modTimes, nil
+}
+
+// poll lists the watched folders every pollInterval, and reports the files and
+// folders that were created or modified since the previous listing
+func (w *pollWatcher) poll() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		w.Lock()
+		dirs := make([]string, 0, len(w.modTimes))
+		for dir := range w.modTimes {
+			dirs = append(dirs, dir)
+		}
+		w.Unlock()
+
+		for _, dir := range dirs {
+			modTimes, err := listModTimes(dir)
+			if err != nil {
+				continue // the folder was removed
+			}
+
+			w.Lock()
+			previous := w.modTimes[dir]
+			w.modTimes[dir] = modTimes
+			w.Unlock()
+
+			for name, modTime := range modTimes {
+				if t, ok := previous[name]; ok && t.Equal(modTime) {
+					continue
+				}
+				p := filepath.Join(dir, name)
+				fi, err := os.Stat(p)
+				if err != nil {
+					continue
+				}
+				if _, known := previous[name]; fi.IsDir() && known {
+					continue // only new folders are reported
+				}
+				select {
+				case w.evs <- fsEvent{path: p, isDir: fi.IsDir()}:
+				case <-w.done:
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package resource
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	single := t.TempDir()
+	for _, p := range []string{filepath.Join(dir, "existing.yaml"), filepath.Join(single, "watched.yaml"), filepath.Join(single, "other.yaml")} {
+		if err := os.WriteFile(p, []byte("kind: ConfigMap\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, errors := Watch(ctx, []string{dir, filepath.Join(single, "watched.yaml")}, DiscoveryOpts{IgnoreFilePatterns: []string{"ignored"}})
+
+	// Changes made before the watches are in place would be missed
+	time.Sleep(100 * time.Millisecond)
+
+	for _, testCase := range []struct {
+		name     string
+		change   func() error
+		expected []string
+	}{
+		{
+			name: "modified file",
+			change: func() error {
+				return os.WriteFile(filepath.Join(dir, "existing.yaml"), []byte("kind: Secret\n"), 0644)
+			},
+			expected: []string{filepath.Join(dir, "existing.yaml")},
+		},
+		{
+			name: "created files, only sending resource files that are not ignored",
+			change: func() error {
+				for _, name := range []string{"new.json", "ignored.yaml", "README.md", "new.yml"} {
+					if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			expected: []string{filepath.Join(dir, "new.json"), filepath.Join(dir, "new.yml")},
+		},
+		{
+			name: "new folder",
+			change: func() error {
+				if err := os.MkdirAll(filepath.Join(dir, "sub", "folder"), 0755); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(dir, "sub", "folder", "nested.yaml"), []byte("{}"), 0644)
+			},
+			expected: []string{filepath.Join(dir, "sub", "folder", "nested.yaml")},
+		},
+		{
+			name: "file given explicitly, ignoring its neighbours",
+			change: func() error {
+				if err := os.WriteFile(filepath.Join(single, "other.yaml"), []byte("{}"), 0644); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(single, "watched.yaml"), []byte("{}"), 0644)
+			},
+			expected: []string{filepath.Join(single, "watched.yaml")},
+		},
+	} {
+		if err := testCase.change(); err != nil {
+			t.Fatalf("test \"%s\": %s", testCase.name, err)
+		}
+
+		got := []string{}
+		timeout := time.After(5 * time.Second)
+		for !reflect.DeepEqual(got, testCase.expected) && len(got) < len(testCase.expected) {
+			select {
+			case batch := <-changes:
+				got = append(got, batch...)
+			case err := <-errors:
+				t.Fatalf("test \"%s\": unexpected error: %s", testCase.name, err)
+			case <-timeout:
+				t.Fatalf("test \"%s\": timed out waiting for %+v, got %+v", testCase.name, testCase.expected, got)
+			}
+		}
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf("test \"%s\": expected %+v, got %+v", testCase.name, testCase.expected, got)
+		}
+	}
+
+	cancel()
+	for range changes {
+	}
+}
+
+// fakeWatcher is an fsWatcher sending the events written to evs
+type fakeWatcher struct {
+	evs chan fsEvent
+}
+
+func (w *fakeWatcher) add(dir string) error   { return nil }
+func (w *fakeWatcher) events() <-chan fsEvent { return w.evs }
+func (w *fakeWatcher) errors() <-chan error   { return nil }
+func (w *fakeWatcher) close() error           { return nil }
+
+func TestWatchOverflow(t *testing.T) {
+	dir := t.TempDir()
+	single := t.TempDir()
+	for _, p := range []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "sub", "b.json"), filepath.Join(dir, "README.md"), filepath.Join(single, "watched.yaml"), filepath.Join(single, "other.yaml")} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("kind: ConfigMap\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := &fakeWatcher{evs: make(chan fsEvent)}
+	defer func(f func() (fsWatcher, error)) { newWatcher = f }(newWatcher)
+	newWatcher = func() (fsWatcher, error) { return w, nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, _ := Watch(ctx, []string{dir, filepath.Join(single, "watched.yaml")}, DiscoveryOpts{})
+
+	w.evs <- fsEvent{overflow: true}
+
+	expected := []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "sub", "b.json"), filepath.Join(single, "watched.yaml")}
+	select {
+	case got := <-changes:
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %+v, got %+v", expected, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("timed out waiting for %+v", expected)
+	}
+}
+
+func TestWatchMissingPath(t *testing.T) {
+	changes, errors := Watch(context.Background(), []string{filepath.Join(t.TempDir(), "missing.yaml")}, DiscoveryOpts{})
+	if err := <-errors; err == nil {
+		t.Errorf("expected an error for a missing path")
+	}
+	if _, ok := <-changes; ok {
+		t.Errorf("expected the changes channel to be closed")
+	}
+}
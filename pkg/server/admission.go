@@ -0,0 +1,119 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/yannh/kubeconform/pkg/resource"
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+// admissionReview is the subset of the admission.k8s.io/v1 AdmissionReview
+// used by validating admission webhooks
+type admissionReview struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Request    *admissionRequest  `json:"request,omitempty"`
+	Response   *admissionResponse `json:"response,omitempty"`
+}
+
+type admissionRequest struct {
+	UID       string          `json:"uid"`
+	Name      string          `json:"name,omitempty"`
+	Namespace string          `json:"namespace,omitempty"`
+	Operation string          `json:"operation"`
+	Object    json.RawMessage `json:"object,omitempty"`
+}
+
+type admissionResponse struct {
+	UID      string           `json:"uid"`
+	Allowed  bool             `json:"allowed"`
+	Status   *admissionStatus `json:"status,omitempty"`
+	Warnings []string         `json:"warnings,omitempty"` // shown to the user by kubectl, for resources with warnings
+}
+
+type admissionStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// handleAdmission validates the object of an AdmissionReview, and denies the
+// request if the object is invalid or could not be validated
+func (s *Server) handleAdmission(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodySize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed reading request body: %s", err), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	review := admissionReview{}
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(w, "expected an AdmissionReview with a request", http.StatusBadRequest)
+		return
+	}
+
+	k8sVersion, err := s.k8sVersion(r.URL.Query().Get("kubernetes-version"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(admissionReview{
+		APIVersion: "admission.k8s.io/v1",
+		Kind:       "AdmissionReview",
+		Response:   admit(s.validator.Session(k8sVersion), review.Request),
+	})
+}
+
+// admit validates the object of an admission request with the validator session v. Requests
+// without an object, such as deletions, are allowed. Warnings are returned as the warnings
+// of the response, whether the object is allowed or not.
+func admit(v validator.Validator, req *admissionRequest) *admissionResponse {
+	response := &admissionResponse{UID: req.UID, Allowed: true}
+	if len(req.Object) == 0 || string(req.Object) == "null" {
+		return response
+	}
+
+	path := req.Name
+	if req.Namespace != "" {
+		path = req.Namespace + "/" + req.Name
+	}
+	result := v.ValidateResource(resource.Resource{Path: path, Bytes: req.Object})
+
+	for _, ve := range result.ValidationErrors {
+		if ve.Severity == validator.SeverityWarning {
+			response.Warnings = append(response.Warnings, ve.Msg)
+		}
+	}
+
+	switch result.Status {
+	case validator.Invalid:
+		msgs := []string{}
+		for _, ve := range result.ValidationErrors {
+			if ve.Severity != validator.SeverityWarning {
+				msgs = append(msgs, fmt.Sprintf("%s: %s", ve.Path, ve.Msg))
+			}
+		}
+		if len(msgs) == 0 && result.Err != nil {
+			msgs = append(msgs, result.Err.Error())
+		}
+		response.Allowed = false
+		response.Status = &admissionStatus{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "resource is invalid: " + strings.Join(msgs, ", "),
+		}
+
+	case validator.Error:
+		response.Allowed = false
+		response.Status = &admissionStatus{
+			Code:    http.StatusUnprocessableEntity,
+			Message: fmt.Sprintf("resource failed validation: %s", result.Err),
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+func TestAdmission(t *testing.T) {
+	s := newTestServer(t, validator.Opts{})
+
+	for _, testCase := range []struct {
+		name           string
+		target         string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"valid object",
+			"/admission",
+			`{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview", "request": {"uid": "705ab4f5", "operation": "CREATE",
+			  "object": {"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "web"}, "spec": {"replicas": 2}}}}`,
+			http.StatusOK,
+			`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","response":{"uid":"705ab4f5","allowed":true}}` + "\n",
+		},
+		{
+			"invalid object",
+			"/admission",
+			`{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview", "request": {"uid": "705ab4f5", "operation": "UPDATE", "namespace": "team-a", "name": "web",
+			  "object": {"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "web"}, "spec": {"replicas": "two"}}}}`,
+			http.StatusOK,
+			`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","response":{"uid":"705ab4f5","allowed":false,"status":{"code":422,"message":"resource is invalid: /spec/replicas: got string, want integer"}}}` + "\n",
+		},
+		{
+			"invalid object with a warning",
+			"/admission?kubernetes-version=1.27.2",
+			`{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview", "request": {"uid": "705ab4f5", "operation": "CREATE",
+			  "object": {"apiVersion": "flowcontrol.apiserver.k8s.io/v1beta2", "kind": "FlowSchema", "metadata": {"name": "web"}, "spec": {"replicas": "two"}}}}`,
+			http.StatusOK,
+			`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","response":{"uid":"705ab4f5","allowed":false,"status":{"code":422,"message":"resource is invalid: /spec/replicas: got string, want integer"},"warnings":["flowcontrol.apiserver.k8s.io/v1beta2 FlowSchema is deprecated since Kubernetes 1.26 and removed in 1.29, use flowcontrol.apiserver.k8s.io/v1 instead"]}}` + "\n",
+		},
+		{
+			"object without schema",
+			"/admission",
+			`{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview", "request": {"uid": "705ab4f5", "operation": "CREATE",
+			  "object": {"apiVersion": "v1", "kind": "Service", "metadata": {"name": "web"}}}}`,
+			http.StatusOK,
+			`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","response":{"uid":"705ab4f5","allowed":false,"status":{"code":422,"message":"resource failed validation: could not find schema for Service"}}}` + "\n",
+		},
+		{
+			"deletion",
+			"/admission",
+			`{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview", "request": {"uid": "705ab4f5", "operation": "DELETE", "object": null}}`,
+			http.StatusOK,
+			`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","response":{"uid":"705ab4f5","allowed":true}}` + "\n",
+		},
+		{
+			"missing request",
+			"/admission",
+			`{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview"}`,
+			http.StatusBadRequest,
+			"expected an AdmissionReview with a request\n",
+		},
+	} {
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, httptest.NewRequest("POST", testCase.target, strings.NewReader(testCase.body)))
+		if w.Code != testCase.expectedStatus {
+			t.Errorf("test \"%s\": expected status %d, got %d", testCase.name, testCase.expectedStatus, w.Code)
+		}
+		if w.Body.String() != testCase.expectedBody {
+			t.Errorf("test \"%s\": expected body %s, got %s", testCase.name, testCase.expectedBody, w.Body.String())
+		}
+	}
+}
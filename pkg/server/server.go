@@ -0,0 +1,131 @@
+// Package server exposes kubeconform's validation over HTTP, both as a generic validation
+// endpoint and as a Kubernetes validating admission webhook.
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yannh/kubeconform/pkg/output"
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+// MaxBodySize is the maximum size of the request bodies accepted by the server
+const MaxBodySize = 32 * 1024 * 1024
+
+// Server validates the resources sent to its HTTP endpoints. All requests share the
+// schemas loaded by the same validator, but are validated independently from each other.
+type Server struct {
+	validator   validator.Validator
+	k8sVersions []string
+}
+
+// New creates a Server validating resources with v. Requests can set the kubernetes-version
+// query parameter to one of k8sVersions, and are validated against the first one otherwise.
+// Schemas are only loaded for these versions.
+func New(v validator.Validator, k8sVersions []string) *Server {
+	if len(k8sVersions) == 0 {
+		k8sVersions = []string{"master"}
+	}
+	return &Server{validator: v, k8sVersions: k8sVersions}
+}
+
+// Handler returns the HTTP handler of the server, serving:
+//   - POST /validate: validates the YAML or JSON resources in the body, and returns the results
+//     in kubeconform's JSON output format. The filename, summary and verbose query parameters
+//     have the same meaning as the equivalent command-line parameters.
+//   - POST /admission: implements the AdmissionReview v1 contract of validating admission webhooks
+//   - GET /healthz: returns 200 once the server is ready
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /validate", s.handleValidate)
+	mux.HandleFunc("POST /admission", s.handleAdmission)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// k8sVersion returns the Kubernetes version to validate against: the version requested,
+// if it is one of the versions of the server, or the first one if none was requested
+func (s *Server) k8sVersion(requested string) (string, error) {
+	if requested == "" {
+		return s.k8sVersions[0], nil
+	}
+	if slices.Contains(s.k8sVersions, requested) {
+		return requested, nil
+	}
+	return "", fmt.Errorf("kubernetes version %s is not served, valid values are: %s", requested, strings.Join(s.k8sVersions, ", "))
+}
+
+func boolParam(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %s for parameter %s", value, name)
+	}
+	return b, nil
+}
+
+// handleValidate validates the resources in the request body. It responds with
+// 200 if all resources are valid or skipped, and 422 otherwise.
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	k8sVersion, err := s.k8sVersion(r.URL.Query().Get("kubernetes-version"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	summary, err := boolParam(r, "summary")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	verbose, err := boolParam(r, "verbose")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		filename = "request"
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodySize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed reading request body: %s", err), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var buf bytes.Buffer
+	o, _ := output.New(&buf, "json", summary, false, verbose)
+
+	// Each request is validated in its own session, so that the CRDs it contains only
+	// apply to its resources. CRDs are validated before the other resources.
+	success := true
+	results := s.validator.Session(k8sVersion).ValidateWithContext(r.Context(), filename, io.NopCloser(bytes.NewReader(body)))
+	for _, result := range results {
+		if result.Status == validator.Invalid || result.Status == validator.Error {
+			success = false
+		}
+		o.Write(result)
+	}
+
+	if err := o.Flush(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !success {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	w.Write(buf.Bytes())
+}
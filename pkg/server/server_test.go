@@ -0,0 +1,203 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yannh/kubeconform/pkg/validator"
+)
+
+func newTestServer(t *testing.T, opts validator.Opts) *Server {
+	dir := t.TempDir()
+	schema := `{
+  "type": "object",
+  "properties": {
+    "spec": {"type": "object", "properties": {"replicas": {"type": "integer"}}}
+  }
+}`
+	for _, version := range []string{"master", "v1.27.2"} {
+		if err := os.MkdirAll(filepath.Join(dir, version), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"deployment-apps-v1.json", "flowschema-flowcontrol-v1beta2.json"} {
+			if err := os.WriteFile(filepath.Join(dir, version, name), []byte(schema), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	v, err := validator.New([]string{filepath.Join(dir, "{{ .NormalizedKubernetesVersion }}", "{{ .ResourceKind }}{{ .KindSuffix }}.json")}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(v, []string{"master", "1.27.2"})
+}
+
+func TestValidate(t *testing.T) {
+	s := newTestServer(t, validator.Opts{})
+
+	for _, testCase := range []struct {
+		name           string
+		method, target string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"valid resources, with a summary",
+			"POST", "/validate?summary=true&filename=deployments.yaml",
+			"apiVersion: apps/v1\nkind: Deployment\n---\n{\"apiVersion\": \"apps/v1\", \"kind\": \"Deployment\"}\n",
+			http.StatusOK,
+			`{
+  "resources": [],
+  "summary": {
+    "valid": 2,
+    "invalid": 0,
+    "errors": 0,
+    "skipped": 0
+  }
+}
+`,
+		},
+		{
+			"invalid resource",
+			"POST", "/validate?kubernetes-version=1.27.2",
+			"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: two\n",
+			http.StatusUnprocessableEntity,
+			`/v1.27.2/deployment-apps-v1.json#' - at '/spec/replicas': got string, want integer",
+      "validationErrors": [
+        {
+          "path": "/spec/replicas",
+          "msg": "got string, want integer",
+          "keyword": "type",
+          "line": 6,
+          "column": 3
+        }
+      ]`,
+		},
+		{
+			"resource without schema",
+			"POST", "/validate?verbose=1",
+			"apiVersion: v1\nkind: Service\n",
+			http.StatusUnprocessableEntity,
+			`"msg": "could not find schema for Service"`,
+		},
+		{
+			"Kubernetes version not served",
+			"POST", "/validate?kubernetes-version=1.28.0",
+			"",
+			http.StatusBadRequest,
+			"kubernetes version 1.28.0 is not served, valid values are: master, 1.27.2\n",
+		},
+		{
+			"invalid summary parameter",
+			"POST", "/validate?summary=maybe",
+			"",
+			http.StatusBadRequest,
+			"invalid value maybe for parameter summary\n",
+		},
+		{
+			"unsupported method",
+			"GET", "/validate",
+			"",
+			http.StatusMethodNotAllowed,
+			"",
+		},
+		{
+			"health check",
+			"GET", "/healthz",
+			"",
+			http.StatusOK,
+			"ok\n",
+		},
+	} {
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, httptest.NewRequest(testCase.method, testCase.target, strings.NewReader(testCase.body)))
+		if w.Code != testCase.expectedStatus {
+			t.Errorf("test \"%s\": expected status %d, got %d", testCase.name, testCase.expectedStatus, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), testCase.expectedBody) {
+			t.Errorf("test \"%s\": expected body to contain %s, got %s", testCase.name, testCase.expectedBody, w.Body.String())
+		}
+	}
+}
+
+func TestValidateCRDsFromInput(t *testing.T) {
+	s := newTestServer(t, validator.Opts{CRDsFromInput: true, IgnoreMissingSchemas: true})
+
+	crd := `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: crontabs.stable.example.com
+spec:
+  group: stable.example.com
+  names:
+    kind: CronTab
+  versions:
+  - name: v1
+    served: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              replicas:
+                type: integer
+`
+	cr := "apiVersion: stable.example.com/v1\nkind: CronTab\nmetadata:\n  name: cron\nspec:\n  replicas: one\n"
+
+	for _, testCase := range []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"custom resource before its CRD",
+			cr + "---\n" + crd,
+			http.StatusUnprocessableEntity,
+			`"msg": "got string, want integer"`,
+		},
+		{
+			"custom resource whose CRD was sent in another request",
+			cr,
+			http.StatusOK,
+			`"warnings": 1`,
+		},
+	} {
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, httptest.NewRequest("POST", "/validate?summary=true", strings.NewReader(testCase.body)))
+		if w.Code != testCase.expectedStatus {
+			t.Errorf("test \"%s\": expected status %d, got %d", testCase.name, testCase.expectedStatus, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), testCase.expectedBody) {
+			t.Errorf("test \"%s\": expected body to contain %s, got %s", testCase.name, testCase.expectedBody, w.Body.String())
+		}
+	}
+}
+
+func TestK8sVersion(t *testing.T) {
+	for _, testCase := range []struct {
+		name        string
+		k8sVersions []string
+		requested   string
+		expected    string
+		expectErr   bool
+	}{
+		{"default version", []string{"1.30.0", "1.29.0"}, "", "1.30.0", false},
+		{"requested version", []string{"1.30.0", "1.29.0"}, "1.29.0", "1.29.0", false},
+		{"version not served", []string{"1.30.0", "1.29.0"}, "1.28.0", "", true},
+		{"master when no version is set", nil, "", "master", false},
+	} {
+		got, err := New(nil, testCase.k8sVersions).k8sVersion(testCase.requested)
+		if got != testCase.expected || (err != nil) != testCase.expectErr {
+			t.Errorf("test \"%s\": expected %s, error %t, got %s, %v", testCase.name, testCase.expected, testCase.expectErr, got, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package validator
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/yannh/kubeconform/pkg/resource"
+)
+
+// DeprecatedAPIVersionRule is the ID of the rule reporting resources using a deprecated apiVersion
+const DeprecatedAPIVersionRule = "deprecated-api-version"
+
+// apiDeprecation describes the deprecation of a Kind in a version of the Kubernetes API.
+// Versions are the minor versions of Kubernetes 1.x.
+type apiDeprecation struct {
+	deprecatedIn int
+	removedIn    int
+	replacement  string // apiVersion to use instead, empty if the Kind was removed without replacement
+}
+
+// apiDeprecations lists the Kinds of the Kubernetes API that were deprecated, indexed by apiVersion
+// https://kubernetes.io/docs/reference/using-api/deprecation-guide/
+var apiDeprecations = map[string]map[string]apiDeprecation{
+	"admissionregistration.k8s.io/v1beta1": {
+		"MutatingWebhookConfiguration":   {16, 22, "admissionregistration.k8s.io/v1"},
+		"ValidatingWebhookConfiguration": {16, 22, "admissionregistration.k8s.io/v1"},
+	},
+	"apiextensions.k8s.io/v1beta1":   {"CustomResourceDefinition": {16, 22, "apiextensions.k8s.io/v1"}},
+	"apiregistration.k8s.io/v1beta1": {"APIService": {19, 22, "apiregistration.k8s.io/v1"}},
+	"apps/v1beta1": {
+		"Deployment":  {9, 16, "apps/v1"},
+		"StatefulSet": {9, 16, "apps/v1"},
+	},
+	"apps/v1beta2": {
+		"DaemonSet":   {9, 16, "apps/v1"},
+		"Deployment":  {9, 16, "apps/v1"},
+		"ReplicaSet":  {9, 16, "apps/v1"},
+		"StatefulSet": {9, 16, "apps/v1"},
+	},
+	"authentication.k8s.io/v1beta1": {"TokenReview": {19, 22, "authentication.k8s.io/v1"}},
+	"authorization.k8s.io/v1beta1": {
+		"LocalSubjectAccessReview": {19, 22, "authorization.k8s.io/v1"},
+		"SelfSubjectAccessReview":  {19, 22, "authorization.k8s.io/v1"},
+		"SubjectAccessReview":      {19, 22, "authorization.k8s.io/v1"},
+	},
+	"autoscaling/v2beta1":         {"HorizontalPodAutoscaler": {22, 25, "autoscaling/v2"}},
+	"autoscaling/v2beta2":         {"HorizontalPodAutoscaler": {23, 26, "autoscaling/v2"}},
+	"batch/v1beta1":               {"CronJob": {21, 25, "batch/v1"}},
+	"certificates.k8s.io/v1beta1": {"CertificateSigningRequest": {19, 22, "certificates.k8s.io/v1"}},
+	"coordination.k8s.io/v1beta1": {"Lease": {19, 22, "coordination.k8s.io/v1"}},
+	"discovery.k8s.io/v1beta1":    {"EndpointSlice": {21, 25, "discovery.k8s.io/v1"}},
+	"events.k8s.io/v1beta1":       {"Event": {19, 25, "events.k8s.io/v1"}},
+	"extensions/v1beta1": {
+		"DaemonSet":         {9, 16, "apps/v1"},
+		"Deployment":        {9, 16, "apps/v1"},
+		"Ingress":           {14, 22, "networking.k8s.io/v1"},
+		"NetworkPolicy":     {9, 16, "networking.k8s.io/v1"},
+		"PodSecurityPolicy": {11, 16, "policy/v1beta1"},
+		"ReplicaSet":        {9, 16, "apps/v1"},
+	},
+	"flowcontrol.apiserver.k8s.io/v1beta1": {
+		"FlowSchema":                 {23, 26, "flowcontrol.apiserver.k8s.io/v1"},
+		"PriorityLevelConfiguration": {23, 26, "flowcontrol.apiserver.k8s.io/v1"},
+	},
+	"flowcontrol.apiserver.k8s.io/v1beta2": {
+		"FlowSchema":                 {26, 29, "flowcontrol.apiserver.k8s.io/v1"},
+		"PriorityLevelConfiguration": {26, 29, "flowcontrol.apiserver.k8s.io/v1"},
+	},
+	"flowcontrol.apiserver.k8s.io/v1beta3": {
+		"FlowSchema":                 {29, 32, "flowcontrol.apiserver.k8s.io/v1"},
+		"PriorityLevelConfiguration": {29, 32, "flowcontrol.apiserver.k8s.io/v1"},
+	},
+	"networking.k8s.io/v1beta1": {
+		"Ingress":      {19, 22, "networking.k8s.io/v1"},
+		"IngressClass": {19, 22, "networking.k8s.io/v1"},
+	},
+	"node.k8s.io/v1beta1": {"RuntimeClass": {20, 25, "node.k8s.io/v1"}},
+	"policy/v1beta1": {
+		"PodDisruptionBudget": {21, 25, "policy/v1"},
+		"PodSecurityPolicy":   {21, 25, ""},
+	},
+	"rbac.authorization.k8s.io/v1beta1": {
+		"ClusterRole":        {17, 22, "rbac.authorization.k8s.io/v1"},
+		"ClusterRoleBinding": {17, 22, "rbac.authorization.k8s.io/v1"},
+		"Role":               {17, 22, "rbac.authorization.k8s.io/v1"},
+		"RoleBinding":        {17, 22, "rbac.authorization.k8s.io/v1"},
+	},
+	"scheduling.k8s.io/v1beta1": {"PriorityClass": {14, 22, "scheduling.k8s.io/v1"}},
+	"storage.k8s.io/v1beta1": {
+		"CSIDriver":          {19, 22, "storage.k8s.io/v1"},
+		"CSINode":            {17, 22, "storage.k8s.io/v1"},
+		"CSIStorageCapacity": {24, 27, "storage.k8s.io/v1"},
+		"StorageClass":       {19, 22, "storage.k8s.io/v1"},
+		"VolumeAttachment":   {19, 22, "storage.k8s.io/v1"},
+	},
+}
+
+// ReplacementAPIVersion returns the apiVersion to use instead of apiVersion for resources of
+// the Kind kind, or an empty string if apiVersion is not deprecated or has no replacement
+func ReplacementAPIVersion(apiVersion, kind string) string {
+	return apiDeprecations[apiVersion][kind].replacement
+}
+
+var kubernetesVersionRegexp = regexp.MustCompile(`^1\.(\d+)\.\d+$`)
+
+// kubernetesMinorVersion returns the minor version of the Kubernetes version k8sVersion, master
+// being more recent than any release. ok is false for versions that are not Kubernetes 1.x
+// versions, such as OpenShift versions.
+func kubernetesMinorVersion(k8sVersion string) (minor int, ok bool) {
+	if k8sVersion == "master" {
+		return math.MaxInt, true
+	}
+	m := kubernetesVersionRegexp.FindStringSubmatch(k8sVersion)
+	if m == nil {
+		return 0, false
+	}
+	minor, err := strconv.Atoi(m[1])
+	return minor, err == nil
+}
+
+// apiDeprecationMessage returns a message describing the deprecation of the apiVersion of the
+// resource sig in the Kubernetes version k8sVersion, or an empty string if the apiVersion is not
+// deprecated in that version. removed is true if the apiVersion is no longer served.
+func apiDeprecationMessage(sig resource.Signature, k8sVersion string) (msg string, removed bool) {
+	d, ok := apiDeprecations[sig.Version][sig.Kind]
+	if !ok {
+		return "", false
+	}
+	minor, ok := kubernetesMinorVersion(k8sVersion)
+	if !ok || minor < d.deprecatedIn {
+		return "", false
+	}
+
+	instead := fmt.Sprintf(", use %s instead", d.replacement)
+	if d.replacement == "" {
+		instead = " without replacement"
+	}
+	if minor >= d.removedIn {
+		return fmt.Sprintf("%s %s was removed in Kubernetes 1.%d%s", sig.Version, sig.Kind, d.removedIn, instead), true
+	}
+	return fmt.Sprintf("%s %s is deprecated since Kubernetes 1.%d and removed in 1.%d%s", sig.Version, sig.Kind, d.deprecatedIn, d.removedIn, instead), false
+}
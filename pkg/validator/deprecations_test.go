@@ -0,0 +1,146 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/yannh/kubeconform/pkg/loader"
+	"github.com/yannh/kubeconform/pkg/registry"
+	"github.com/yannh/kubeconform/pkg/resource"
+)
+
+func TestAPIDeprecationMessage(t *testing.T) {
+	for _, testCase := range []struct {
+		apiVersion, kind, k8sVersion string
+		expect                       string
+		expectRemoved                bool
+	}{
+		{"networking.k8s.io/v1", "Ingress", "master", "", false},
+		{"extensions/v1beta1", "Ingress", "1.13.0", "", false},
+		{"extensions/v1beta1", "Ingress", "1.14.0", "extensions/v1beta1 Ingress is deprecated since Kubernetes 1.14 and removed in 1.22, use networking.k8s.io/v1 instead", false},
+		{"extensions/v1beta1", "Ingress", "1.22.0", "extensions/v1beta1 Ingress was removed in Kubernetes 1.22, use networking.k8s.io/v1 instead", true},
+		{"extensions/v1beta1", "Ingress", "master", "extensions/v1beta1 Ingress was removed in Kubernetes 1.22, use networking.k8s.io/v1 instead", true},
+		{"policy/v1beta1", "PodSecurityPolicy", "1.25.3", "policy/v1beta1 PodSecurityPolicy was removed in Kubernetes 1.25 without replacement", true},
+		{"extensions/v1beta1", "Ingress", "3.11.0", "", false},
+	} {
+		got, removed := apiDeprecationMessage(resource.Signature{Kind: testCase.kind, Version: testCase.apiVersion}, testCase.k8sVersion)
+		if got != testCase.expect || removed != testCase.expectRemoved {
+			t.Errorf("%s %s on %s: expected \"%s\" (removed: %t), got \"%s\" (removed: %t)", testCase.apiVersion, testCase.kind, testCase.k8sVersion, testCase.expect, testCase.expectRemoved, got, removed)
+		}
+	}
+}
+
+func TestReplacementAPIVersion(t *testing.T) {
+	for _, testCase := range []struct {
+		apiVersion, kind string
+		expect           string
+	}{
+		{"extensions/v1beta1", "Ingress", "networking.k8s.io/v1"},
+		{"policy/v1beta1", "PodSecurityPolicy", ""},
+		{"apps/v1", "Deployment", ""},
+	} {
+		if got := ReplacementAPIVersion(testCase.apiVersion, testCase.kind); got != testCase.expect {
+			t.Errorf("%s %s: expected \"%s\", got \"%s\"", testCase.apiVersion, testCase.kind, testCase.expect, got)
+		}
+	}
+}
+
+func TestValidateDeprecatedAPIVersions(t *testing.T) {
+	ingress := "apiVersion: extensions/v1beta1\nkind: Ingress\nmetadata:\n  name: web\n"
+	deprecated := ValidationError{
+		Path:     "/apiVersion",
+		Msg:      "extensions/v1beta1 Ingress is deprecated since Kubernetes 1.14 and removed in 1.22, use networking.k8s.io/v1 instead",
+		Line:     1,
+		Column:   1,
+		Rule:     DeprecatedAPIVersionRule,
+		Severity: SeverityWarning,
+	}
+	removed := deprecated
+	removed.Msg = "extensions/v1beta1 Ingress was removed in Kubernetes 1.22, use networking.k8s.io/v1 instead"
+
+	for _, testCase := range []struct {
+		name                 string
+		k8sVersion           string
+		schemaFound          bool
+		ignoreMissingSchemas bool
+		expectStatus         Status
+		expectErr            string
+		expectErrors         []ValidationError
+	}{
+		{
+			"deprecated apiVersion",
+			"1.19.0",
+			true,
+			false,
+			Warning,
+			"rule deprecated-api-version: " + deprecated.Msg,
+			[]ValidationError{deprecated},
+		},
+		{
+			"removed apiVersion, with a schema",
+			"1.22.0",
+			true,
+			false,
+			Warning,
+			"rule deprecated-api-version: " + removed.Msg,
+			[]ValidationError{removed},
+		},
+		{
+			"removed apiVersion, without schema",
+			"1.22.0",
+			false,
+			false,
+			Error,
+			"could not find schema for Ingress: " + removed.Msg,
+			[]ValidationError{removed},
+		},
+		{
+			"removed apiVersion, ignoring missing schemas",
+			"1.22.0",
+			false,
+			true,
+			Warning,
+			"rule deprecated-api-version: " + removed.Msg + ", rule missing-schema: could not find schema for Ingress",
+			[]ValidationError{removed, {Msg: "could not find schema for Ingress", Line: 1, Column: 1, Rule: missingSchemaRule, Severity: SeverityWarning}},
+		},
+		{
+			"apiVersion not deprecated yet",
+			"1.13.0",
+			true,
+			false,
+			Valid,
+			"",
+			nil,
+		},
+	} {
+		schemaFound := testCase.schemaFound
+		val := v{
+			opts: Opts{
+				SkipKinds:            map[string]struct{}{},
+				RejectKinds:          map[string]struct{}{},
+				IgnoreMissingSchemas: testCase.ignoreMissingSchemas,
+			},
+			schemaDownload: downloadSchema,
+			regs: []registry.Registry{
+				newMockRegistry(func() (string, any, error) {
+					if !schemaFound {
+						return "", nil, loader.NewNotFoundError(fmt.Errorf("not found"))
+					}
+					return "", map[string]any{"type": "object"}, nil
+				}),
+			},
+		}
+
+		got := val.ValidateResourceForVersion(resource.Resource{Bytes: []byte(ingress), Line: 1}, testCase.k8sVersion)
+		if got.Status != testCase.expectStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", testCase.name, testCase.expectStatus, got.Status, got.Err)
+		}
+		if (got.Err == nil && testCase.expectErr != "") || (got.Err != nil && got.Err.Error() != testCase.expectErr) {
+			t.Errorf("%s: expected error \"%s\", got %v", testCase.name, testCase.expectErr, got.Err)
+		}
+		if !reflect.DeepEqual(got.ValidationErrors, testCase.expectErrors) {
+			t.Errorf("%s: expected %+v, got %+v", testCase.name, testCase.expectErrors, got.ValidationErrors)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/yannh/kubeconform/pkg/resource"
+)
+
+// DuplicateResourceRule is the rule of the validation errors reporting duplicate resources
+const DuplicateResourceRule = "duplicate-resource"
+
+// DuplicateResourceError is returned for a resource that has the same apiVersion, kind,
+// namespace and name as another resource. Resources are validated concurrently, so the
+// error is returned for the resource validated last, and references the other one.
+type DuplicateResourceError struct {
+	Signature resource.Signature // Signature of the resource, with its namespace normalised
+	OtherPath string             // Path of the file the other resource was found in
+	OtherLine int                // Line the other resource starts at, 0 if unknown
+}
+
+func (e *DuplicateResourceError) Error() string {
+	if e.OtherLine == 0 {
+		return fmt.Sprintf("duplicate resource %s, also defined in %s", e.Signature.QualifiedName(), e.OtherPath)
+	}
+	return fmt.Sprintf("duplicate resource %s, also defined in %s:%d", e.Signature.QualifiedName(), e.OtherPath, e.OtherLine)
+}
+
+// withDuplicateError returns the result of the validation of a duplicate resource, with
+// the Error status. Its validation errors are kept, and the duplicate is reported as one
+// of them so it is located on the name of the resource.
+func withDuplicateError(result Result, err *DuplicateResourceError) Result {
+	line, column := result.Resource.Position("/metadata/name")
+	result.ValidationErrors = append(result.ValidationErrors, ValidationError{
+		Path:   "/metadata/name",
+		Msg:    err.Error(),
+		Line:   line,
+		Column: column,
+		Rule:   DuplicateResourceRule,
+	})
+	if result.Err != nil && result.Status != Skipped {
+		result.Err = fmt.Errorf("%w, %s", err, result.Err)
+	} else {
+		result.Err = err
+	}
+	result.Status = Error
+	return result
+}
+
+// clusterScopedKinds lists the Kinds of the Kubernetes API that are not namespaced, indexed
+// by API group. For those, the namespace of a resource is ignored when looking for duplicates.
+var clusterScopedKinds = map[string]map[string]struct{}{
+	"": {
+		"ComponentStatus":  {},
+		"Namespace":        {},
+		"Node":             {},
+		"PersistentVolume": {},
+	},
+	"admissionregistration.k8s.io": {
+		"MutatingWebhookConfiguration":     {},
+		"ValidatingAdmissionPolicy":        {},
+		"ValidatingAdmissionPolicyBinding": {},
+		"ValidatingWebhookConfiguration":   {},
+	},
+	"apiextensions.k8s.io":         {"CustomResourceDefinition": {}},
+	"apiregistration.k8s.io":       {"APIService": {}},
+	"certificates.k8s.io":          {"CertificateSigningRequest": {}, "ClusterTrustBundle": {}},
+	"flowcontrol.apiserver.k8s.io": {"FlowSchema": {}, "PriorityLevelConfiguration": {}},
+	"networking.k8s.io":            {"IngressClass": {}, "IPAddress": {}, "ServiceCIDR": {}},
+	"node.k8s.io":                  {"RuntimeClass": {}},
+	"policy":                       {"PodSecurityPolicy": {}},
+	"rbac.authorization.k8s.io":    {"ClusterRole": {}, "ClusterRoleBinding": {}},
+	"resource.k8s.io":              {"DeviceClass": {}, "ResourceSlice": {}},
+	"scheduling.k8s.io":            {"PriorityClass": {}},
+	"storage.k8s.io": {
+		"CSIDriver":             {},
+		"CSINode":               {},
+		"StorageClass":          {},
+		"VolumeAttachment":      {},
+		"VolumeAttributesClass": {},
+	},
+}
+
+func apiGroup(sig resource.Signature) string {
+	if i := strings.LastIndex(sig.Version, "/"); i != -1 {
+		return sig.Version[:i]
+	}
+	return ""
+}
+
+// duplicateDetector keeps track of the resources validated so far
+type duplicateDetector struct {
+	sync.Mutex
+	defaultNamespace string
+	seen             map[string]location // indexed by qualified name
+	crdScopes        map[string]bool     // true for cluster-scoped custom resources, indexed by group/kind
+}
+
+type location struct {
+	path string
+	line int
+}
+
+func newDuplicateDetector(defaultNamespace string) *duplicateDetector {
+	return &duplicateDetector{
+		defaultNamespace: defaultNamespace,
+		seen:             map[string]location{},
+		crdScopes:        map[string]bool{},
+	}
+}
+
+// addCRD records the scope of the custom resources defined by the CustomResourceDefinition
+// crd, so the namespace of cluster-scoped custom resources is ignored. CRDs need to be
+// checked before the custom resources they define.
+func (d *duplicateDetector) addCRD(crd map[string]interface{}) {
+	spec, _ := crd["spec"].(map[string]interface{})
+	names, _ := spec["names"].(map[string]interface{})
+	group, _ := spec["group"].(string)
+	kind, _ := names["kind"].(string)
+	scope, _ := spec["scope"].(string)
+	if kind == "" {
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.crdScopes[group+"/"+kind] = scope == "Cluster"
+}
+
+// isClusterScoped returns true if the resource with the signature sig is not namespaced,
+// according to the CRDs found in the input, or to the kinds of the Kubernetes API
+func (d *duplicateDetector) isClusterScoped(sig resource.Signature) bool {
+	group := apiGroup(sig)
+	d.Lock()
+	clusterScoped, ok := d.crdScopes[group+"/"+sig.Kind]
+	d.Unlock()
+	if ok {
+		return clusterScoped
+	}
+	_, ok = clusterScopedKinds[group][sig.Kind]
+	return ok
+}
+
+// check records the resource, found at line of the file path, and returns an error if a
+// resource with the same qualified name was already recorded. Resources without a name,
+// or using generateName, can not conflict and are ignored.
+func (d *duplicateDetector) check(path string, line int, sig resource.Signature) *DuplicateResourceError {
+	if sig.Name == "" || strings.HasSuffix(sig.Name, "{{ generateName }}") {
+		return nil
+	}
+
+	if d.isClusterScoped(sig) {
+		sig.Namespace = ""
+	} else if sig.Namespace == "" {
+		sig.Namespace = d.defaultNamespace
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	qn := sig.QualifiedName()
+	if other, ok := d.seen[qn]; ok {
+		return &DuplicateResourceError{Signature: sig, OtherPath: other.path, OtherLine: other.line}
+	}
+	d.seen[qn] = location{path, line}
+
+	return nil
+}
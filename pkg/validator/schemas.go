@@ -0,0 +1,246 @@
+package validator
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	jsonschema "github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/yannh/kubeconform/pkg/resource"
+)
+
+// schemaCall is the download of a schema, shared by all the resources needing it concurrently
+type schemaCall struct {
+	done   chan struct{}
+	schema *jsonschema.Schema
+	err    error
+}
+
+// schemaCalls are the downloads of schemas in progress, indexed by cache key
+type schemaCalls struct {
+	sync.Mutex
+	calls map[string]*schemaCall
+}
+
+// schemaStats counts how the schemas needed to validate resources were obtained
+type schemaStats struct {
+	downloads atomic.Int64 // schemas downloaded and compiled, including the ones that could not be found
+	cacheHits atomic.Int64 // schemas read from the memory cache, or downloaded for another resource
+	notFound  atomic.Int64 // schemas that could not be found in any registry
+}
+
+// schema returns the schema of the resources of kind and apiVersion in the Kubernetes version
+// k8sVersion, from the memory cache or from the registries. Concurrent calls for the same schema
+// share a single download, so each schema is downloaded and compiled once. Schemas that could not
+// be found are cached as nil, so they are only looked for once; download errors are not cached.
+func (val *v) schema(kind, apiVersion, k8sVersion string) (*jsonschema.Schema, error) {
+	if val.shared != nil {
+		return val.shared.schema(kind, apiVersion, k8sVersion)
+	}
+
+	k := key(kind, apiVersion, k8sVersion)
+	if schema, ok := val.cachedSchema(k); ok {
+		return schema, nil
+	}
+
+	val.schemaCalls.Lock()
+	if c, ok := val.schemaCalls.calls[k]; ok {
+		val.schemaCalls.Unlock()
+		<-c.done
+		val.stats.cacheHits.Add(1)
+		return c.schema, c.err
+	}
+	// The download may have completed since the cache was checked
+	if schema, ok := val.cachedSchema(k); ok {
+		val.schemaCalls.Unlock()
+		return schema, nil
+	}
+	if val.schemaCalls.calls == nil {
+		val.schemaCalls.calls = map[string]*schemaCall{}
+	}
+	c := &schemaCall{done: make(chan struct{})}
+	val.schemaCalls.calls[k] = c
+	val.schemaCalls.Unlock()
+
+	c.schema, c.err = val.schemaDownload(val.regs, &val.compilers, kind, apiVersion, k8sVersion)
+	val.stats.downloads.Add(1)
+	if c.err == nil {
+		if c.schema == nil {
+			val.stats.notFound.Add(1)
+		}
+		if val.schemaMemoryCache != nil {
+			val.schemaMemoryCache.Set(k, c.schema)
+		}
+	}
+
+	val.schemaCalls.Lock()
+	delete(val.schemaCalls.calls, k)
+	val.schemaCalls.Unlock()
+	close(c.done)
+
+	return c.schema, c.err
+}
+
+// cachedSchema returns the schema cached with the key k, nil if it could not be found
+func (val *v) cachedSchema(k string) (*jsonschema.Schema, bool) {
+	if val.schemaMemoryCache == nil {
+		return nil, false
+	}
+	s, err := val.schemaMemoryCache.Get(k)
+	if err != nil {
+		return nil, false
+	}
+	val.stats.cacheHits.Add(1)
+	return s.(*jsonschema.Schema), true
+}
+
+// schemaCompilers compile the schemas downloaded from the registries, with a compiler per registry
+// and Kubernetes version. Resources loaded by a compiler are shared by the schemas it compiles, so
+// the definitions referenced by non-standalone schemas, such as _definitions.json, are loaded and
+// compiled once rather than for each schema.
+type schemaCompilers struct {
+	sync.Mutex
+	loader    jsonschema.SchemeURLLoader
+	compilers map[schemaCompilerKey]*schemaCompiler
+}
+
+type schemaCompilerKey struct {
+	registry   int // index of the registry in the registries of the validator
+	k8sVersion string
+}
+
+// schemaCompiler is a compiler, which can only compile a schema at a time
+type schemaCompiler struct {
+	sync.Mutex
+	c *jsonschema.Compiler
+}
+
+func (sc *schemaCompilers) newCompiler() *jsonschema.Compiler {
+	c := jsonschema.NewCompiler()
+	c.RegisterFormat(&jsonschema.Format{Name: "duration", Validate: validateDuration})
+	c.RegisterVocabulary(validationsVocabulary)
+	c.RegisterVocabulary(structuralVocabulary)
+	c.UseLoader(structuralLoader{sc.loader})
+	c.DefaultDraft(jsonschema.Draft4)
+	return c
+}
+
+// structuralLoader rewrites the documents loaded by a loader with structuralSchema, so that the
+// schemas referenced by the schemas of the registries, such as the definitions in _definitions.json,
+// are rewritten like the schemas themselves
+type structuralLoader struct {
+	loader jsonschema.URLLoader
+}
+
+func (l structuralLoader) Load(url string) (any, error) {
+	doc, err := l.loader.Load(url)
+	if err != nil {
+		return nil, err
+	}
+	return structuralSchema(doc), nil
+}
+
+// compile compiles the schema s, downloaded from path in the registry with the index reg,
+// with the compiler of the registry for the Kubernetes version k8sVersion. If shared is
+// false, s is compiled with a new compiler instead.
+func (sc *schemaCompilers) compile(reg int, shared bool, k8sVersion, path string, s any) (*jsonschema.Schema, error) {
+	sc.Lock()
+	k := schemaCompilerKey{reg, k8sVersion}
+	compiler, ok := sc.compilers[k]
+	if !ok || !shared {
+		compiler = &schemaCompiler{c: sc.newCompiler()}
+	}
+	if !ok && shared {
+		if sc.compilers == nil {
+			sc.compilers = map[schemaCompilerKey]*schemaCompiler{}
+		}
+		sc.compilers[k] = compiler
+	}
+	sc.Unlock()
+
+	compiler.Lock()
+	defer compiler.Unlock()
+
+	// A schema at the same path was already compiled, for another apiVersion of the same kind
+	var exists *jsonschema.ResourceExistsError
+	if err := compiler.c.AddResource(path, structuralSchema(s)); err != nil && !errors.As(err, &exists) {
+		return nil, err
+	}
+	return compiler.c.Compile(path)
+}
+
+// PrefetchStats counts the schemas resolved by Prefetch
+type PrefetchStats struct {
+	Fetched int // schemas downloaded from a registry
+	Cached  int // schemas that were already in the memory cache
+	Missing int // schemas that could not be found in any registry
+	Errors  int // schemas that could not be downloaded, they are looked for again during validation
+}
+
+// Prefetch resolves the schemas of the resources with the signatures sigs, for each of the
+// Kubernetes versions k8sVersions, or for the version of the options if none is given. Schemas
+// are downloaded concurrently by up to parallelism goroutines, and cached for the validation
+// of the resources. Resources that are skipped or rejected are ignored.
+func (val *v) Prefetch(sigs []resource.Signature, k8sVersions []string, parallelism int) PrefetchStats {
+	if len(k8sVersions) == 0 {
+		k8sVersions = []string{val.opts.KubernetesVersion}
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	type schemaID struct{ kind, apiVersion, k8sVersion string }
+	ids := []schemaID{}
+	seen := map[schemaID]struct{}{}
+	for _, sig := range sigs {
+		if sig.Kind == "" || sig.Version == "" || matchesKinds(val.opts.SkipKinds, sig) || matchesKinds(val.opts.RejectKinds, sig) {
+			continue
+		}
+		for _, k8sVersion := range k8sVersions {
+			id := schemaID{sig.Kind, sig.Version, k8sVersion}
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	stats := PrefetchStats{}
+	mu := sync.Mutex{}
+	todo := make(chan schemaID)
+	wg := sync.WaitGroup{}
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range todo {
+				if _, cached := val.cachedSchema(key(id.kind, id.apiVersion, id.k8sVersion)); cached {
+					mu.Lock()
+					stats.Cached++
+					mu.Unlock()
+					continue
+				}
+				schema, err := val.schema(id.kind, id.apiVersion, id.k8sVersion)
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					stats.Errors++
+				case schema == nil:
+					stats.Missing++
+				default:
+					stats.Fetched++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, id := range ids {
+		todo <- id
+	}
+	close(todo)
+	wg.Wait()
+
+	return stats
+}
@@ -0,0 +1,279 @@
+package validator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/yannh/kubeconform/pkg/cache"
+	"github.com/yannh/kubeconform/pkg/loader"
+	"github.com/yannh/kubeconform/pkg/registry"
+	"github.com/yannh/kubeconform/pkg/resource"
+)
+
+func TestSchemaDownloadedOnce(t *testing.T) {
+	var downloads sync.Map // number of downloads, indexed by kind
+	val := v{
+		opts:              Opts{SkipKinds: map[string]struct{}{}, RejectKinds: map[string]struct{}{}, IgnoreMissingSchemas: true},
+		schemaMemoryCache: cache.NewInMemoryCache(),
+		regs: []registry.Registry{
+			newMockRegistry(func() (string, any, error) {
+				return "", map[string]any{"type": "object"}, nil
+			}),
+		},
+	}
+	val.schemaDownload = func(registries []registry.Registry, compilers *schemaCompilers, kind, version, k8sVersion string) (*jsonschema.Schema, error) {
+		n, _ := downloads.LoadOrStore(kind, new(atomic.Int64))
+		n.(*atomic.Int64).Add(1)
+		time.Sleep(10 * time.Millisecond) // give other workers the time to need the schema too
+		if kind == "Widget" {
+			return nil, nil
+		}
+		return downloadSchema(registries, compilers, kind, version, k8sVersion)
+	}
+
+	resources := make(chan resource.Resource)
+	go func() {
+		for i := 0; i < 500; i++ {
+			kind := "Deployment"
+			if i%5 == 0 {
+				kind = "Widget"
+			}
+			resources <- resource.Resource{Bytes: []byte(fmt.Sprintf("apiVersion: apps/v1\nkind: %s\nmetadata:\n  name: app-%d\n", kind, i))}
+		}
+		close(resources)
+	}()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for res := range resources {
+				if result := val.ValidateResource(res); result.Status != Valid && result.Status != Warning {
+					t.Errorf("expected resource to be valid or to have warnings, got %d: %s", result.Status, result.Err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, kind := range []string{"Deployment", "Widget"} {
+		if n, _ := downloads.Load(kind); n == nil || n.(*atomic.Int64).Load() != 1 {
+			t.Errorf("expected the schema of %s to be downloaded once, got %v", kind, n)
+		}
+	}
+	if val.stats.downloads.Load() != 2 || val.stats.notFound.Load() != 1 || val.stats.cacheHits.Load() != 498 {
+		t.Errorf("expected 2 downloads, 1 schema not found and 498 cache hits, got %d, %d and %d", val.stats.downloads.Load(), val.stats.notFound.Load(), val.stats.cacheHits.Load())
+	}
+}
+
+func TestSchemaDownloadErrorsNotCached(t *testing.T) {
+	calls := 0
+	val := v{
+		opts:              Opts{SkipKinds: map[string]struct{}{}, RejectKinds: map[string]struct{}{}},
+		schemaDownload:    downloadSchema,
+		schemaMemoryCache: cache.NewInMemoryCache(),
+		regs: []registry.Registry{
+			newMockRegistry(func() (string, any, error) {
+				calls++
+				if calls == 1 {
+					return "", nil, fmt.Errorf("connection reset")
+				}
+				return "", nil, loader.NewNotFoundError(fmt.Errorf("not found"))
+			}),
+		},
+	}
+
+	res := resource.Resource{Bytes: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n")}
+	for i, expect := range []string{"connection reset", "could not find schema for ConfigMap", "could not find schema for ConfigMap"} {
+		if result := val.ValidateResource(res); result.Status != Error || result.Err == nil || result.Err.Error() != expect {
+			t.Errorf("validation %d: expected error %s, got %v", i, expect, result.Err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected the schema to be looked for again after an error only, got %d downloads", calls)
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	var running, maxRunning, downloads atomic.Int64
+	val := v{
+		opts: Opts{
+			SkipKinds:         map[string]struct{}{"Secret": {}},
+			RejectKinds:       map[string]struct{}{},
+			KubernetesVersion: "master",
+		},
+		schemaMemoryCache: cache.NewInMemoryCache(),
+		regs: []registry.Registry{
+			newMockRegistry(func() (string, any, error) {
+				return "", map[string]any{"type": "object"}, nil
+			}),
+		},
+	}
+	val.schemaDownload = func(registries []registry.Registry, compilers *schemaCompilers, kind, version, k8sVersion string) (*jsonschema.Schema, error) {
+		downloads.Add(1)
+		n := running.Add(1)
+		defer running.Add(-1)
+		for m := maxRunning.Load(); n > m && !maxRunning.CompareAndSwap(m, n); m = maxRunning.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		switch kind {
+		case "Widget":
+			return nil, nil
+		case "Gadget":
+			return nil, fmt.Errorf("connection reset")
+		}
+		return downloadSchema(registries, compilers, kind, version, k8sVersion)
+	}
+
+	sigs := []resource.Signature{
+		{Kind: "Secret", Version: "v1", Name: "skipped"},
+		{Kind: "Widget", Version: "example.com/v1", Name: "missing"},
+		{Kind: "Gadget", Version: "example.com/v1", Name: "error"},
+		{Kind: "Deployment", Version: "apps/v1", Name: "a"},
+		{Kind: "Deployment", Version: "apps/v1", Name: "b"},
+		{Kind: "Service", Version: "v1", Name: "c"},
+	}
+	for i := 0; i < 6; i++ {
+		sigs = append(sigs, resource.Signature{Kind: fmt.Sprintf("Kind%d", i), Version: "v1"})
+	}
+
+	for _, testCase := range []struct {
+		name        string
+		k8sVersions []string
+		expect      PrefetchStats
+	}{
+		{"first prefetch", []string{"1.27.0", "1.28.0"}, PrefetchStats{Fetched: 16, Missing: 2, Errors: 2}},
+		{"schemas cached, except errors", []string{"1.27.0", "1.28.0"}, PrefetchStats{Cached: 18, Errors: 2}},
+		{"version of the options", nil, PrefetchStats{Fetched: 8, Missing: 1, Errors: 1}},
+	} {
+		if got := val.Prefetch(sigs, testCase.k8sVersions, 4); got != testCase.expect {
+			t.Errorf("%s: expected %+v, got %+v", testCase.name, testCase.expect, got)
+		}
+	}
+	if maxRunning.Load() > 4 {
+		t.Errorf("expected at most 4 concurrent downloads, got %d", maxRunning.Load())
+	}
+
+	n := downloads.Load()
+	res := resource.Resource{Bytes: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: a\n")}
+	if result := val.ValidateResourceForVersion(res, "1.28.0"); result.Status != Valid {
+		t.Errorf("expected resource to be valid, got %d: %s", result.Status, result.Err)
+	}
+	if downloads.Load() != n {
+		t.Errorf("expected the prefetched schema to be used for validation")
+	}
+}
+
+// countingLoader counts the documents loaded, indexed by URL
+type countingLoader struct {
+	sync.Mutex
+	loads map[string]int
+}
+
+func (l *countingLoader) Load(url string) (any, error) {
+	l.Lock()
+	l.loads[filepath.Base(url)]++
+	l.Unlock()
+	return jsonschema.FileLoader{}.Load(url)
+}
+
+func TestNonStandaloneSchemas(t *testing.T) {
+	dir := t.TempDir()
+	definitions := `{"definitions": {
+  "io.k8s.api.core.v1.PodTemplateSpec": {"type": "object", "properties": {"spec": {"type": "object", "properties": {"containers": {"type": "array"}}}}},
+  "io.k8s.api.apps.v1.DeploymentSpec": {"type": "object", "properties": {"replicas": {"type": "integer"}, "template": {"$ref": "#/definitions/io.k8s.api.core.v1.PodTemplateSpec"}}},
+  "io.k8s.api.apps.v1.StatefulSetSpec": {"type": "object", "properties": {"replicas": {"type": "integer"}, "template": {"$ref": "#/definitions/io.k8s.api.core.v1.PodTemplateSpec"}}}
+}}`
+	files := map[string]string{
+		"_definitions.json":            definitions,
+		"deployment-apps-v1.json":      `{"type": "object", "properties": {"spec": {"$ref": "_definitions.json#/definitions/io.k8s.api.apps.v1.DeploymentSpec"}}}`,
+		"statefulset-apps-v1.json":     `{"type": "object", "properties": {"spec": {"$ref": "_definitions.json#/definitions/io.k8s.api.apps.v1.StatefulSetSpec"}}}`,
+		"deployment-apps-v1beta1.json": `{"type": "object", "properties": {"spec": {"$ref": "_definitions.json#/definitions/io.k8s.api.apps.v1.DeploymentSpec"}}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reg, err := registry.New(filepath.Join(dir, "{{ .ResourceKind }}{{ .KindSuffix }}.json"), "", false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &countingLoader{loads: map[string]int{}}
+	val := v{
+		opts:              Opts{SkipKinds: map[string]struct{}{}, RejectKinds: map[string]struct{}{}},
+		schemaDownload:    downloadSchema,
+		schemaMemoryCache: cache.NewInMemoryCache(),
+		regs:              []registry.Registry{reg},
+		compilers:         schemaCompilers{loader: jsonschema.SchemeURLLoader{"file": l}},
+	}
+
+	for _, testCase := range []struct {
+		doc          string
+		k8sVersion   string
+		expectStatus Status
+	}{
+		{"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: a\nspec:\n  replicas: 2\n", "master", Valid},
+		{"apiVersion: apps/v1\nkind: StatefulSet\nmetadata:\n  name: b\nspec:\n  template:\n    spec:\n      containers: {}\n", "master", Invalid},
+		{"apiVersion: apps/v1beta1\nkind: Deployment\nmetadata:\n  name: c\nspec:\n  replicas: two\n", "master", Invalid},
+		{"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: d\nspec:\n  replicas: 2\n", "1.28.0", Valid},
+	} {
+		res := resource.Resource{Bytes: []byte(testCase.doc)}
+		if result := val.ValidateResourceForVersion(res, testCase.k8sVersion); result.Status != testCase.expectStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", testCase.doc, testCase.expectStatus, result.Status, result.Err)
+		}
+	}
+
+	// The definitions are loaded once per Kubernetes version, rather than once per schema
+	if l.loads["_definitions.json"] != 2 {
+		t.Errorf("expected the definitions to be loaded twice, got %v", l.loads)
+	}
+}
+
+func TestStructuralReferencedSchemas(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"_definitions.json":       `{"definitions": {"io.k8s.apimachinery.pkg.util.intstr.IntOrString": {"type": "string", "format": "int-or-string"}}}`,
+		"deployment-apps-v1.json": `{"type": "object", "properties": {"spec": {"type": "object", "properties": {"maxSurge": {"$ref": "_definitions.json#/definitions/io.k8s.apimachinery.pkg.util.intstr.IntOrString"}}}}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reg, err := registry.New(filepath.Join(dir, "{{ .ResourceKind }}{{ .KindSuffix }}.json"), "", false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	val := v{
+		opts:              Opts{SkipKinds: map[string]struct{}{}, RejectKinds: map[string]struct{}{}},
+		schemaDownload:    downloadSchema,
+		schemaMemoryCache: cache.NewInMemoryCache(),
+		regs:              []registry.Registry{reg},
+		compilers:         schemaCompilers{loader: jsonschema.SchemeURLLoader{"file": jsonschema.FileLoader{}}},
+	}
+
+	for _, testCase := range []struct {
+		maxSurge     string
+		expectStatus Status
+	}{
+		{"25%", Valid},
+		{"1", Valid},
+		{"true", Invalid},
+	} {
+		res := resource.Resource{Bytes: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: a\nspec:\n  maxSurge: " + testCase.maxSurge + "\n")}
+		if result := val.ValidateResourceForVersion(res, "master"); result.Status != testCase.expectStatus {
+			t.Errorf("maxSurge %s: expected status %d, got %d: %s", testCase.maxSurge, testCase.expectStatus, result.Status, result.Err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	jsonschema "github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/message"
+)
+
+// Extensions Kubernetes adds to OpenAPI schemas to describe structural schemas
+// https://kubernetes.io/docs/tasks/extend-kubernetes/custom-resources/custom-resource-definitions/#specifying-a-structural-schema
+const (
+	intOrStringKeyword           = "x-kubernetes-int-or-string"
+	preserveUnknownFieldsKeyword = "x-kubernetes-preserve-unknown-fields"
+	embeddedResourceKeyword      = "x-kubernetes-embedded-resource"
+	listTypeKeyword              = "x-kubernetes-list-type"
+	listMapKeysKeyword           = "x-kubernetes-list-map-keys"
+)
+
+// structuralVocabulary makes the compiler validate resources against the Kubernetes extensions
+// of schemas taken from the API server or from CRDs: values of int-or-string fields must be integers
+// or strings, embedded resources must set apiVersion and kind, and the items of lists of type set
+// or map must be unique.
+var structuralVocabulary = &jsonschema.Vocabulary{
+	URL:     "https://kubernetes.io/schemas/structural",
+	Compile: compileStructural,
+}
+
+type structural struct {
+	intOrString      bool
+	embeddedResource bool
+	listType         string
+	listMapKeys      []string
+}
+
+func compileStructural(ctx *jsonschema.CompilerContext, obj map[string]any) (jsonschema.SchemaExt, error) {
+	s := structural{}
+	s.intOrString, _ = obj[intOrStringKeyword].(bool)
+	s.embeddedResource, _ = obj[embeddedResourceKeyword].(bool)
+	s.listType, _ = obj[listTypeKeyword].(string)
+	if keys, ok := obj[listMapKeysKeyword].([]any); ok {
+		for _, k := range keys {
+			if k, ok := k.(string); ok {
+				s.listMapKeys = append(s.listMapKeys, k)
+			}
+		}
+	}
+
+	if !s.intOrString && !s.embeddedResource && s.listType != "set" && (s.listType != "map" || len(s.listMapKeys) == 0) {
+		return nil, nil
+	}
+	return s, nil
+}
+
+func (s structural) Validate(ctx *jsonschema.ValidatorContext, v any) {
+	if s.intOrString {
+		if _, ok := v.(string); !ok && !isInteger(v) {
+			ctx.AddError(&structuralError{intOrStringKeyword, fmt.Sprintf("got %s, want integer or string", jsonType(v)), nil})
+		}
+	}
+
+	if obj, ok := v.(map[string]any); ok && s.embeddedResource {
+		for _, field := range []string{"apiVersion", "kind"} {
+			if _, found := obj[field]; !found {
+				ctx.AddError(&structuralError{embeddedResourceKeyword, fmt.Sprintf("missing property '%s'", field), nil})
+			}
+		}
+	}
+
+	items, ok := v.([]any)
+	if !ok {
+		return
+	}
+	switch s.listType {
+	case "set":
+		seen := map[string]int{}
+		for i, item := range items {
+			k, _ := json.Marshal(item)
+			if j, found := seen[string(k)]; found {
+				ctx.AddError(&structuralError{listTypeKeyword, fmt.Sprintf("items at index %d and %d are equal, but list type is set", j, i), []string{fmt.Sprint(i)}})
+				continue
+			}
+			seen[string(k)] = i
+		}
+
+	case "map":
+		seen := map[string]int{}
+		for i, item := range items {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			values := make([]any, len(s.listMapKeys))
+			for n, key := range s.listMapKeys {
+				values[n] = obj[key]
+			}
+			k, _ := json.Marshal(values)
+			if j, found := seen[string(k)]; found {
+				ctx.AddError(&structuralError{listTypeKeyword, fmt.Sprintf("items at index %d and %d have the same %s, but list type is map", j, i, strings.Join(s.listMapKeys, ", ")), []string{fmt.Sprint(i)}})
+				continue
+			}
+			seen[string(k)] = i
+		}
+	}
+}
+
+// structuralError is reported when a resource does not follow one of the Kubernetes extensions of its schema
+type structuralError struct {
+	keyword string
+	msg     string
+	path    []string // tokens of the JSON pointer the error is located at, relative to the validated value
+}
+
+func (e *structuralError) KeywordPath() []string {
+	return []string{e.keyword}
+}
+
+func (e *structuralError) LocalizedString(*message.Printer) string {
+	return e.msg
+}
+
+func (e *structuralError) relativePath() []string {
+	return e.path
+}
+
+// structuralSchema returns a copy of the schema s, in which the JSON schema keywords conflicting with the
+// Kubernetes extensions are relaxed: int-or-string fields, which are often declared with the type string and
+// the int-or-string format, accept any type, as the vocabulary checks them, and objects preserving unknown
+// fields accept additional properties. This replaces the rewriting done by openapi2jsonschema.py. It is applied
+// to the schemas of the registries, and to the documents they reference, such as _definitions.json.
+func structuralSchema(s any) any {
+	switch s := s.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(s))
+		for k, v := range s {
+			c[k] = structuralSchema(v)
+		}
+		if s["format"] == "int-or-string" {
+			c[intOrStringKeyword] = true
+			delete(c, "format")
+		}
+		if c[intOrStringKeyword] == true {
+			delete(c, "type")
+		}
+		if c[preserveUnknownFieldsKeyword] == true && c["additionalProperties"] == false {
+			delete(c, "additionalProperties")
+		}
+		return c
+
+	case []any:
+		c := make([]any, len(s))
+		for i, v := range s {
+			c[i] = structuralSchema(v)
+		}
+		return c
+	}
+	return s
+}
+
+func isInteger(v any) bool {
+	switch v := v.(type) {
+	case int, int32, int64, uint64:
+		return true
+	case float64:
+		return v == math.Trunc(v) && !math.IsInf(v, 0)
+	}
+	return false
+}
+
+// jsonType returns the name of the JSON type of v, as used in the messages of the jsonschema package
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "number"
+}
@@ -0,0 +1,156 @@
+package validator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/yannh/kubeconform/pkg/registry"
+	"github.com/yannh/kubeconform/pkg/resource"
+)
+
+func TestStructuralSchemas(t *testing.T) {
+	schema := `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "apiVersion": {"type": "string"},
+    "kind": {"type": "string"},
+    "spec": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "port": {"type": "string", "format": "int-or-string"},
+        "maxSurge": {"x-kubernetes-int-or-string": true},
+        "config": {"type": "object", "additionalProperties": false, "x-kubernetes-preserve-unknown-fields": true},
+        "template": {"type": "object", "x-kubernetes-embedded-resource": true, "x-kubernetes-preserve-unknown-fields": true},
+        "finalizers": {"type": "array", "items": {"type": "string"}, "x-kubernetes-list-type": "set"},
+        "ports": {
+          "type": "array",
+          "x-kubernetes-list-type": "map",
+          "x-kubernetes-list-map-keys": ["port", "protocol"],
+          "items": {"type": "object", "properties": {"port": {"type": "integer"}, "protocol": {"type": "string"}}}
+        }
+      }
+    }
+  }
+}`
+
+	for _, testCase := range []struct {
+		name           string
+		rawResource    string
+		expectStatus   Status
+		expectedErrors []ValidationError
+	}{
+		{
+			"valid resource",
+			`kind: name
+apiVersion: v1
+spec:
+  port: 8080
+  maxSurge: 25%
+  config: {debug: true}
+  template: {apiVersion: v1, kind: Pod, spec: {}}
+  finalizers: [a, b]
+  ports: [{port: 80, protocol: TCP}, {port: 80, protocol: UDP}]
+`,
+			Valid,
+			nil,
+		},
+		{
+			"int-or-string field",
+			"kind: name\napiVersion: v1\nspec:\n  maxSurge: 1.5\n",
+			Invalid,
+			[]ValidationError{
+				{Path: "/spec/maxSurge", Msg: "got number, want integer or string", Keyword: "x-kubernetes-int-or-string", Line: 4, Column: 3},
+			},
+		},
+		{
+			"field with the int-or-string format",
+			"kind: name\napiVersion: v1\nspec:\n  port: true\n",
+			Invalid,
+			[]ValidationError{
+				{Path: "/spec/port", Msg: "got boolean, want integer or string", Keyword: "x-kubernetes-int-or-string", Line: 4, Column: 3},
+			},
+		},
+		{
+			"embedded resource",
+			"kind: name\napiVersion: v1\nspec:\n  template: {kind: Pod}\n",
+			Invalid,
+			[]ValidationError{
+				{Path: "/spec/template", Msg: "missing property 'apiVersion'", Keyword: "x-kubernetes-embedded-resource", Line: 4, Column: 3},
+			},
+		},
+		{
+			"set",
+			"kind: name\napiVersion: v1\nspec:\n  finalizers:\n  - a\n  - b\n  - a\n",
+			Invalid,
+			[]ValidationError{
+				{Path: "/spec/finalizers/2", Msg: "items at index 0 and 2 are equal, but list type is set", Keyword: "x-kubernetes-list-type", Line: 7, Column: 5},
+			},
+		},
+		{
+			"map",
+			"kind: name\napiVersion: v1\nspec:\n  ports:\n  - {port: 80, protocol: TCP}\n  - {port: 80, protocol: TCP}\n",
+			Invalid,
+			[]ValidationError{
+				{Path: "/spec/ports/1", Msg: "items at index 0 and 1 have the same port, protocol, but list type is map", Keyword: "x-kubernetes-list-type", Line: 6, Column: 5},
+			},
+		},
+		{
+			"additional properties are still denied outside of objects preserving unknown fields",
+			"kind: name\napiVersion: v1\nspec:\n  replicas: 2\n",
+			Invalid,
+			[]ValidationError{
+				{Path: "/spec", Msg: "additional properties 'replicas' not allowed", Keyword: "additionalProperties", Line: 3, Column: 1},
+			},
+		},
+	} {
+		val := v{
+			opts: Opts{
+				SkipKinds:   map[string]struct{}{},
+				RejectKinds: map[string]struct{}{},
+			},
+			schemaDownload: downloadSchema,
+			regs: []registry.Registry{
+				newMockRegistry(func() (string, any, error) {
+					s, err := jsonschema.UnmarshalJSON(strings.NewReader(schema))
+					return "", s, err
+				}),
+			},
+		}
+
+		got := val.ValidateResource(resource.Resource{Bytes: []byte(testCase.rawResource), Line: 1})
+		if got.Status != testCase.expectStatus {
+			t.Errorf("test \"%s\": expected status %d, got %d: %s", testCase.name, testCase.expectStatus, got.Status, got.Err)
+		}
+		if !reflect.DeepEqual(got.ValidationErrors, testCase.expectedErrors) {
+			t.Errorf("test \"%s\": expected %+v, got %+v", testCase.name, testCase.expectedErrors, got.ValidationErrors)
+		}
+	}
+}
+
+func TestStructuralSchema(t *testing.T) {
+	s := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"port":   map[string]any{"type": "string", "format": "int-or-string"},
+			"config": map[string]any{"type": "object", "additionalProperties": false, "x-kubernetes-preserve-unknown-fields": true},
+		},
+	}
+	expected := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"port":   map[string]any{"x-kubernetes-int-or-string": true},
+			"config": map[string]any{"type": "object", "x-kubernetes-preserve-unknown-fields": true},
+		},
+	}
+
+	if got := structuralSchema(s); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if port := s["properties"].(map[string]any)["port"].(map[string]any); port["format"] != "int-or-string" {
+		t.Errorf("expected the original schema to be left unchanged, got %+v", s)
+	}
+}
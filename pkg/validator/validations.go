@@ -0,0 +1,259 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	jsonschema "github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/yannh/kubeconform/pkg/cel"
+	"golang.org/x/text/message"
+)
+
+// validationsKeyword is the keyword CustomResourceDefinitions use to declare CEL validation rules
+// https://kubernetes.io/docs/tasks/extend-kubernetes/custom-resources/custom-resource-definitions/#validation-rules
+const validationsKeyword = "x-kubernetes-validations"
+
+// unsupportedValidationRule is the ID of the rule reporting x-kubernetes-validations rules,
+// or their messageExpression and fieldPath, that could not be compiled
+const unsupportedValidationRule = "unsupported-validation-rule"
+
+// validationsVocabulary makes the compiler evaluate the x-kubernetes-validations rules of schemas.
+// Transition rules, which compare a resource to its previous version using oldSelf, are ignored.
+// Rules that can not be compiled are reported with a warning on the resources validated against
+// the schema, see unsupportedRules.
+var validationsVocabulary = &jsonschema.Vocabulary{
+	URL:     "https://kubernetes.io/schemas/x-kubernetes-validations",
+	Compile: compileValidations,
+}
+
+// validationRule is a compiled x-kubernetes-validations rule
+type validationRule struct {
+	rule              string
+	program           *cel.Program // nil if the rule could not be compiled
+	message           string
+	messageExpression *cel.Program
+	fieldPath         []string // tokens of the JSON pointer errors are reported at, relative to the validated value
+	unsupported       []string // why parts of the rule could not be compiled, reported as warnings
+}
+
+type validations []validationRule
+
+func compileValidations(ctx *jsonschema.CompilerContext, obj map[string]any) (jsonschema.SchemaExt, error) {
+	rules, ok := obj[validationsKeyword].([]any)
+	if !ok {
+		return nil, nil
+	}
+
+	compiled := validations{}
+	for _, r := range rules {
+		rule, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+		expr, _ := rule["rule"].(string)
+		vr := validationRule{rule: expr}
+		p, err := cel.Compile(expr, "self")
+		if err != nil {
+			if _, transitionErr := cel.Compile(expr, "self", "oldSelf"); transitionErr == nil {
+				continue
+			}
+			vr.unsupported = append(vr.unsupported, fmt.Sprintf("rule was not evaluated, %s", err))
+			compiled = append(compiled, vr)
+			continue
+		}
+		vr.program = p
+
+		vr.message, _ = rule["message"].(string)
+		if msgExpr, ok := rule["messageExpression"].(string); ok {
+			if vr.messageExpression, err = cel.CompileString(msgExpr, "self"); err != nil {
+				vr.unsupported = append(vr.unsupported, fmt.Sprintf("messageExpression of rule %s was not evaluated, %s", expr, err))
+			}
+		}
+		if fieldPath, ok := rule["fieldPath"].(string); ok {
+			if vr.fieldPath, err = parseFieldPath(fieldPath); err != nil {
+				vr.unsupported = append(vr.unsupported, fmt.Sprintf("errors of rule %s are reported at the validated field: %s", expr, err))
+			}
+		}
+		compiled = append(compiled, vr)
+	}
+
+	if len(compiled) == 0 {
+		return nil, nil
+	}
+	return compiled, nil
+}
+
+func (vs validations) Validate(ctx *jsonschema.ValidatorContext, v any) {
+	for _, vr := range vs {
+		if vr.program == nil {
+			continue
+		}
+		ok, err := vr.program.EvalBool(map[string]any{"self": v})
+		switch {
+		case err != nil:
+			ctx.AddError(&validationRuleError{fmt.Sprintf("%s evaluating rule: %s", err, vr.rule), vr.fieldPath})
+		case !ok:
+			ctx.AddError(&validationRuleError{vr.errorMessage(v), vr.fieldPath})
+		}
+	}
+}
+
+// errorMessage returns the message reported when the rule is not followed by v. As in
+// Kubernetes, messageExpression takes precedence over message when it evaluates to a
+// non-empty string.
+func (vr validationRule) errorMessage(v any) string {
+	if vr.messageExpression != nil {
+		if msg, err := vr.messageExpression.EvalString(map[string]any{"self": v}); err == nil && strings.TrimSpace(msg) != "" {
+			return msg
+		}
+	}
+	if vr.message != "" {
+		return vr.message
+	}
+	return "failed rule: " + vr.rule
+}
+
+// validationRuleError is reported when a resource does not follow an x-kubernetes-validations rule
+type validationRuleError struct {
+	msg       string
+	fieldPath []string
+}
+
+func (e *validationRuleError) KeywordPath() []string {
+	return []string{validationsKeyword}
+}
+
+func (e *validationRuleError) LocalizedString(*message.Printer) string {
+	return e.msg
+}
+
+func (e *validationRuleError) relativePath() []string {
+	return e.fieldPath
+}
+
+// unsupportedRules returns a warning for each part of the x-kubernetes-validations rules of
+// schema, or of the schemas it uses, that could not be compiled. The warnings are found once,
+// when the schema is first used, rather than while validating: they are reported once per
+// resource, and do not change the result of the validation.
+func (val *v) unsupportedRules(schema *jsonschema.Schema) []ValidationError {
+	if warnings, ok := val.ruleWarnings.Load(schema); ok {
+		return warnings.([]ValidationError)
+	}
+
+	warnings := []ValidationError{}
+	visited := map[*jsonschema.Schema]bool{}
+	var walk func(s *jsonschema.Schema)
+	walk = func(s *jsonschema.Schema) {
+		if s == nil || visited[s] {
+			return
+		}
+		visited[s] = true
+
+		for _, ext := range s.Extensions {
+			vs, ok := ext.(validations)
+			if !ok {
+				continue
+			}
+			location := s.Location
+			if i := strings.Index(location, "#"); i >= 0 {
+				location = location[i:]
+			}
+			for _, vr := range vs {
+				for _, msg := range vr.unsupported {
+					warnings = append(warnings, ValidationError{
+						Msg:      fmt.Sprintf("%s, in schema %s", msg, location),
+						Keyword:  validationsKeyword,
+						Rule:     unsupportedValidationRule,
+						Severity: SeverityWarning,
+					})
+				}
+			}
+		}
+
+		for _, sub := range subschemas(s) {
+			walk(sub)
+		}
+	}
+	walk(schema)
+
+	val.ruleWarnings.Store(schema, warnings)
+	return warnings
+}
+
+// subschemas returns the schemas the schema s uses to validate values
+func subschemas(s *jsonschema.Schema) []*jsonschema.Schema {
+	subs := []*jsonschema.Schema{s.Ref, s.RecursiveRef, s.Not, s.If, s.Then, s.Else, s.PropertyNames,
+		s.UnevaluatedProperties, s.Contains, s.Items2020, s.UnevaluatedItems, s.ContentSchema}
+	if s.DynamicRef != nil {
+		subs = append(subs, s.DynamicRef.Ref)
+	}
+	subs = append(subs, s.AllOf...)
+	subs = append(subs, s.AnyOf...)
+	subs = append(subs, s.OneOf...)
+	subs = append(subs, s.PrefixItems...)
+	for _, sub := range s.Properties {
+		subs = append(subs, sub)
+	}
+	for _, sub := range s.PatternProperties {
+		subs = append(subs, sub)
+	}
+	for _, sub := range s.DependentSchemas {
+		subs = append(subs, sub)
+	}
+	for _, dep := range s.Dependencies {
+		if sub, ok := dep.(*jsonschema.Schema); ok {
+			subs = append(subs, sub)
+		}
+	}
+	for _, v := range []any{s.AdditionalProperties, s.Items, s.AdditionalItems} {
+		switch v := v.(type) {
+		case *jsonschema.Schema:
+			subs = append(subs, v)
+		case []*jsonschema.Schema:
+			subs = append(subs, v...)
+		}
+	}
+	return subs
+}
+
+// parseFieldPath returns the tokens of the relative JSON path fieldPath, such as .spec.foo
+// or .metadata.labels['app.kubernetes.io/name']
+func parseFieldPath(fieldPath string) ([]string, error) {
+	tokens := []string{}
+	s := fieldPath
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, "."):
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end == -1 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid field path %s", fieldPath)
+			}
+			tokens = append(tokens, s[:end])
+			s = s[end:]
+
+		case strings.HasPrefix(s, "['"):
+			end := strings.Index(s, "']")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid field path %s", fieldPath)
+			}
+			tokens = append(tokens, s[2:end])
+			s = s[end+2:]
+
+		case strings.HasPrefix(s, "["):
+			end := strings.Index(s, "]")
+			if end == -1 || strings.Trim(s[1:end], "0123456789") != "" || end == 1 {
+				return nil, fmt.Errorf("invalid field path %s", fieldPath)
+			}
+			tokens = append(tokens, s[1:end])
+			s = s[end+1:]
+
+		default:
+			return nil, fmt.Errorf("invalid field path %s", fieldPath)
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,241 @@
+package validator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/yannh/kubeconform/pkg/registry"
+	"github.com/yannh/kubeconform/pkg/resource"
+)
+
+func TestValidationRules(t *testing.T) {
+	schema := `{
+  "type": "object",
+  "properties": {
+    "spec": {
+      "type": "object",
+      "x-kubernetes-validations": [
+        {"rule": "self.minReplicas <= self.maxReplicas", "message": "minReplicas must not exceed maxReplicas"},
+        {"rule": "self.name.startsWith('app-')", "messageExpression": "'invalid name ' + self.name", "fieldPath": ".name"},
+        {"rule": "self.replicas == oldSelf.replicas", "message": "transition rules are ignored"}
+      ],
+      "properties": {
+        "minReplicas": {"type": "integer"},
+        "maxReplicas": {"type": "integer"},
+        "name": {"type": "string"},
+        "ports": {
+          "type": "array",
+          "items": {"type": "integer", "x-kubernetes-validations": [{"rule": "self > 1024"}]}
+        }
+      }
+    }
+  }
+}`
+
+	for _, testCase := range []struct {
+		name           string
+		rawResource    string
+		expectStatus   Status
+		expectedErrors []ValidationError
+	}{
+		{
+			"valid resource",
+			"kind: name\napiVersion: v1\nspec:\n  minReplicas: 1\n  maxReplicas: 2\n  name: app-web\n  ports: [8080]\n",
+			Valid,
+			nil,
+		},
+		{
+			"rules are only evaluated on values that are set",
+			"kind: name\napiVersion: v1\n",
+			Valid,
+			nil,
+		},
+		{
+			"failed rules",
+			"kind: name\napiVersion: v1\nspec:\n  minReplicas: 3\n  maxReplicas: 2\n  name: web\n  ports: [8080, 80]\n",
+			Invalid,
+			[]ValidationError{
+				{Path: "/spec/ports/1", Msg: "failed rule: self > 1024", Keyword: "x-kubernetes-validations", Line: 7, Column: 17},
+				{Path: "/spec", Msg: "minReplicas must not exceed maxReplicas", Keyword: "x-kubernetes-validations", Line: 3, Column: 1},
+				{Path: "/spec/name", Msg: "invalid name web", Keyword: "x-kubernetes-validations", Line: 6, Column: 3},
+			},
+		},
+		{
+			"rule evaluation error",
+			"kind: name\napiVersion: v1\nspec:\n  name: app-web\n",
+			Invalid,
+			[]ValidationError{
+				{Path: "/spec", Msg: "no such key: minReplicas evaluating rule: self.minReplicas <= self.maxReplicas", Keyword: "x-kubernetes-validations", Line: 3, Column: 1},
+			},
+		},
+	} {
+		val := v{
+			opts: Opts{
+				SkipKinds:   map[string]struct{}{},
+				RejectKinds: map[string]struct{}{},
+			},
+			schemaDownload: downloadSchema,
+			regs: []registry.Registry{
+				newMockRegistry(func() (string, any, error) {
+					s, err := jsonschema.UnmarshalJSON(strings.NewReader(schema))
+					return "", s, err
+				}),
+			},
+		}
+
+		got := val.ValidateResource(resource.Resource{Bytes: []byte(testCase.rawResource), Line: 1})
+		if got.Status != testCase.expectStatus {
+			t.Errorf("test \"%s\": expected status %d, got %d: %s", testCase.name, testCase.expectStatus, got.Status, got.Err)
+		}
+		if !reflect.DeepEqual(got.ValidationErrors, testCase.expectedErrors) {
+			t.Errorf("test \"%s\": expected %+v, got %+v", testCase.name, testCase.expectedErrors, got.ValidationErrors)
+		}
+	}
+}
+
+func TestUnsupportedValidationRules(t *testing.T) {
+	schema := `{
+  "type": "object",
+  "properties": {
+    "spec": {
+      "type": "object",
+      "x-kubernetes-validations": [
+        {"rule": "quantity(self.memory).isLessThan(quantity('1Gi'))"},
+        {"rule": "self.replicas > 0", "messageExpression": "type(self.replicas)", "message": "replicas must be positive"},
+        {"rule": "self.replicas < 10", "fieldPath": "replicas"},
+        {"rule": "self.replicas >= oldSelf.replicas"}
+      ]
+    }
+  }
+}`
+
+	warnings := []ValidationError{
+		{Msg: "rule was not evaluated, failed compiling expression quantity(self.memory).isLessThan(quantity('1Gi')): undeclared reference to 'quantity', undeclared reference to 'isLessThan', in schema #/properties/spec", Keyword: "x-kubernetes-validations", Line: 1, Column: 1, Rule: "unsupported-validation-rule", Severity: "warning"},
+		{Msg: "messageExpression of rule self.replicas > 0 was not evaluated, failed compiling expression type(self.replicas): evaluates to type(dyn), expected string, in schema #/properties/spec", Keyword: "x-kubernetes-validations", Line: 1, Column: 1, Rule: "unsupported-validation-rule", Severity: "warning"},
+		{Msg: "errors of rule self.replicas < 10 are reported at the validated field: invalid field path replicas, in schema #/properties/spec", Keyword: "x-kubernetes-validations", Line: 1, Column: 1, Rule: "unsupported-validation-rule", Severity: "warning"},
+	}
+
+	for _, testCase := range []struct {
+		name           string
+		rawResource    string
+		expectStatus   Status
+		expectedErrors []ValidationError
+	}{
+		{
+			"valid resource, with warnings for the rules that could not be compiled",
+			"kind: name\napiVersion: v1\nspec:\n  replicas: 1\n",
+			Warning,
+			warnings,
+		},
+		{
+			"invalid resource, using the message and the validated field instead",
+			"kind: name\napiVersion: v1\nspec:\n  replicas: 10\n",
+			Invalid,
+			append(append([]ValidationError{}, warnings...),
+				ValidationError{Path: "/spec", Msg: "failed rule: self.replicas < 10", Keyword: "x-kubernetes-validations", Line: 3, Column: 1},
+			),
+		},
+		{
+			"invalid resource, using the message instead of the messageExpression",
+			"kind: name\napiVersion: v1\nspec:\n  replicas: 0\n",
+			Invalid,
+			append(append([]ValidationError{}, warnings...),
+				ValidationError{Path: "/spec", Msg: "replicas must be positive", Keyword: "x-kubernetes-validations", Line: 3, Column: 1},
+			),
+		},
+	} {
+		val := v{
+			opts: Opts{
+				SkipKinds:   map[string]struct{}{},
+				RejectKinds: map[string]struct{}{},
+			},
+			schemaDownload: downloadSchema,
+			regs: []registry.Registry{
+				newMockRegistry(func() (string, any, error) {
+					s, err := jsonschema.UnmarshalJSON(strings.NewReader(schema))
+					return "", s, err
+				}),
+			},
+		}
+
+		got := val.ValidateResource(resource.Resource{Bytes: []byte(testCase.rawResource), Line: 1})
+		if got.Status != testCase.expectStatus {
+			t.Errorf("test \"%s\": expected status %d, got %d: %s", testCase.name, testCase.expectStatus, got.Status, got.Err)
+		}
+		if !reflect.DeepEqual(got.ValidationErrors, testCase.expectedErrors) {
+			t.Errorf("test \"%s\": expected %+v, got %+v", testCase.name, testCase.expectedErrors, got.ValidationErrors)
+		}
+	}
+}
+
+func TestUnsupportedValidationRulesDoNotChangeValidation(t *testing.T) {
+	schema := `{
+  "type": "object",
+  "properties": {
+    "ports": {
+      "type": "array",
+      "items": {
+        "oneOf": [
+          {"type": "integer", "x-kubernetes-validations": [{"rule": "url(self).getScheme() == 'https'"}]},
+          {"type": "string"}
+        ]
+      }
+    }
+  }
+}`
+
+	val := v{
+		opts: Opts{
+			SkipKinds:   map[string]struct{}{},
+			RejectKinds: map[string]struct{}{},
+		},
+		schemaDownload: downloadSchema,
+		regs: []registry.Registry{
+			newMockRegistry(func() (string, any, error) {
+				s, err := jsonschema.UnmarshalJSON(strings.NewReader(schema))
+				return "", s, err
+			}),
+		},
+	}
+
+	// The rule applies to each integer, it is reported once and does not make the first branch of oneOf fail
+	for i := 0; i < 2; i++ {
+		got := val.ValidateResource(resource.Resource{Bytes: []byte("kind: name\napiVersion: v1\nports: [1, 2, a]\n"), Line: 1})
+		expected := []ValidationError{
+			{Msg: "rule was not evaluated, failed compiling expression url(self).getScheme() == 'https': undeclared reference to 'url', undeclared reference to 'getScheme', in schema #/properties/ports/items/oneOf/0", Keyword: "x-kubernetes-validations", Line: 1, Column: 1, Rule: "unsupported-validation-rule", Severity: "warning"},
+		}
+		if got.Status != Warning {
+			t.Errorf("expected status %d, got %d: %s", Warning, got.Status, got.Err)
+		}
+		if !reflect.DeepEqual(got.ValidationErrors, expected) {
+			t.Errorf("expected %+v, got %+v", expected, got.ValidationErrors)
+		}
+	}
+}
+
+func TestParseFieldPath(t *testing.T) {
+	for _, testCase := range []struct {
+		fieldPath string
+		expected  []string
+		expectErr bool
+	}{
+		{".spec.replicas", []string{"spec", "replicas"}, false},
+		{".metadata.labels['app.kubernetes.io/name']", []string{"metadata", "labels", "app.kubernetes.io/name"}, false},
+		{"['spec'].ports[0]", []string{"spec", "ports", "0"}, false},
+		{"", []string{}, false},
+		{"spec", nil, true},
+		{".spec..replicas", nil, true},
+		{".spec['replicas", nil, true},
+		{".ports[a]", nil, true},
+	} {
+		got, err := parseFieldPath(testCase.fieldPath)
+		if (err != nil) != testCase.expectErr {
+			t.Errorf("%s: expected error %t, got %s", testCase.fieldPath, testCase.expectErr, err)
+		}
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf("%s: expected %+v, got %+v", testCase.fieldPath, testCase.expected, got)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	jsonschema "github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
 	"github.com/yannh/kubeconform/pkg/cache"
 	"github.com/yannh/kubeconform/pkg/loader"
 	"github.com/yannh/kubeconform/pkg/registry"
@@ -14,8 +15,9 @@ import (
 	"golang.org/x/text/message"
 	"io"
 	"os"
-	"sigs.k8s.io/yaml"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -29,30 +31,80 @@ const (
 	Valid          // resource is valid
 	Invalid        // resource is invalid
 	Empty          // resource is empty. Note: is triggered for files starting with a --- separator.
+	Warning        // resource is valid, but warnings were reported, for example for a deprecated apiVersion
+)
+
+// missingSchemaRule is the ID of the rule reporting resources whose schema could not be found, with -ignore-missing-schemas
+const missingSchemaRule = "missing-schema"
+
+// Severities of the validation errors reported by rules
+const (
+	SeverityError   = "error"   // the resource is invalid
+	SeverityWarning = "warning" // the error is reported, but the resource is still valid
 )
 
 type ValidationError struct {
-	Path string `json:"path"`
-	Msg  string `json:"msg"`
+	Path     string `json:"path"`
+	Msg      string `json:"msg"`
+	Keyword  string `json:"keyword,omitempty"`  // JSON schema keyword the resource does not satisfy, e.g. required
+	Line     int    `json:"line,omitempty"`     // line of the file the error is located at, 0 if unknown
+	Column   int    `json:"column,omitempty"`   // column of the file the error is located at, 0 if unknown
+	Rule     string `json:"rule,omitempty"`     // ID of the rule reporting the error, empty for schema errors
+	Severity string `json:"severity,omitempty"` // severity of the rule reporting the error
 }
 
 func (ve *ValidationError) Error() string {
 	return ve.Msg
 }
 
-// Result contains the details of the result of a resource validation
+// HasWarnings returns true if warnings were reported for the resource
+func (r Result) HasWarnings() bool {
+	if r.Status == Warning {
+		return true
+	}
+	for _, ve := range r.ValidationErrors {
+		if ve.Severity == SeverityWarning {
+			return true
+		}
+	}
+	return false
+}
+
+// relativeError is implemented by the errors of the Kubernetes vocabularies that are
+// located below the value the schema validated, such as an item of a list
+type relativeError interface {
+	relativePath() []string
+}
+
+// Result contains the details of the result of a resource validation. For resources with
+// the Warning status, Err summarises the warnings.
 type Result struct {
-	Resource         resource.Resource
-	Err              error
-	Status           Status
-	ValidationErrors []ValidationError
+	Resource          resource.Resource
+	Err               error
+	Status            Status
+	ValidationErrors  []ValidationError
+	KubernetesVersion string // version of Kubernetes the resource was validated against
 }
 
 // Validator exposes multiple methods to validate your Kubernetes resources.
 type Validator interface {
 	ValidateResource(res resource.Resource) Result
+	ValidateResourceForVersion(res resource.Resource, k8sVersion string) Result
 	Validate(filename string, r io.ReadCloser) []Result
 	ValidateWithContext(ctx context.Context, filename string, r io.ReadCloser) []Result
+	Prefetch(sigs []resource.Signature, k8sVersions []string, parallelism int) PrefetchStats
+	// Session returns a validator validating resources against the Kubernetes version k8sVersion,
+	// with the schemas loaded by this one. The CustomResourceDefinitions found in the input and the
+	// duplicates are only tracked for the resources validated by the session, so that independent
+	// sets of resources, such as the requests of a server, do not see each other.
+	Session(k8sVersion string) Validator
+}
+
+// RuleEngine evaluates additional rules, such as organisation policies, on resources
+// that have a schema. It is called after the resource was validated against its schema.
+type RuleEngine interface {
+	// Evaluate returns an error for each rule obj, a resource with the signature sig, does not follow
+	Evaluate(sig resource.Signature, obj map[string]interface{}) []ValidationError
 }
 
 // Opts contains a set of options for the validator.
@@ -65,6 +117,11 @@ type Opts struct {
 	KubernetesVersion    string              // Kubernetes Version - has to match one in https://github.com/instrumenta/kubernetes-json-schema
 	Strict               bool                // thros an error if resources contain undocumented fields
 	IgnoreMissingSchemas bool                // skip a resource if no schema for that resource can be found
+	CheckDuplicates      bool                // report resources with the same apiVersion, kind, namespace and name
+	DefaultNamespace     string              // namespace of resources that do not set one, used to detect duplicates
+	CRDsFromInput        bool                // validate custom resources against the CustomResourceDefinitions found in the input
+	RuleEngine           RuleEngine          // rules evaluated on resources after schema validation, can be nil
+	KubeContext          string              // kubeconfig context of the cluster kube:// schema locations read schemas from
 }
 
 // New returns a new Validator
@@ -72,7 +129,34 @@ func New(schemaLocations []string, opts Opts) (Validator, error) {
 	// Default to our kubernetes-json-schema fork
 	// raw.githubusercontent.com is frontend by Fastly and very fast
 	if len(schemaLocations) == 0 {
-		schemaLocations = []string{"https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/{{ .NormalizedKubernetesVersion }}-standalone{{ .StrictSuffix }}/{{ .ResourceKind }}{{ .KindSuffix }}.json"}
+		schemaLocations = []string{"default"}
+	}
+
+	// With a kubeconfig context, schemas are read from the cluster before any other location
+	if opts.KubeContext != "" {
+		withCluster := []string{}
+		for _, schemaLocation := range schemaLocations {
+			if schemaLocation == "kube://" {
+				schemaLocation = "kube://" + opts.KubeContext
+			}
+			withCluster = append(withCluster, schemaLocation)
+		}
+		if !slices.ContainsFunc(withCluster, func(l string) bool { return strings.HasPrefix(l, "kube://") }) {
+			withCluster = append([]string{"kube://" + opts.KubeContext}, withCluster...)
+		}
+		schemaLocations = withCluster
+	}
+
+	// When schemas are embedded in the binary, they are looked up before downloading them
+	if registry.EmbeddedSchemas {
+		withEmbedded := []string{}
+		for _, schemaLocation := range schemaLocations {
+			if schemaLocation == "default" {
+				withEmbedded = append(withEmbedded, "embedded")
+			}
+			withEmbedded = append(withEmbedded, schemaLocation)
+		}
+		schemaLocations = withEmbedded
 	}
 
 	registries := []registry.Registry{}
@@ -88,6 +172,10 @@ func New(schemaLocations []string, opts Opts) (Validator, error) {
 		opts.KubernetesVersion = "master"
 	}
 
+	if opts.DefaultNamespace == "" {
+		opts.DefaultNamespace = "default"
+	}
+
 	if opts.SkipKinds == nil {
 		opts.SkipKinds = map[string]struct{}{}
 	}
@@ -113,26 +201,73 @@ func New(schemaLocations []string, opts Opts) (Validator, error) {
 		return nil, fmt.Errorf("failed creating HTTP loader: %s", err)
 	}
 
-	return &v{
+	var duplicates *duplicateDetector
+	if opts.CheckDuplicates {
+		duplicates = newDuplicateDetector(opts.DefaultNamespace)
+	}
+
+	val := &v{
 		opts:              opts,
 		schemaDownload:    downloadSchema,
 		schemaMemoryCache: cache.NewInMemoryCache(),
 		regs:              registries,
-		loader: jsonschema.SchemeURLLoader{
-			"file":  jsonschema.FileLoader{},
-			"http":  httpLoader,
-			"https": httpLoader,
+		duplicates:        duplicates,
+		compilers: schemaCompilers{
+			loader: jsonschema.SchemeURLLoader{
+				"file":  jsonschema.FileLoader{},
+				"http":  httpLoader,
+				"https": httpLoader,
+			},
 		},
-	}, nil
+	}
+	if opts.CRDsFromInput {
+		val.crds = registry.NewCRDRegistry(opts.Strict)
+		val.crdSchemas = cache.NewInMemoryCache()
+	}
+
+	return val, nil
 }
 
 type v struct {
 	opts              Opts
 	schemaDiskCache   cache.Cache
 	schemaMemoryCache cache.Cache
-	schemaDownload    func(registries []registry.Registry, loader jsonschema.SchemeURLLoader, kind, version, k8sVersion string) (*jsonschema.Schema, error)
+	schemaDownload    func(registries []registry.Registry, compilers *schemaCompilers, kind, version, k8sVersion string) (*jsonschema.Schema, error)
 	regs              []registry.Registry
-	loader            jsonschema.SchemeURLLoader
+	compilers         schemaCompilers       // compilers of the schemas of each registry and Kubernetes version
+	duplicates        *duplicateDetector    // nil unless duplicate detection is enabled
+	crds              *registry.CRDRegistry // nil unless CRDs found in the input are used for validation
+	crdSchemas        cache.Cache           // schemas compiled from the CRDs found in the input, looked up before the registries
+	shared            *v                    // validator whose schemas are used by a session, nil otherwise
+	ruleWarnings      sync.Map              // x-kubernetes-validations rules that could not be compiled, per schema
+	schemaCalls       schemaCalls           // downloads of schemas in progress
+	stats             schemaStats
+}
+
+// Session returns a validator sharing the schemas of val, with its own CustomResourceDefinitions
+// found in the input and duplicate detection, validating resources against k8sVersion
+func (val *v) Session(k8sVersion string) Validator {
+	shared := val
+	if val.shared != nil {
+		shared = val.shared
+	}
+
+	session := &v{
+		opts:              val.opts,
+		schemaMemoryCache: shared.schemaMemoryCache,
+		regs:              shared.regs,
+		compilers:         schemaCompilers{loader: shared.compilers.loader},
+		shared:            shared,
+	}
+	session.opts.KubernetesVersion = k8sVersion
+	if val.crds != nil {
+		session.crds = registry.NewCRDRegistry(val.opts.Strict)
+		session.crdSchemas = cache.NewInMemoryCache()
+	}
+	if val.duplicates != nil {
+		session.duplicates = newDuplicateDetector(val.opts.DefaultNamespace)
+	}
+	return session
 }
 
 func key(resourceKind, resourceAPIVersion, k8sVersion string) string {
@@ -142,38 +277,31 @@ func key(resourceKind, resourceAPIVersion, k8sVersion string) string {
 // ValidateResource validates a single resource. This allows to validate
 // large resource streams using multiple Go Routines.
 func (val *v) ValidateResource(res resource.Resource) Result {
-	// For backward compatibility reasons when determining whether
-	// a resource should be skipped or rejected we use both
-	// the GVK encoding of the resource signatures (the recommended method
-	// for skipping/rejecting resources) and the raw Kind.
-
-	skip := func(signature resource.Signature) bool {
-		if _, ok := val.opts.SkipKinds[signature.GroupVersionKind()]; ok {
-			return ok
-		}
-		_, ok := val.opts.SkipKinds[signature.Kind]
-		return ok
-	}
+	return val.ValidateResourceForVersion(res, val.opts.KubernetesVersion)
+}
 
-	reject := func(signature resource.Signature) bool {
-		if _, ok := val.opts.RejectKinds[signature.GroupVersionKind()]; ok {
-			return ok
-		}
-		_, ok := val.opts.RejectKinds[signature.Kind]
-		return ok
-	}
+// ValidateResourceForVersion validates a single resource against the schemas of the
+// Kubernetes version k8sVersion, rather than the version set in the options. Schemas
+// are cached per version, so a resource can be validated against several versions.
+func (val *v) ValidateResourceForVersion(res resource.Resource, k8sVersion string) Result {
+	result := val.validateResource(res, k8sVersion)
+	result.KubernetesVersion = k8sVersion
+	return result
+}
 
+func (val *v) validateResource(res resource.Resource, k8sVersion string) Result {
 	if len(res.Bytes) == 0 {
 		return Result{Resource: res, Err: nil, Status: Empty}
 	}
 
-	var r map[string]interface{}
-	unmarshaller := yaml.Unmarshal
+	// Resources are usually decoded during discovery already
+	decode := res.Object
 	if val.opts.Strict {
-		unmarshaller = yaml.UnmarshalStrict
+		decode = res.StrictObject
 	}
 
-	if err := unmarshaller(res.Bytes, &r); err != nil {
+	r, err := decode()
+	if err != nil {
 		return Result{Resource: res, Status: Error, Err: fmt.Errorf("error unmarshalling resource: %s", err)}
 	}
 
@@ -181,87 +309,248 @@ func (val *v) ValidateResource(res resource.Resource) Result {
 		return Result{Resource: res, Err: nil, Status: Empty}
 	}
 
-	sig, err := res.SignatureFromMap(r)
+	sig, err := res.Signature()
 	if err != nil {
 		return Result{Resource: res, Err: fmt.Errorf("error while parsing: %s", err), Status: Error}
 	}
 
-	if skip(*sig) {
+	if matchesKinds(val.opts.SkipKinds, *sig) {
 		return Result{Resource: res, Err: nil, Status: Skipped}
 	}
 
-	if reject(*sig) {
+	if matchesKinds(val.opts.RejectKinds, *sig) {
 		return Result{Resource: res, Err: fmt.Errorf("prohibited resource kind %s", sig.Kind), Status: Error}
 	}
 
-	cached := false
-	var schema *jsonschema.Schema
-
-	if val.schemaMemoryCache != nil {
-		s, err := val.schemaMemoryCache.Get(key(sig.Kind, sig.Version, val.opts.KubernetesVersion))
-		if err == nil {
-			cached = true
-			schema = s.(*jsonschema.Schema)
+	result := val.validateObject(res, r, sig, k8sVersion)
+	if val.duplicates != nil {
+		if sig.IsCRD() {
+			val.duplicates.addCRD(r)
 		}
+		if err := val.duplicates.check(res.Path, res.Line, *sig); err != nil {
+			result = withDuplicateError(result, err)
+		}
+	}
+	return result
+}
+
+// validateObject validates the resource res, decoded as r, against its schema and the rules
+func (val *v) validateObject(res resource.Resource, r map[string]interface{}, sig *resource.Signature, k8sVersion string) Result {
+	// Resources using a deprecated apiVersion are reported with a warning, or with an error
+	// naming the replacement if no schema is found for an apiVersion that was removed
+	warnings := []ValidationError{}
+	deprecation, removed := apiDeprecationMessage(*sig, k8sVersion)
+	if deprecation != "" {
+		line, column := res.Position("/apiVersion")
+		warnings = append(warnings, ValidationError{
+			Path:     "/apiVersion",
+			Msg:      deprecation,
+			Line:     line,
+			Column:   column,
+			Rule:     DeprecatedAPIVersionRule,
+			Severity: SeverityWarning,
+		})
 	}
 
-	if !cached {
-		if schema, err = val.schemaDownload(val.regs, val.loader, sig.Kind, sig.Version, val.opts.KubernetesVersion); err != nil {
+	if val.crds != nil && sig.IsCRD() {
+		if err := val.addCRD(r, k8sVersion); err != nil {
 			return Result{Resource: res, Err: err, Status: Error}
 		}
+	}
 
-		if val.schemaMemoryCache != nil {
-			val.schemaMemoryCache.Set(key(sig.Kind, sig.Version, val.opts.KubernetesVersion), schema)
-		}
+	schema, err := val.resourceSchema(sig.Kind, sig.Version, k8sVersion)
+	if err != nil {
+		return Result{Resource: res, Err: err, Status: Error}
 	}
 
 	if schema == nil {
 		if val.opts.IgnoreMissingSchemas {
-			return Result{Resource: res, Err: nil, Status: Skipped}
+			// The resource is not validated, but reported with a warning like the other findings
+			// that do not make it invalid, so that -fail-on warning and the outputs account for it
+			line, column := res.Position("")
+			warnings = append(warnings, ValidationError{Msg: fmt.Sprintf("could not find schema for %s", sig.Kind), Line: line, Column: column, Rule: missingSchemaRule, Severity: SeverityWarning})
+			return warningResult(res, warnings)
 		}
 
+		if removed {
+			return Result{Resource: res, Err: fmt.Errorf("could not find schema for %s: %s", sig.Kind, deprecation), Status: Error, ValidationErrors: warnings}
+		}
 		return Result{Resource: res, Err: fmt.Errorf("could not find schema for %s", sig.Kind), Status: Error}
 	}
 
+	for _, w := range val.unsupportedRules(schema) {
+		w.Line, w.Column = res.Position("")
+		warnings = append(warnings, w)
+	}
+
+	validationErrors := warnings
+	var validationErr error
 	err = schema.Validate(r)
 	if err != nil {
-		validationErrors := []ValidationError{}
 		var e *jsonschema.ValidationError
 		if errors.As(err, &e) {
-			for _, ve := range e.Causes {
+			for _, ve := range causes(e) {
 				path := ""
 				for _, f := range ve.InstanceLocation {
 					path = path + "/" + f
 				}
+				if re, ok := ve.ErrorKind.(relativeError); ok {
+					for _, f := range re.relativePath() {
+						path = path + "/" + f
+					}
+				}
+				keyword := ""
+				if kp := ve.ErrorKind.KeywordPath(); len(kp) > 0 {
+					keyword = kp[0]
+				}
+				line, column := res.Position(path)
 				validationErrors = append(validationErrors, ValidationError{
-					Path: path,
-					Msg:  ve.ErrorKind.LocalizedString(message.NewPrinter(language.English)),
+					Path:    path,
+					Msg:     ve.ErrorKind.LocalizedString(message.NewPrinter(language.English)),
+					Keyword: keyword,
+					Line:    line,
+					Column:  column,
 				})
 			}
+		}
 
+		validationErr = fmt.Errorf("problem validating schema. Check JSON formatting: %s", strings.ReplaceAll(err.Error(), "\n", " "))
+	}
+
+	if val.opts.RuleEngine != nil {
+		failedRules := []string{}
+		for _, ve := range val.opts.RuleEngine.Evaluate(*sig, r) {
+			ve.Line, ve.Column = res.Position(ve.Path)
+			validationErrors = append(validationErrors, ve)
+			if ve.Severity != SeverityWarning {
+				failedRules = append(failedRules, fmt.Sprintf("rule %s: %s", ve.Rule, ve.Msg))
+			}
 		}
+		if validationErr == nil && len(failedRules) > 0 {
+			validationErr = fmt.Errorf("failed %s", strings.Join(failedRules, ", "))
+		}
+	}
 
+	if validationErr != nil {
 		return Result{
 			Resource:         res,
 			Status:           Invalid,
-			Err:              fmt.Errorf("problem validating schema. Check JSON formatting: %s", strings.ReplaceAll(err.Error(), "\n", " ")),
+			Err:              validationErr,
 			ValidationErrors: validationErrors,
 		}
 	}
 
+	// Valid resources with warnings, such as deprecations or the ones reported by rules, get the Warning status
+	if len(validationErrors) > 0 {
+		return warningResult(res, validationErrors)
+	}
+
 	return Result{Resource: res, Status: Valid}
 }
 
+// warningResult returns the result of the resource res, with the Warning status and the warnings ves
+func warningResult(res resource.Resource, ves []ValidationError) Result {
+	msgs := []string{}
+	for _, ve := range ves {
+		if ve.Rule == "" {
+			msgs = append(msgs, ve.Msg)
+			continue
+		}
+		msgs = append(msgs, fmt.Sprintf("rule %s: %s", ve.Rule, ve.Msg))
+	}
+	return Result{Resource: res, Status: Warning, Err: fmt.Errorf("%s", strings.Join(msgs, ", ")), ValidationErrors: ves}
+}
+
+// matchesKinds returns true if the resource with the signature sig is one of kinds, such as the
+// kinds to skip or reject. For backward compatibility reasons, kinds can contain both the GVK
+// encoding of resource signatures (the recommended method for skipping/rejecting resources)
+// and raw Kinds.
+func matchesKinds(kinds map[string]struct{}, sig resource.Signature) bool {
+	if _, ok := kinds[sig.GroupVersionKind()]; ok {
+		return ok
+	}
+	_, ok := kinds[sig.Kind]
+	return ok
+}
+
+// causes returns the errors that caused the validation error e. The errors of the allOf and
+// $ref keywords, and groups of errors at the same location, are replaced by their own
+// causes, as OpenAPI documents wrap most references in allOf.
+func causes(e *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	l := []*jsonschema.ValidationError{}
+	for _, cause := range e.Causes {
+		switch cause.ErrorKind.(type) {
+		case *kind.AllOf, *kind.Reference, *kind.Group:
+			if len(cause.Causes) > 0 {
+				l = append(l, causes(cause)...)
+				continue
+			}
+		}
+		l = append(l, cause)
+	}
+	return l
+}
+
+// addCRD adds the schemas generated from the CustomResourceDefinition crd to the
+// CRD registry, replacing any schema previously compiled for the same resources
+func (val *v) addCRD(crd map[string]interface{}, k8sVersion string) error {
+	manifests, err := val.crds.AddCRD(crd)
+	if err != nil {
+		return fmt.Errorf("failed converting CustomResourceDefinition to JSON schema: %s", err)
+	}
+
+	for _, m := range manifests {
+		path, s, err := val.crds.DownloadSchema(m.Kind, m.Version, k8sVersion)
+		if err != nil {
+			return err
+		}
+		// The schemas of a CRD change when it is added again, they are compiled on their own
+		// rather than with the other schemas
+		schema, err := val.compilers.compile(-1, false, k8sVersion, path, s)
+		if err != nil {
+			return fmt.Errorf("failed compiling schema generated from CustomResourceDefinition: %s", err)
+		}
+		val.crdSchemas.Set(key(m.Kind, m.Version, k8sVersion), schema)
+	}
+
+	return nil
+}
+
+// resourceSchema returns the schema of the resources of kind and apiVersion, generated from
+// the CustomResourceDefinitions found in the input if one of them defines it, or from the
+// registries otherwise
+func (val *v) resourceSchema(kind, apiVersion, k8sVersion string) (*jsonschema.Schema, error) {
+	if val.crdSchemas != nil {
+		if s, err := val.crdSchemas.Get(key(kind, apiVersion, k8sVersion)); err == nil {
+			return s.(*jsonschema.Schema), nil
+		}
+	}
+	return val.schema(kind, apiVersion, k8sVersion)
+}
+
 // ValidateWithContext validates resources found in r
 // filename should be a name for the stream, such as a filename or stdin
 func (val *v) ValidateWithContext(ctx context.Context, filename string, r io.ReadCloser) []Result {
 	validationResults := []Result{}
 	resourcesChan, _ := resource.FromStream(ctx, filename, r)
+
+	// When using CRDs found in the input, or detecting duplicates, all resources are read
+	// first so that CRDs can be validated before the custom resources they define
+	var deferred []int
 	for {
 		select {
 		case res, ok := <-resourcesChan:
 			if ok {
-				validationResults = append(validationResults, val.ValidateResource(res))
+				if val.crds != nil || val.duplicates != nil {
+					validationResults = append(validationResults, Result{Resource: res})
+					if sig, err := res.Signature(); err == nil && sig.IsCRD() {
+						validationResults[len(validationResults)-1] = val.ValidateResource(res)
+					} else {
+						deferred = append(deferred, len(validationResults)-1)
+					}
+				} else {
+					validationResults = append(validationResults, val.ValidateResource(res))
+				}
 			} else {
 				resourcesChan = nil
 			}
@@ -275,6 +564,10 @@ func (val *v) ValidateWithContext(ctx context.Context, filename string, r io.Rea
 		}
 	}
 
+	for _, i := range deferred {
+		validationResults[i] = val.ValidateResource(validationResults[i].Resource)
+	}
+
 	r.Close()
 	return validationResults
 }
@@ -364,22 +657,15 @@ func validateDuration(v any) error {
 	return nil
 }
 
-func downloadSchema(registries []registry.Registry, l jsonschema.SchemeURLLoader, kind, version, k8sVersion string) (*jsonschema.Schema, error) {
+func downloadSchema(registries []registry.Registry, compilers *schemaCompilers, kind, version, k8sVersion string) (*jsonschema.Schema, error) {
 	var err error
 	var path string
 	var s any
 
-	for _, reg := range registries {
+	for i, reg := range registries {
 		path, s, err = reg.DownloadSchema(kind, version, k8sVersion)
 		if err == nil {
-			c := jsonschema.NewCompiler()
-			c.RegisterFormat(&jsonschema.Format{"duration", validateDuration})
-			c.UseLoader(l)
-			c.DefaultDraft(jsonschema.Draft4)
-			if err := c.AddResource(path, s); err != nil {
-				continue
-			}
-			schema, err := c.Compile(path)
+			schema, err := compilers.compile(i, true, k8sVersion, path, s)
 			// If we got a non-parseable response, we try the next registry
 			if err != nil {
 				continue
@@ -2,10 +2,16 @@ package validator
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"github.com/santhosh-tekuri/jsonschema/v6"
 	"github.com/yannh/kubeconform/pkg/loader"
 	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/yannh/kubeconform/pkg/registry"
@@ -107,8 +113,9 @@ lastName: bar
 			Invalid,
 			[]ValidationError{
 				{
-					Path: "/firstName",
-					Msg:  "got string, want number",
+					Path:    "/firstName",
+					Msg:     "got string, want number",
+					Keyword: "type",
 				},
 			},
 		},
@@ -146,8 +153,9 @@ firstName: foo
 			Invalid,
 			[]ValidationError{
 				{
-					Path: "",
-					Msg:  "missing property 'lastName'",
+					Path:    "",
+					Msg:     "missing property 'lastName'",
+					Keyword: "required",
 				},
 			},
 		},
@@ -331,8 +339,8 @@ lastName: bar
 			nil,
 			true,
 			false,
-			Skipped,
-			[]ValidationError{},
+			Warning,
+			[]ValidationError{{Msg: "could not find schema for name", Rule: missingSchemaRule, Severity: SeverityWarning}},
 		},
 		{
 			"missing schema in both registries, do not ignore missing",
@@ -361,8 +369,8 @@ lastName: bar
 			[]byte(`<html>error page</html>`),
 			true,
 			false,
-			Skipped,
-			[]ValidationError{},
+			Warning,
+			[]ValidationError{{Msg: "could not find schema for name", Rule: missingSchemaRule, Severity: SeverityWarning}},
 		},
 		{
 			"non-json response in both registries, do not ignore missing",
@@ -458,7 +466,7 @@ interval: test
 			false,
 			false,
 			Invalid,
-			[]ValidationError{{Path: "/interval", Msg: "'test' is not valid duration: must start with P"}},
+			[]ValidationError{{Path: "/interval", Msg: "'test' is not valid duration: must start with P", Keyword: "format"}},
 		},
 	} {
 		val := v{
@@ -543,8 +551,8 @@ age: not a number
 }`)
 
 	expectedErrors := []ValidationError{
-		{Path: "", Msg: "missing property 'lastName'"},
-		{Path: "/age", Msg: "got string, want integer"},
+		{Path: "", Msg: "missing property 'lastName'", Keyword: "required"},
+		{Path: "/age", Msg: "got string, want integer", Keyword: "type"},
 	}
 
 	val := v{
@@ -570,6 +578,53 @@ age: not a number
 	}
 }
 
+func TestValidationErrorsWithReferences(t *testing.T) {
+	rawResource := []byte("kind: name\napiVersion: v1\nspec:\n  replicas: two\n  paused: 1\n")
+
+	// OpenAPI documents wrap references in allOf, so they can be documented
+	schema := []byte(`{
+  "type": "object",
+  "properties": {
+    "spec": {"description": "the spec", "allOf": [{"$ref": "#/components/schemas/Spec"}]}
+  },
+  "components": {
+    "schemas": {
+      "Spec": {
+        "type": "object",
+        "properties": {
+          "replicas": {"type": "integer"},
+          "paused": {"allOf": [{"type": "boolean"}]}
+        }
+      }
+    }
+  }
+}`)
+
+	val := v{
+		opts: Opts{
+			SkipKinds:   map[string]struct{}{},
+			RejectKinds: map[string]struct{}{},
+		},
+		schemaDownload: downloadSchema,
+		regs: []registry.Registry{
+			newMockRegistry(func() (string, any, error) {
+				s, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+				return "", s, err
+			}),
+		},
+	}
+
+	got := val.ValidateResource(resource.Resource{Bytes: rawResource, Line: 1})
+	sort.Slice(got.ValidationErrors, func(i, j int) bool { return got.ValidationErrors[i].Path < got.ValidationErrors[j].Path })
+	expectedErrors := []ValidationError{
+		{Path: "/spec/paused", Msg: "got number, want boolean", Keyword: "type", Line: 5, Column: 3},
+		{Path: "/spec/replicas", Msg: "got string, want integer", Keyword: "type", Line: 4, Column: 3},
+	}
+	if !reflect.DeepEqual(expectedErrors, got.ValidationErrors) {
+		t.Errorf("Expected %+v, got %+v", expectedErrors, got.ValidationErrors)
+	}
+}
+
 func TestValidateFile(t *testing.T) {
 	inputData := []byte(`
 kind: name
@@ -622,7 +677,7 @@ firstName: foo
 
 	expectedStatuses := []Status{Valid, Invalid}
 	expectedValidationErrors := []ValidationError{
-		{Path: "", Msg: "missing property 'lastName'"},
+		{Path: "", Msg: "missing property 'lastName'", Keyword: "required", Line: 7, Column: 1},
 	}
 	if !reflect.DeepEqual(expectedStatuses, gotStatuses) {
 		t.Errorf("Expected %+v, got %+v", expectedStatuses, gotStatuses)
@@ -631,3 +686,531 @@ firstName: foo
 		t.Errorf("Expected %+v, got %+v", expectedValidationErrors, gotValidationErrors)
 	}
 }
+
+type mockRuleEngine struct {
+	errs []ValidationError
+}
+
+func (m mockRuleEngine) Evaluate(sig resource.Signature, obj map[string]interface{}) []ValidationError {
+	return m.errs
+}
+
+func TestValidateRules(t *testing.T) {
+	rawResource := []byte("kind: name\napiVersion: v1\nspec:\n  hostNetwork: true\n")
+	warning := ValidationError{Path: "/spec/hostNetwork", Msg: "hostNetwork is discouraged", Rule: "host-network", Severity: SeverityWarning}
+	failure := ValidationError{Path: "/spec", Msg: "spec.replicas must be set", Rule: "replicas", Severity: SeverityError}
+
+	for _, testCase := range []struct {
+		name           string
+		schema         string
+		ruleErrors     []ValidationError
+		expectStatus   Status
+		expectErr      string
+		expectedErrors []ValidationError
+	}{
+		{
+			"no rule errors",
+			`{"type": "object"}`,
+			nil,
+			Valid,
+			"",
+			nil,
+		},
+		{
+			"warnings do not make the resource invalid",
+			`{"type": "object"}`,
+			[]ValidationError{warning},
+			Warning,
+			"rule host-network: hostNetwork is discouraged",
+			[]ValidationError{{Path: "/spec/hostNetwork", Msg: "hostNetwork is discouraged", Line: 4, Column: 3, Rule: "host-network", Severity: SeverityWarning}},
+		},
+		{
+			"failed rules make the resource invalid",
+			`{"type": "object"}`,
+			[]ValidationError{warning, failure},
+			Invalid,
+			"failed rule replicas: spec.replicas must be set",
+			[]ValidationError{
+				{Path: "/spec/hostNetwork", Msg: "hostNetwork is discouraged", Line: 4, Column: 3, Rule: "host-network", Severity: SeverityWarning},
+				{Path: "/spec", Msg: "spec.replicas must be set", Line: 3, Column: 1, Rule: "replicas", Severity: SeverityError},
+			},
+		},
+		{
+			"rule errors are appended to schema errors",
+			`{"type": "object", "required": ["metadata"]}`,
+			[]ValidationError{failure},
+			Invalid,
+			"problem validating schema. Check JSON formatting: ",
+			[]ValidationError{
+				{Path: "", Msg: "missing property 'metadata'", Keyword: "required", Line: 1, Column: 1},
+				{Path: "/spec", Msg: "spec.replicas must be set", Line: 3, Column: 1, Rule: "replicas", Severity: SeverityError},
+			},
+		},
+	} {
+		val := v{
+			opts: Opts{
+				SkipKinds:   map[string]struct{}{},
+				RejectKinds: map[string]struct{}{},
+				RuleEngine:  mockRuleEngine{testCase.ruleErrors},
+			},
+			schemaDownload: downloadSchema,
+			regs: []registry.Registry{
+				newMockRegistry(func() (string, any, error) {
+					s, err := jsonschema.UnmarshalJSON(strings.NewReader(testCase.schema))
+					return "", s, err
+				}),
+			},
+		}
+
+		got := val.ValidateResource(resource.Resource{Bytes: rawResource, Line: 1})
+		if got.Status != testCase.expectStatus {
+			t.Errorf("test \"%s\": expected status %d, got %d", testCase.name, testCase.expectStatus, got.Status)
+		}
+		if (got.Err == nil && testCase.expectErr != "") || (got.Err != nil && !strings.HasPrefix(got.Err.Error(), testCase.expectErr)) {
+			t.Errorf("test \"%s\": expected error %s, got %v", testCase.name, testCase.expectErr, got.Err)
+		}
+		if !reflect.DeepEqual(got.ValidationErrors, testCase.expectedErrors) {
+			t.Errorf("test \"%s\": expected %+v, got %+v", testCase.name, testCase.expectedErrors, got.ValidationErrors)
+		}
+	}
+}
+
+func TestValidateDuplicates(t *testing.T) {
+	schema := []byte(`{"type": "object"}`)
+
+	for _, testCase := range []struct {
+		name      string
+		resources []string
+		skipKinds map[string]struct{}
+		expect    []Status
+	}{
+		{
+			"same object twice",
+			[]string{
+				"apiVersion: v1\nkind: ReplicationController\nmetadata:\n  name: bob\n",
+				"apiVersion: v1\nkind: ReplicationController\nmetadata:\n  name: bob\n",
+			},
+			nil,
+			[]Status{Valid, Error},
+		},
+		{
+			"same object, once in the default namespace",
+			[]string{
+				"apiVersion: v1\nkind: ReplicationController\nmetadata:\n  name: bob\n  namespace: the-default-namespace\n",
+				"apiVersion: v1\nkind: ReplicationController\nmetadata:\n  name: bob\n",
+			},
+			nil,
+			[]Status{Valid, Error},
+		},
+		{
+			"same object in different namespaces",
+			[]string{
+				"apiVersion: v1\nkind: ReplicationController\nmetadata:\n  name: bob\n  namespace: a\n",
+				"apiVersion: v1\nkind: ReplicationController\nmetadata:\n  name: bob\n  namespace: b\n",
+			},
+			nil,
+			[]Status{Valid, Valid},
+		},
+		{
+			"same cluster-scoped object in different namespaces",
+			[]string{
+				"apiVersion: v1\nkind: PersistentVolume\nmetadata:\n  name: pv0003\n  namespace: a\n",
+				"apiVersion: v1\nkind: PersistentVolume\nmetadata:\n  name: pv0003\n",
+			},
+			nil,
+			[]Status{Valid, Error},
+		},
+		{
+			"same kind and name, different apiVersion",
+			[]string{
+				"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: nginx\n",
+				"apiVersion: apps/v1beta1\nkind: Deployment\nmetadata:\n  name: nginx\n",
+			},
+			nil,
+			[]Status{Valid, Valid},
+		},
+		{
+			"same object of a skipped kind",
+			[]string{
+				"apiVersion: v1\nkind: SkipThisKind\nmetadata:\n  name: identical\n",
+				"apiVersion: v1\nkind: SkipThisKind\nmetadata:\n  name: identical\n",
+			},
+			map[string]struct{}{"SkipThisKind": {}},
+			[]Status{Skipped, Skipped},
+		},
+		{
+			"objects using generateName",
+			[]string{
+				"apiVersion: batch/v1\nkind: Job\nmetadata:\n  generateName: pi-\n",
+				"apiVersion: batch/v1\nkind: Job\nmetadata:\n  generateName: pi-\n",
+			},
+			nil,
+			[]Status{Valid, Valid},
+		},
+	} {
+		skipKinds := testCase.skipKinds
+		if skipKinds == nil {
+			skipKinds = map[string]struct{}{}
+		}
+		val := v{
+			opts: Opts{
+				SkipKinds:   skipKinds,
+				RejectKinds: map[string]struct{}{},
+			},
+			schemaDownload: downloadSchema,
+			duplicates:     newDuplicateDetector("the-default-namespace"),
+			regs: []registry.Registry{
+				newMockRegistry(func() (string, any, error) {
+					s, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+					return "", s, err
+				}),
+			},
+		}
+
+		got := []Status{}
+		for i, raw := range testCase.resources {
+			res := val.ValidateResource(resource.Resource{Path: fmt.Sprintf("file%d.yaml", i), Bytes: []byte(raw), Line: 1})
+			got = append(got, res.Status)
+			if res.Status == Error {
+				var dupErr *DuplicateResourceError
+				if !errors.As(res.Err, &dupErr) {
+					t.Errorf("Test '%s': expected a DuplicateResourceError, got %s", testCase.name, res.Err)
+				} else if dupErr.OtherPath != "file0.yaml" || dupErr.OtherLine != 1 {
+					t.Errorf("Test '%s': expected duplicate to reference file0.yaml:1, got %s:%d", testCase.name, dupErr.OtherPath, dupErr.OtherLine)
+				}
+			}
+		}
+
+		if !reflect.DeepEqual(testCase.expect, got) {
+			t.Errorf("Test '%s': expected statuses %+v, got %+v", testCase.name, testCase.expect, got)
+		}
+	}
+}
+
+func TestValidateDuplicatesOfCustomResources(t *testing.T) {
+	crd := `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: backups.example.com
+spec:
+  group: example.com
+  scope: %s
+  names:
+    kind: Backup
+`
+	for _, testCase := range []struct {
+		scope  string
+		expect []Status
+	}{
+		{"Cluster", []Status{Valid, Valid, Error}},
+		{"Namespaced", []Status{Valid, Valid, Valid}},
+	} {
+		val := v{
+			opts:           Opts{SkipKinds: map[string]struct{}{}, RejectKinds: map[string]struct{}{}},
+			schemaDownload: downloadSchema,
+			duplicates:     newDuplicateDetector("default"),
+			regs: []registry.Registry{
+				newMockRegistry(func() (string, any, error) {
+					s, err := jsonschema.UnmarshalJSON(strings.NewReader(`{"type": "object"}`))
+					return "", s, err
+				}),
+			},
+		}
+
+		got := []Status{}
+		for _, raw := range []string{
+			fmt.Sprintf(crd, testCase.scope),
+			"apiVersion: example.com/v1\nkind: Backup\nmetadata:\n  name: nightly\n  namespace: a\n",
+			"apiVersion: example.com/v1\nkind: Backup\nmetadata:\n  name: nightly\n  namespace: b\n",
+		} {
+			got = append(got, val.ValidateResource(resource.Resource{Path: "backups.yaml", Bytes: []byte(raw)}).Status)
+		}
+
+		if !reflect.DeepEqual(testCase.expect, got) {
+			t.Errorf("scope %s: expected statuses %+v, got %+v", testCase.scope, testCase.expect, got)
+		}
+	}
+}
+
+func TestValidateInvalidDuplicate(t *testing.T) {
+	val := v{
+		opts:           Opts{SkipKinds: map[string]struct{}{}, RejectKinds: map[string]struct{}{}},
+		schemaDownload: downloadSchema,
+		duplicates:     newDuplicateDetector("default"),
+		regs: []registry.Registry{
+			newMockRegistry(func() (string, any, error) {
+				s, err := jsonschema.UnmarshalJSON(strings.NewReader(`{"properties": {"spec": {"type": "object"}}}`))
+				return "", s, err
+			}),
+		},
+	}
+
+	first := val.ValidateResource(resource.Resource{Path: "a.yaml", Line: 3, Bytes: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec: {}\n")})
+	if first.Status != Valid {
+		t.Fatalf("expected the first resource to be valid, got %d: %s", first.Status, first.Err)
+	}
+
+	got := val.ValidateResource(resource.Resource{Path: "b.yaml", Line: 1, Bytes: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec: 42\n")})
+	expectErrors := []ValidationError{
+		{Path: "/spec", Msg: "got number, want object", Keyword: "type", Line: 5, Column: 1},
+		{Path: "/metadata/name", Msg: "duplicate resource v1/Service/default/web, also defined in a.yaml:3", Line: 4, Column: 3, Rule: DuplicateResourceRule},
+	}
+	if got.Status != Error {
+		t.Errorf("expected status Error, got %d", got.Status)
+	}
+	if !reflect.DeepEqual(got.ValidationErrors, expectErrors) {
+		t.Errorf("expected %+v, got %+v", expectErrors, got.ValidationErrors)
+	}
+	var dupErr *DuplicateResourceError
+	if !errors.As(got.Err, &dupErr) || !strings.Contains(got.Err.Error(), "got number, want object") {
+		t.Errorf("expected a DuplicateResourceError also reporting the schema error, got %s", got.Err)
+	}
+}
+
+func TestValidateCRDsFromInput(t *testing.T) {
+	crd := `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: crontabs.stable.example.com
+spec:
+  group: stable.example.com
+  names:
+    kind: CronTab
+  versions:
+  - name: v1
+    served: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              replicas:
+                type: integer
+`
+	validCR := "apiVersion: stable.example.com/v1\nkind: CronTab\nspec:\n  replicas: 1\n"
+	invalidCR := "apiVersion: stable.example.com/v1\nkind: CronTab\nspec:\n  replicas: one\n"
+	strictInvalidCR := "apiVersion: stable.example.com/v1\nkind: CronTab\nspec:\n  replicas: 1\n  image: foo\n"
+	namedCR := "apiVersion: stable.example.com/v1\nkind: CronTab\nmetadata:\n  name: cron\nspec:\n  replicas: 1\n"
+	rootInvalidCR := "apiVersion: stable.example.com/v1\nkind: CronTab\nmetadata:\n  name: cron\nspce:\n  replicas: 1\n"
+
+	for _, testCase := range []struct {
+		name          string
+		input         string
+		crdsFromInput bool
+		strict        bool
+		expect        []Status
+	}{
+		{
+			"custom resources after the CRD",
+			crd + "---\n" + validCR + "---\n" + invalidCR,
+			true,
+			false,
+			[]Status{Warning, Valid, Invalid},
+		},
+		{
+			"custom resources before the CRD",
+			validCR + "---\n" + invalidCR + "---\n" + crd,
+			true,
+			false,
+			[]Status{Valid, Invalid, Warning},
+		},
+		{
+			"additional properties are allowed unless strict",
+			strictInvalidCR + "---\n" + crd,
+			true,
+			false,
+			[]Status{Valid, Warning},
+		},
+		{
+			"additional properties are rejected in strict mode",
+			strictInvalidCR + "---\n" + crd,
+			true,
+			true,
+			[]Status{Invalid, Warning},
+		},
+		{
+			"apiVersion, kind and metadata are allowed in strict mode, though the CRD does not declare them",
+			crd + "---\n" + namedCR + "---\n" + rootInvalidCR,
+			true,
+			true,
+			[]Status{Warning, Valid, Invalid},
+		},
+		{
+			"CRDs from the input are not used by default",
+			validCR + "---\n" + crd,
+			false,
+			false,
+			[]Status{Warning, Warning},
+		},
+	} {
+		val, err := New([]string{"testdata/does-not-exist/{{ .ResourceKind }}.json"}, Opts{
+			CRDsFromInput:        testCase.crdsFromInput,
+			IgnoreMissingSchemas: true,
+			Strict:               testCase.strict,
+		})
+		if err != nil {
+			t.Fatalf("%s: failed creating validator: %s", testCase.name, err)
+		}
+
+		got := []Status{}
+		for _, res := range val.Validate("test-file", io.NopCloser(bytes.NewReader([]byte(testCase.input)))) {
+			got = append(got, res.Status)
+		}
+		if !reflect.DeepEqual(testCase.expect, got) {
+			t.Errorf("%s: expected %+v, got %+v", testCase.name, testCase.expect, got)
+		}
+	}
+}
+
+func TestValidateSessions(t *testing.T) {
+	crd := `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: crontabs.stable.example.com
+spec:
+  group: stable.example.com
+  names:
+    kind: CronTab
+  versions:
+  - name: v1
+    served: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        required: [spec]
+`
+	cr := "apiVersion: stable.example.com/v1\nkind: CronTab\nmetadata:\n  name: cron\n"
+
+	val, err := New([]string{"testdata/does-not-exist/{{ .ResourceKind }}.json"}, Opts{
+		CRDsFromInput:        true,
+		CheckDuplicates:      true,
+		IgnoreMissingSchemas: true,
+	})
+	if err != nil {
+		t.Fatalf("failed creating validator: %s", err)
+	}
+
+	for _, testCase := range []struct {
+		name   string
+		input  string
+		expect []Status
+	}{
+		{"custom resource validated against the CRD of the session", crd + "---\n" + cr, []Status{Warning, Invalid}},
+		{"CRDs and resources of other sessions are ignored", cr, []Status{Warning}},
+	} {
+		got := []Status{}
+		for _, res := range val.Session("1.30.0").Validate("test-file", io.NopCloser(bytes.NewReader([]byte(testCase.input)))) {
+			if res.KubernetesVersion != "1.30.0" {
+				t.Errorf("%s: expected resources to be validated against 1.30.0, got %s", testCase.name, res.KubernetesVersion)
+			}
+			got = append(got, res.Status)
+		}
+		if !reflect.DeepEqual(testCase.expect, got) {
+			t.Errorf("%s: expected %+v, got %+v", testCase.name, testCase.expect, got)
+		}
+	}
+}
+
+func TestValidateResourceForVersion(t *testing.T) {
+	schemas := t.TempDir()
+	for version, schema := range map[string]string{
+		"v1.27.0": `{"type": "object", "required": ["spec"]}`,
+		"v1.28.0": `{"type": "object"}`,
+	} {
+		if err := os.MkdirAll(filepath.Join(schemas, version), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(schemas, version, "replicationcontroller.json"), []byte(schema), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	val, err := New([]string{schemas + "/{{ .NormalizedKubernetesVersion }}/{{ .ResourceKind }}.json"}, Opts{KubernetesVersion: "1.28.0"})
+	if err != nil {
+		t.Fatalf("failed creating validator: %s", err)
+	}
+
+	res := resource.Resource{Path: "test-file", Bytes: []byte("apiVersion: v1\nkind: ReplicationController\nmetadata:\n  name: bob\n")}
+	for _, testCase := range []struct {
+		k8sVersion   string
+		expectStatus Status
+	}{
+		{"1.27.0", Invalid},
+		{"1.28.0", Valid},
+		{"1.27.0", Invalid},
+		{"1.29.0", Error},
+	} {
+		got := val.ValidateResourceForVersion(res, testCase.k8sVersion)
+		if got.Status != testCase.expectStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", testCase.k8sVersion, testCase.expectStatus, got.Status, got.Err)
+		}
+		if got.KubernetesVersion != testCase.k8sVersion {
+			t.Errorf("%s: expected the result to be tagged with version %s, got %s", testCase.k8sVersion, testCase.k8sVersion, got.KubernetesVersion)
+		}
+	}
+
+	if got := val.ValidateResource(res); got.Status != Valid || got.KubernetesVersion != "1.28.0" {
+		t.Errorf("expected the resource to be valid on the default version 1.28.0, got %d on %s", got.Status, got.KubernetesVersion)
+	}
+}
+
+// manyResources returns a stream of n multi-document Deployments
+func manyResources(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app-%d
+  namespace: team-%d
+  labels:
+    app.kubernetes.io/name: app-%d
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        image: registry.example.com/app:1.%d.0
+        args: ["--port", "8080", "--verbose"]
+        ports:
+        - containerPort: 8080
+        env:
+        - name: LOG_LEVEL
+          value: info
+`, i, i%10, i, i)
+	}
+	return b.String()
+}
+
+func BenchmarkValidate(b *testing.B) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"spec": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"replicas": map[string]any{"type": "integer"},
+				},
+			},
+		},
+	}
+	stream := manyResources(1000)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		val, _ := New(nil, Opts{})
+		val.(*v).regs = []registry.Registry{newMockRegistry(func() (string, any, error) { return "", schema, nil })}
+		for _, res := range val.Validate("resources.yaml", io.NopCloser(strings.NewReader(stream))) {
+			if res.Status != Valid {
+				b.Fatalf("expected resource to be valid, got %s", res.Err)
+			}
+			if _, err := res.Resource.Signature(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}